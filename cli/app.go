@@ -9,29 +9,53 @@ import (
 
 // App represents a cli app
 type App struct {
-	name    string
-	version string
-	init    cli.Command
-	root    cli.Command
-	interm  cli.Command
-	server  cli.Command
-	client  cli.Command
-	sign    cli.Command
-	verify  cli.Command
+	name     string
+	version  string
+	init     cli.Command
+	root     cli.Command
+	interm   cli.Command
+	server   cli.Command
+	client   cli.Command
+	sign     cli.Command
+	verify   cli.Command
+	rekey    cli.Command
+	revoke   cli.Command
+	crl      cli.Command
+	ocsp     cli.Command
+	list     cli.Command
+	inspect  cli.Command
+	renew    cli.Command
+	export   cli.Command
+	bundle   cli.Command
+	importCA cli.Command
+	plan     cli.Command
+	apply    cli.Command
 }
 
 // NewApp creates a new cli app
 func NewApp(name, version string) *App {
 	return &App{
-		name:    name,
-		version: version,
-		init:    NewInitCommand(),
-		root:    NewReqCommand(pki.Cert{Type: pki.CertTypeRoot}),
-		interm:  NewReqCommand(pki.Cert{Type: pki.CertTypeInterm}),
-		server:  NewReqCommand(pki.Cert{Type: pki.CertTypeServer}),
-		client:  NewReqCommand(pki.Cert{Type: pki.CertTypeClient}),
-		sign:    NewSignCommand(),
-		verify:  NewVerifyCommand(),
+		name:     name,
+		version:  version,
+		init:     NewInitCommand(),
+		root:     NewReqCommand(pki.Cert{Type: pki.CertTypeRoot}),
+		interm:   NewReqCommand(pki.Cert{Type: pki.CertTypeInterm}),
+		server:   NewReqCommand(pki.Cert{Type: pki.CertTypeServer}),
+		client:   NewReqCommand(pki.Cert{Type: pki.CertTypeClient}),
+		sign:     NewSignCommand(),
+		verify:   NewVerifyCommand(),
+		rekey:    NewRekeyStorageCommand(),
+		revoke:   NewRevokeCommand(),
+		crl:      NewCRLCommand(),
+		ocsp:     NewOCSPServeCommand(),
+		list:     NewListCommand(),
+		inspect:  NewInspectCommand(),
+		renew:    NewRenewCommand(),
+		export:   NewExportCommand(),
+		bundle:   NewBundleCommand(),
+		importCA: NewImportCACommand(),
+		plan:     NewPlanCommand(),
+		apply:    NewApplyCommand(),
 	}
 }
 
@@ -62,6 +86,42 @@ func (a *App) Run(args []string) int {
 		"verify": func() (cli.Command, error) {
 			return a.verify, nil
 		},
+		"rekey-storage": func() (cli.Command, error) {
+			return a.rekey, nil
+		},
+		"revoke": func() (cli.Command, error) {
+			return a.revoke, nil
+		},
+		"crl": func() (cli.Command, error) {
+			return a.crl, nil
+		},
+		"ocsp-serve": func() (cli.Command, error) {
+			return a.ocsp, nil
+		},
+		"list": func() (cli.Command, error) {
+			return a.list, nil
+		},
+		"inspect": func() (cli.Command, error) {
+			return a.inspect, nil
+		},
+		"renew": func() (cli.Command, error) {
+			return a.renew, nil
+		},
+		"export": func() (cli.Command, error) {
+			return a.export, nil
+		},
+		"bundle": func() (cli.Command, error) {
+			return a.bundle, nil
+		},
+		"import-ca": func() (cli.Command, error) {
+			return a.importCA, nil
+		},
+		"plan": func() (cli.Command, error) {
+			return a.plan, nil
+		},
+		"apply": func() (cli.Command, error) {
+			return a.apply, nil
+		},
 	}
 
 	status, err := app.Run()
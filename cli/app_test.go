@@ -15,26 +15,50 @@ var (
 		`Available commands are:`,
 	}
 
-	helpMockInit   = "help text for mocked init command"
-	helpMockRoot   = "help text for mocked root command"
-	helpMockInterm = "help text for mocked intermediate command"
-	helpMockServer = "help text for mocked server command"
-	helpMockClient = "help text for mocked client command"
-	helpMockSign   = "help text for mocked sign command"
-	helpMockVerify = "help text for mocked verify command"
+	helpMockInit     = "help text for mocked init command"
+	helpMockRoot     = "help text for mocked root command"
+	helpMockInterm   = "help text for mocked intermediate command"
+	helpMockServer   = "help text for mocked server command"
+	helpMockClient   = "help text for mocked client command"
+	helpMockSign     = "help text for mocked sign command"
+	helpMockVerify   = "help text for mocked verify command"
+	helpMockRekey    = "help text for mocked rekey-storage command"
+	helpMockRevoke   = "help text for mocked revoke command"
+	helpMockCRL      = "help text for mocked crl command"
+	helpMockOCSP     = "help text for mocked ocsp-serve command"
+	helpMockList     = "help text for mocked list command"
+	helpMockInspect  = "help text for mocked inspect command"
+	helpMockRenew    = "help text for mocked renew command"
+	helpMockExport   = "help text for mocked export command"
+	helpMockBundle   = "help text for mocked bundle command"
+	helpMockImportCA = "help text for mocked import-ca command"
+	helpMockPlan     = "help text for mocked plan command"
+	helpMockApply    = "help text for mocked apply command"
 )
 
 func newMockApp(name, version string) *App {
 	return &App{
-		name:    name,
-		version: version,
-		init:    &cli.MockCommand{RunResult: 0, HelpText: helpMockInit},
-		root:    &cli.MockCommand{RunResult: 0, HelpText: helpMockRoot},
-		interm:  &cli.MockCommand{RunResult: 0, HelpText: helpMockInterm},
-		server:  &cli.MockCommand{RunResult: 0, HelpText: helpMockServer},
-		client:  &cli.MockCommand{RunResult: 0, HelpText: helpMockClient},
-		sign:    &cli.MockCommand{RunResult: 0, HelpText: helpMockSign},
-		verify:  &cli.MockCommand{RunResult: 0, HelpText: helpMockVerify},
+		name:     name,
+		version:  version,
+		init:     &cli.MockCommand{RunResult: 0, HelpText: helpMockInit},
+		root:     &cli.MockCommand{RunResult: 0, HelpText: helpMockRoot},
+		interm:   &cli.MockCommand{RunResult: 0, HelpText: helpMockInterm},
+		server:   &cli.MockCommand{RunResult: 0, HelpText: helpMockServer},
+		client:   &cli.MockCommand{RunResult: 0, HelpText: helpMockClient},
+		sign:     &cli.MockCommand{RunResult: 0, HelpText: helpMockSign},
+		verify:   &cli.MockCommand{RunResult: 0, HelpText: helpMockVerify},
+		rekey:    &cli.MockCommand{RunResult: 0, HelpText: helpMockRekey},
+		revoke:   &cli.MockCommand{RunResult: 0, HelpText: helpMockRevoke},
+		crl:      &cli.MockCommand{RunResult: 0, HelpText: helpMockCRL},
+		ocsp:     &cli.MockCommand{RunResult: 0, HelpText: helpMockOCSP},
+		list:     &cli.MockCommand{RunResult: 0, HelpText: helpMockList},
+		inspect:  &cli.MockCommand{RunResult: 0, HelpText: helpMockInspect},
+		renew:    &cli.MockCommand{RunResult: 0, HelpText: helpMockRenew},
+		export:   &cli.MockCommand{RunResult: 0, HelpText: helpMockExport},
+		bundle:   &cli.MockCommand{RunResult: 0, HelpText: helpMockBundle},
+		importCA: &cli.MockCommand{RunResult: 0, HelpText: helpMockImportCA},
+		plan:     &cli.MockCommand{RunResult: 0, HelpText: helpMockPlan},
+		apply:    &cli.MockCommand{RunResult: 0, HelpText: helpMockApply},
 	}
 }
 
@@ -59,6 +83,18 @@ func TestNewApp(t *testing.T) {
 		assert.NotNil(t, app.client)
 		assert.NotNil(t, app.sign)
 		assert.NotNil(t, app.verify)
+		assert.NotNil(t, app.rekey)
+		assert.NotNil(t, app.revoke)
+		assert.NotNil(t, app.crl)
+		assert.NotNil(t, app.ocsp)
+		assert.NotNil(t, app.list)
+		assert.NotNil(t, app.inspect)
+		assert.NotNil(t, app.renew)
+		assert.NotNil(t, app.export)
+		assert.NotNil(t, app.bundle)
+		assert.NotNil(t, app.importCA)
+		assert.NotNil(t, app.plan)
+		assert.NotNil(t, app.apply)
 	}
 }
 
@@ -105,6 +141,50 @@ func TestAppRun(t *testing.T) {
 		{"cli", "0.10.1", []string{"verify"}, 0, nil},
 		{"cli", "0.10.2", []string{"verify", "-help"}, 0, []string{helpMockVerify}},
 		{"cli", "0.10.3", []string{"verify", "--help"}, 0, []string{helpMockVerify}},
+
+		{"cli", "0.11.1", []string{"rekey-storage"}, 0, nil},
+		{"cli", "0.11.2", []string{"rekey-storage", "-help"}, 0, []string{helpMockRekey}},
+		{"cli", "0.11.3", []string{"rekey-storage", "--help"}, 0, []string{helpMockRekey}},
+
+		{"cli", "0.12.1", []string{"revoke"}, 0, nil},
+		{"cli", "0.12.2", []string{"revoke", "-help"}, 0, []string{helpMockRevoke}},
+		{"cli", "0.12.3", []string{"revoke", "--help"}, 0, []string{helpMockRevoke}},
+
+		{"cli", "0.13.1", []string{"crl"}, 0, nil},
+		{"cli", "0.13.2", []string{"crl", "-help"}, 0, []string{helpMockCRL}},
+		{"cli", "0.13.3", []string{"crl", "--help"}, 0, []string{helpMockCRL}},
+
+		{"cli", "0.14.1", []string{"ocsp-serve"}, 0, nil},
+		{"cli", "0.14.2", []string{"ocsp-serve", "-help"}, 0, []string{helpMockOCSP}},
+		{"cli", "0.14.3", []string{"ocsp-serve", "--help"}, 0, []string{helpMockOCSP}},
+
+		{"cli", "0.15.1", []string{"list"}, 0, nil},
+		{"cli", "0.15.2", []string{"list", "-help"}, 0, []string{helpMockList}},
+		{"cli", "0.15.3", []string{"list", "--help"}, 0, []string{helpMockList}},
+
+		{"cli", "0.16.1", []string{"inspect"}, 0, nil},
+		{"cli", "0.16.2", []string{"inspect", "-help"}, 0, []string{helpMockInspect}},
+		{"cli", "0.16.3", []string{"inspect", "--help"}, 0, []string{helpMockInspect}},
+
+		{"cli", "0.17.1", []string{"renew"}, 0, nil},
+		{"cli", "0.17.2", []string{"renew", "-help"}, 0, []string{helpMockRenew}},
+		{"cli", "0.17.3", []string{"renew", "--help"}, 0, []string{helpMockRenew}},
+
+		{"cli", "0.18.1", []string{"export"}, 0, nil},
+		{"cli", "0.18.2", []string{"export", "-help"}, 0, []string{helpMockExport}},
+		{"cli", "0.18.3", []string{"export", "--help"}, 0, []string{helpMockExport}},
+		{"cli", "0.19.1", []string{"bundle"}, 0, nil},
+		{"cli", "0.19.2", []string{"bundle", "-help"}, 0, []string{helpMockBundle}},
+		{"cli", "0.19.3", []string{"bundle", "--help"}, 0, []string{helpMockBundle}},
+		{"cli", "0.20.1", []string{"import-ca"}, 0, nil},
+		{"cli", "0.20.2", []string{"import-ca", "-help"}, 0, []string{helpMockImportCA}},
+		{"cli", "0.20.3", []string{"import-ca", "--help"}, 0, []string{helpMockImportCA}},
+		{"cli", "0.21.1", []string{"plan"}, 0, nil},
+		{"cli", "0.21.2", []string{"plan", "-help"}, 0, []string{helpMockPlan}},
+		{"cli", "0.21.3", []string{"plan", "--help"}, 0, []string{helpMockPlan}},
+		{"cli", "0.22.1", []string{"apply"}, 0, nil},
+		{"cli", "0.22.2", []string{"apply", "-help"}, 0, []string{helpMockApply}},
+		{"cli", "0.22.3", []string{"apply", "--help"}, 0, []string{helpMockApply}},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	applySuccess = " ✓ %s %s"
+	applyFailure = " ✗ Failed to %s %s. Error: %s"
+	applySummary = "\nApplied: %d created, %d renewed, %d reissued, %d revoked.\n"
+
+	applySynopsis = `Converges workspace to a manifest.`
+	applyHelp     = `
+	You can use this command to create, renew, reissue, and revoke certificates, so workspace matches a manifest.
+	The changes are the same ones shown by plan command, and they are applied in the order of manifest.
+	Applying the same manifest again makes no change, so it can safely run in CI.
+
+	Passwords of certificate authorities are read from password_file or password_env of them in manifest.
+	Otherwise, they are read from -password-file or -password-env flags, and you will be asked for them if none is given.
+	Using -non-interactive flag, nothing is asked and the password is read from a line of standard input if no flag is given.
+
+	A certificate authority needs its password for signing, and intermediates need their own passwords for creating their keys.
+	If signing a new certificate fails, its request is kept and signed next time.
+	Applying stops at the first failure.
+
+	Flags:
+		-file               the path to manifest file (default: manifest.yaml)
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authorities without one in manifest from a file
+		-password-env       read the password of certificate authorities without one in manifest from an environment variable
+	`
+)
+
+// ApplyCommand represents the apply command
+type ApplyCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+	now   func() time.Time
+}
+
+// NewApplyCommand creates a new command
+func NewApplyCommand() *ApplyCommand {
+	return &ApplyCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+		now:   time.Now,
+	}
+}
+
+// applier applies the actions of a manifest to workspace
+type applier struct {
+	ui        cli.Ui
+	pki       pki.Manager
+	in        *input
+	state     *pki.State
+	spec      *pki.Spec
+	manifest  *pki.Manifest
+	passwords map[string]string
+}
+
+// password returns the password of a certificate authority
+// The password given by flags or standard input is read once and used for every certificate authority without one in manifest.
+func (a *applier) password(name string) (string, error) {
+	if password, ok := a.passwords[name]; ok {
+		return password, nil
+	}
+
+	var password string
+	var err error
+
+	if mc, _ := a.manifest.Find(name); mc.PasswordFile != "" || mc.PasswordEnv != "" {
+		password, err = readPassword(mc.PasswordFile, mc.PasswordEnv)
+	} else if p, ok := a.passwords[""]; ok {
+		password = p
+	} else if p, ok, e := a.in.password(); e != nil || ok {
+		password, err = p, e
+		if ok {
+			a.passwords[""] = p
+		}
+	} else if a.in.isNonInteractive() {
+		err = errors.New("password of " + name + " is required in non-interactive mode (set password_file or password_env in manifest)")
+	} else {
+		password, err = a.ui.AskSecret(fmt.Sprintf(promptTemplate, "Password for "+name, "string"))
+		if err == nil {
+			err = checkPassword(password)
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	a.passwords[name] = password
+	return password, nil
+}
+
+// configFor returns the config of a certificate with its password if it is a certificate authority
+func (a *applier) configFor(c pki.Cert) (pki.Config, error) {
+	// Type field is ensured to be valid
+	config, _ := a.state.ConfigFor(c.Type)
+
+	var err error
+	if c.Type == pki.CertTypeRoot || c.Type == pki.CertTypeInterm {
+		config.Password, err = a.password(c.Name)
+	}
+
+	return config, err
+}
+
+func (a *applier) create(action pki.Action) error {
+	mc, _ := a.manifest.Find(action.Cert.Name)
+	claimSpec, _ := a.spec.ClaimFor(action.Cert.Type)
+	claim := mc.Claim(claimSpec)
+
+	config, usage := issuanceFor(a.state, a.spec, action.CA, action.Cert)
+	if action.Cert.Type == pki.CertTypeRoot || action.Cert.Type == pki.CertTypeInterm {
+		password, err := a.password(action.Cert.Name)
+		if err != nil {
+			return err
+		}
+		config.Password = password
+	}
+
+	if action.Cert.Type == pki.CertTypeRoot {
+		return a.pki.GenCert(config, claim, action.Cert)
+	}
+
+	// A request left from a previous failure is signed as it is
+	if _, err := os.Stat(action.Cert.CSRPath()); os.IsNotExist(err) {
+		err = a.pki.GenCSR(config, claim, action.Cert)
+		if err != nil {
+			return err
+		}
+	}
+
+	configCA, err := a.configFor(action.CA)
+	if err != nil {
+		return err
+	}
+
+	// Type field is ensured to be valid
+	policyCA, _ := a.spec.PolicyForCA(action.CA)
+
+	return a.pki.SignCSR(configCA, action.CA, config, action.Cert, usage, pki.PolicyTrustFunc(policyCA, action.Cert.Type))
+}
+
+func (a *applier) renew(action pki.Action) error {
+	configCA, err := a.configFor(action.CA)
+	if err != nil {
+		return err
+	}
+
+	// Root certificate authority is its own issuer
+	config := configCA
+	if action.Cert != action.CA {
+		config, err = a.configFor(action.Cert)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Type field is ensured to be valid
+	policyCA, _ := a.spec.PolicyForCA(action.CA)
+
+	return a.pki.RenewCert(configCA, action.CA, config, action.Cert, action.Rekey, pki.PolicyTrustFunc(policyCA, action.Cert.Type))
+}
+
+func (a *applier) reissue(action pki.Action) error {
+	mc, _ := a.manifest.Find(action.Cert.Name)
+	claimSpec, _ := a.spec.ClaimFor(action.Cert.Type)
+	claim := mc.Claim(claimSpec)
+
+	// Common name not set in manifest is kept from the existing certificate
+	claim.CommonName = mc.CommonName
+
+	configCA, err := a.configFor(action.CA)
+	if err != nil {
+		return err
+	}
+
+	// Root certificate authority is its own issuer
+	config := configCA
+	if action.Cert != action.CA {
+		config, err = a.configFor(action.Cert)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Type field is ensured to be valid
+	policyCA, _ := a.spec.PolicyForCA(action.CA)
+
+	return a.pki.ReissueCert(configCA, action.CA, config, action.Cert, claim, action.Rekey, pki.PolicyTrustFunc(policyCA, action.Cert.Type))
+}
+
+func (a *applier) apply(action pki.Action) error {
+	switch action.Type {
+	case pki.ActionCreate:
+		return a.create(action)
+	case pki.ActionRenew:
+		return a.renew(action)
+	case pki.ActionReissue:
+		return a.reissue(action)
+	case pki.ActionRevoke:
+		return a.pki.RevokeCert(action.CA, action.Cert, action.Reason)
+	default:
+		return errors.New("invalid action " + action.Type)
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *ApplyCommand) Synopsis() string {
+	return applySynopsis
+}
+
+// Help returns the long help text for command
+func (c *ApplyCommand) Help() string {
+	return applyHelp
+}
+
+// Run executes the command
+func (c *ApplyCommand) Run(args []string) int {
+	var fFile string
+
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fFile, "file", defaultManifest, "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	state, spec, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	manifest, actions, status := loadManifest(c.ui, fFile, c.now())
+	if status != 0 {
+		return status
+	}
+
+	if len(actions) == 0 {
+		c.ui.Info(planUpToDate)
+		return 0
+	}
+
+	a := &applier{
+		ui:        c.ui,
+		pki:       c.pki,
+		in:        in,
+		state:     state,
+		spec:      spec,
+		manifest:  manifest,
+		passwords: map[string]string{},
+	}
+
+	counts := map[string]int{}
+	c.ui.Output("")
+	for _, action := range actions {
+		err = a.apply(action)
+		if err != nil {
+			c.ui.Error(fmt.Sprintf(applyFailure, action.Type, action.Cert.Name, err.Error()))
+			return ErrorApply
+		}
+
+		counts[action.Type]++
+		c.ui.Info(fmt.Sprintf(applySuccess, action.Type, action.Cert.Name))
+	}
+
+	c.ui.Output(fmt.Sprintf(applySummary, counts[pki.ActionCreate], counts[pki.ActionRenew], counts[pki.ActionReissue], counts[pki.ActionRevoke]))
+
+	return 0
+}
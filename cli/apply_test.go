@@ -0,0 +1,312 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	applyManifest = `
+certs:
+  - name: root
+    type: root
+    common_name: Root CA
+    password_env: ROOT_PASSWORD
+  - name: sre
+    type: intermediate
+    ca: root
+    common_name: SRE CA
+  - name: webapp
+    type: server
+    ca: sre
+    common_name: webapp.example.com
+    dns_names: [webapp.example.com]
+`
+
+	reissueManifest = `
+certs:
+  - name: root
+    type: root
+    common_name: Root CA
+    password_env: ROOT_PASSWORD
+  - name: sre
+    type: intermediate
+    ca: root
+    common_name: SRE CA
+  - name: webapp
+    type: server
+    ca: sre
+    common_name: webapp.example.com
+    dns_names: [webapp.example.com, www.example.com]
+`
+
+	resubjectManifest = `
+certs:
+  - name: root
+    type: root
+    common_name: Root CA
+    password_env: ROOT_PASSWORD
+  - name: sre
+    type: intermediate
+    ca: root
+    common_name: Platform CA
+  - name: webapp
+    type: server
+    ca: sre
+    common_name: webapp.example.com
+    dns_names: [webapp.example.com, www.example.com]
+`
+
+	revokeManifest = `
+certs:
+  - name: root
+    type: root
+    password_env: ROOT_PASSWORD
+  - name: sre
+    type: intermediate
+    ca: root
+  - name: webapp
+    type: server
+    ca: sre
+    revoke: superseded
+`
+)
+
+func TestNewApplyCommand(t *testing.T) {
+	cmd := NewApplyCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.NotNil(t, cmd.stdin)
+	assert.NotNil(t, cmd.now)
+
+	assert.Equal(t, "Converges workspace to a manifest.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestApplyCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Setenv("ROOT_PASSWORD", "rootSecret")
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	tests := []struct {
+		title          string
+		args           []string
+		manifest       string
+		stdin          string
+		input          string
+		expectedOutput string
+	}{
+		{
+			"Interactive",
+			[]string{},
+			applyManifest,
+			"",
+			`intermSecret
+			`,
+			"Applied: 3 created, 0 renewed, 0 reissued, 0 revoked.",
+		},
+		{
+			"UpToDate",
+			[]string{},
+			applyManifest,
+			"",
+			``,
+			"No changes. Workspace is up to date with manifest.",
+		},
+		{
+			"Reissue",
+			[]string{"-non-interactive"},
+			reissueManifest,
+			"intermSecret\n",
+			``,
+			"Applied: 0 created, 0 renewed, 1 reissued, 0 revoked.",
+		},
+		{
+			"ReissuedUpToDate",
+			[]string{},
+			reissueManifest,
+			"",
+			``,
+			"No changes. Workspace is up to date with manifest.",
+		},
+		{
+			"ReissueCASubject",
+			[]string{"-non-interactive"},
+			resubjectManifest,
+			"intermSecret\n",
+			``,
+			"Applied: 0 created, 1 renewed, 1 reissued, 0 revoked.",
+		},
+		{
+			"NonInteractive",
+			[]string{"-non-interactive"},
+			revokeManifest,
+			"intermSecret\n",
+			``,
+			"Applied: 0 created, 0 renewed, 0 reissued, 1 revoked.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader(test.input))
+			cmd := &ApplyCommand{
+				ui:    mockUI,
+				pki:   pki.NewX509Manager(),
+				stdin: strings.NewReader(test.stdin),
+				now:   time.Now,
+			}
+
+			exit := cmd.Run(append(test.args, "-file="+writeManifest(t, test.manifest)))
+			assert.Zero(t, exit, mockUI.ErrorWriter.String())
+			assert.Contains(t, mockUI.OutputWriter.String(), test.expectedOutput)
+		})
+	}
+
+	// Server certificate is renewed by intermediate certificate authority after its subject has changed
+	cInterm := pki.Cert{Name: "sre", Type: pki.CertTypeInterm}
+	cServer := pki.Cert{Name: "webapp", Type: pki.CertTypeServer}
+	assert.NoError(t, pki.NewX509Manager().VerifyCert(cInterm, cServer, "webapp.example.com"))
+}
+
+func TestApplyCommandError(t *testing.T) {
+	tests := []struct {
+		title         string
+		args          []string
+		manifest      string
+		input         string
+		manager       *mockManager
+		expectedExit  int
+		expectedError string
+	}{
+		{
+			"InvalidFlag",
+			[]string{"-invalid"},
+			"",
+			``,
+			&mockManager{},
+			ErrorInvalidFlag,
+			"",
+		},
+		{
+			"NoManifest",
+			[]string{"-file=manifest.yaml"},
+			"",
+			``,
+			&mockManager{},
+			ErrorManifest,
+			"Failed to read manifest from manifest.yaml",
+		},
+		{
+			"NoPassword",
+			[]string{"-non-interactive"},
+			"certs:\n  - name: root\n    type: root\n",
+			``,
+			&mockManager{},
+			ErrorApply,
+			"Failed to create root. Error: password should be at least 6 characters",
+		},
+		{
+			"InvalidPassword",
+			[]string{},
+			"certs:\n  - name: root\n    type: root\n",
+			`short
+			`,
+			&mockManager{},
+			ErrorApply,
+			"Failed to create root. Error: password should be at least 6 characters",
+		},
+		{
+			"NoPasswordEnv",
+			[]string{},
+			"certs:\n  - name: root\n    type: root\n    password_env: NO_PASSWORD\n",
+			``,
+			&mockManager{},
+			ErrorApply,
+			"Failed to create root. Error: password environment variable NO_PASSWORD is not set",
+		},
+		{
+			"GenCertError",
+			[]string{"-password-env=ROOT_PASSWORD"},
+			"certs:\n  - name: root\n    type: root\n",
+			``,
+			&mockManager{GenCertError: errors.New("error")},
+			ErrorApply,
+			"Failed to create root. Error: error",
+		},
+	}
+
+	t.Setenv("ROOT_PASSWORD", "rootSecret")
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			args := test.args
+			if test.manifest != "" {
+				args = append(args, "-file="+writeManifest(t, test.manifest))
+			}
+
+			mockUI := newMockUI(strings.NewReader(test.input))
+			cmd := &ApplyCommand{
+				ui:    mockUI,
+				pki:   test.manager,
+				stdin: strings.NewReader(""),
+				now:   time.Now,
+			}
+
+			exit := cmd.Run(args)
+			assert.Equal(t, test.expectedExit, exit)
+			assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+		})
+	}
+}
+
+func TestApplierPassword(t *testing.T) {
+	t.Setenv("SRE_PASSWORD", "sreSecret")
+
+	manifest := &pki.Manifest{
+		Certs: []pki.ManifestCert{
+			{Name: "root", Type: "root"},
+			{Name: "sre", Type: "intermediate", CA: "root", PasswordEnv: "SRE_PASSWORD"},
+			{Name: "ops", Type: "intermediate", CA: "root"},
+		},
+	}
+
+	mockUI := newMockUI(strings.NewReader(""))
+	a := &applier{
+		ui:        mockUI,
+		in:        parseInput(t, []string{"-non-interactive"}, "sharedSecret\n"),
+		manifest:  manifest,
+		passwords: map[string]string{},
+	}
+
+	password, err := a.password("sre")
+	assert.NoError(t, err)
+	assert.Equal(t, "sreSecret", password)
+
+	// The password from standard input is read once and shared
+	password, err = a.password("root")
+	assert.NoError(t, err)
+	assert.Equal(t, "sharedSecret", password)
+
+	password, err = a.password("ops")
+	assert.NoError(t, err)
+	assert.Equal(t, "sharedSecret", password)
+
+	assert.Empty(t, mockUI.OutputWriter.String())
+}
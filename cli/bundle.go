@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	bundleSuccess = "\n ✓ Wrote trust bundle to %s\n"
+
+	bundleSynopsis = `Writes a trust bundle of certificate authorities in workspace.`
+	bundleHelp     = `
+	You can use this command to write the certificates of all active root and intermediate certificate authorities
+	into a single trust bundle. Revoked and expired certificate authorities are left out.
+
+	The pem format is a list of concatenated PEM certificates used by most applications (nginx, envoy, curl, etc.).
+	The der format is a DER-encoded PKCS#7 bundle (p7b) used by Windows and Java applications.
+
+	Flags:
+		-format    the bundle format: pem or der (default: pem)
+		-out       the path to bundle file or - for standard output (default: ca-bundle.<format>)
+	`
+)
+
+// BundleCommand represents the bundle command
+type BundleCommand struct {
+	ui  cli.Ui
+	now func() time.Time
+}
+
+// NewBundleCommand creates a new command
+func NewBundleCommand() *BundleCommand {
+	return &BundleCommand{
+		ui:  newColoredUI(),
+		now: time.Now,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *BundleCommand) Synopsis() string {
+	return bundleSynopsis
+}
+
+// Help returns the long help text for command
+func (c *BundleCommand) Help() string {
+	return bundleHelp
+}
+
+// Run executes the command
+func (c *BundleCommand) Run(args []string) int {
+	var fFormat, fOut string
+
+	flags := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fFormat, "format", pki.BundleFormatPEM, "")
+	flags.StringVar(&fOut, "out", "", "")
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fFormat != pki.BundleFormatPEM && fFormat != pki.BundleFormatDER {
+		c.ui.Error("Bundle format is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	// Binary bundles are not written to terminal
+	if fFormat == pki.BundleFormatDER && fOut == "-" {
+		c.ui.Error("Bundle in der format cannot be written to standard output.")
+		return ErrorInvalidFlag
+	}
+
+	_, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	data, err := pki.TrustBundle(fFormat, c.now())
+	if err != nil {
+		c.ui.Error("Failed to create trust bundle. Error: " + err.Error())
+		return ErrorBundle
+	}
+
+	if fOut == "-" {
+		c.ui.Output(string(data))
+		return 0
+	}
+
+	if fOut == "" {
+		fOut = "ca-bundle." + fFormat
+	}
+
+	err = os.WriteFile(fOut, data, 0644)
+	if err != nil {
+		c.ui.Error("Failed to write " + fOut + ". Error: " + err.Error())
+		return ErrorBundle
+	}
+
+	c.ui.Info(fmt.Sprintf(bundleSuccess, fOut))
+
+	return 0
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"encoding/pem"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBundleCommand(t *testing.T) {
+	cmd := NewBundleCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.NotNil(t, cmd.now)
+
+	assert.Equal(t, "Writes a trust bundle of certificate authorities in workspace.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestBundleCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	writeListMocks(t)
+
+	tests := []struct {
+		title        string
+		args         []string
+		expectedFile string
+	}{
+		{"Default", []string{}, "ca-bundle.pem"},
+		{"PEM", []string{"-format=pem", "-out=trust.pem"}, "trust.pem"},
+		{"DER", []string{"-format=der"}, "ca-bundle.der"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &BundleCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run(test.args)
+			defer os.Remove(test.expectedFile)
+
+			assert.Zero(t, exit)
+
+			data, err := os.ReadFile(test.expectedFile)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, data)
+		})
+	}
+
+	t.Run("Stdout", func(t *testing.T) {
+		mockUI := newMockUI(strings.NewReader(""))
+		cmd := &BundleCommand{
+			ui:  mockUI,
+			now: time.Now,
+		}
+
+		exit := cmd.Run([]string{"-out=-"})
+		assert.Zero(t, exit)
+
+		count := 0
+		data := mockUI.OutputWriter.Bytes()
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			count++
+		}
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		mockUI := newMockUI(strings.NewReader(""))
+		cmd := &BundleCommand{
+			ui:  mockUI,
+			now: time.Now,
+		}
+
+		exit := cmd.Run([]string{"-out=missing/ca-bundle.pem"})
+		assert.Equal(t, ErrorBundle, exit)
+	})
+}
+
+func TestBundleCommandError(t *testing.T) {
+	tests := []struct {
+		title        string
+		noWorkspace  bool
+		args         []string
+		expectedExit int
+	}{
+		{"InvalidFlag", false, []string{"-invalid"}, ErrorInvalidFlag},
+		{"InvalidFormat", false, []string{"-format=p12"}, ErrorInvalidFlag},
+		{"DERStdout", false, []string{"-format=der", "-out=-"}, ErrorInvalidFlag},
+		{"NoState", true, []string{}, ErrorReadState},
+		{"NoCA", false, []string{}, ErrorBundle},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+			}
+
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &BundleCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
@@ -36,7 +36,8 @@ const (
 	You can later change these specs by editing "spec.toml" file.`
 	textEnterPolicyTips = `
 	You can specify the signing policy for certificate authorities.
-	Enter the name of each spec you want be matched/supplied as appeared in specs.`
+	Enter the name of each spec you want be matched/supplied as appeared in specs.
+	Other policy rules (allowed types, names, IP ranges, validity, and keys) can be set later in "spec.toml" file.`
 	textEnterConfigTips = `
 	Using passwords for certificate authorities is mandatory.
 	The password length should be at least 6 characters.`
@@ -45,6 +46,15 @@ const (
 	If you don't want to use any of the specs, leave it empty.`
 )
 
+var (
+	// policySkip are the rules of policies not asked when creating a spec
+	// They can be set later in spec file.
+	policySkip = []string{
+		"Policy.Types", "Policy.CommonNames", "Policy.DNSNames", "Policy.ForbidWildcard", "Policy.IPRanges",
+		"Policy.MaxDays", "Policy.Algorithms", "Policy.MinKeyLength", "Policy.RequiredOrganizationalUnits",
+	}
+)
+
 func newColoredUI() *cli.ColoredUi {
 	return &cli.ColoredUi{
 		OutputColor: cli.UiColorNone,
@@ -59,6 +69,35 @@ func newColoredUI() *cli.ColoredUi {
 	}
 }
 
+// stderrUI writes informational messages of a ui to its error stream
+// It keeps standard output clean when data is written to it.
+type stderrUI struct {
+	cli.Ui
+}
+
+// newStderrUI creates a ui that writes prompts and informational messages to the error stream of another ui
+func newStderrUI(ui cli.Ui) cli.Ui {
+	if colored, ok := ui.(*cli.ColoredUi); ok {
+		if basic, ok := colored.Ui.(*cli.BasicUi); ok {
+			b := *basic
+			b.Writer = b.ErrorWriter
+			c := *colored
+			c.Ui = &b
+			ui = &c
+		}
+	}
+
+	return &stderrUI{ui}
+}
+
+func (u *stderrUI) Output(message string) {
+	u.Ui.Warn(message)
+}
+
+func (u *stderrUI) Info(message string) {
+	u.Ui.Warn(message)
+}
+
 func loadWorkspace(ui cli.Ui) (*pki.State, *pki.Spec, int) {
 	state, err := pki.LoadState(pki.FileState)
 	if err != nil {
@@ -72,6 +111,12 @@ func loadWorkspace(ui cli.Ui) (*pki.State, *pki.Spec, int) {
 		return nil, nil, ErrorReadSpec
 	}
 
+	err = spec.Validate()
+	if err != nil {
+		ui.Error("Spec is not valid. Error: " + err.Error())
+		return nil, nil, ErrorInvalidSpec
+	}
+
 	return state, spec, 0
 }
 
@@ -91,6 +136,32 @@ func saveWorkspace(state *pki.State, spec *pki.Spec, ui cli.Ui) int {
 	return 0
 }
 
+// issuanceFor returns the config and usages of a certificate signed by a certificate authority from state and spec
+// A root certificate authority is signed by itself.
+func issuanceFor(state *pki.State, spec *pki.Spec, cCA, c pki.Cert) (pki.Config, pki.Usage) {
+	// Type field is ensured to be valid
+	config, _ := state.ConfigFor(c.Type)
+	usage, _ := spec.UsageFor(c.Type)
+
+	// Path length and name constraints of a certificate authority by its name take precedence over its type
+	if c.Type == pki.CertTypeRoot || c.Type == pki.CertTypeInterm {
+		authority := spec.AuthorityFor(c.Name)
+		if authority.MaxPathLen != nil {
+			config.MaxPathLen = authority.MaxPathLen
+		}
+		if c.Type == pki.CertTypeInterm {
+			usage.NameConstraints = authority.NameConstraints
+		}
+	}
+
+	// Distribution URLs and SPIFFE trust domain belong to the certificate authority signing the request
+	authorityCA := spec.AuthorityFor(cCA.Name)
+	usage.Distribution = authorityCA.Distribution
+	usage.SPIFFETrustDomain = authorityCA.SPIFFETrustDomain
+
+	return config, usage
+}
+
 func resolveByName(name string) pki.Cert {
 	var c pki.Cert
 
@@ -99,19 +170,15 @@ func resolveByName(name string) pki.Cert {
 		return c
 	}
 
-	c.Name, c.Type = name, pki.CertTypeInterm
-	if _, err := os.Stat(c.KeyPath()); err == nil {
-		return c
-	}
-
-	c.Name, c.Type = name, pki.CertTypeServer
-	if _, err := os.Stat(c.KeyPath()); err == nil {
-		return c
-	}
-
-	c.Name, c.Type = name, pki.CertTypeClient
-	if _, err := os.Stat(c.KeyPath()); err == nil {
-		return c
+	// Certificates issued for external requests do not have a key in workspace
+	for _, certType := range []int{pki.CertTypeInterm, pki.CertTypeServer, pki.CertTypeClient} {
+		c.Name, c.Type = name, certType
+		if _, err := os.Stat(c.KeyPath()); err == nil {
+			return c
+		}
+		if _, err := os.Stat(c.CertPath()); err == nil {
+			return c
+		}
 	}
 
 	return pki.Cert{}
@@ -209,15 +276,17 @@ func askForNewSpec(ui cli.Ui) (*pki.Spec, error) {
 	ui.Info(textEnterPolicyTips)
 
 	rootPolicy := pki.Policy{}
+	rootPolicySkip := append([]string{}, policySkip...)
 	ui.Output(textRootEnterPolicy)
-	err = util.AskForStruct(&rootPolicy, "toml", true, nil, ui)
+	err = util.AskForStruct(&rootPolicy, "toml", true, &rootPolicySkip, ui)
 	if err != nil {
 		return nil, err
 	}
 
 	intermPolicy := pki.Policy{}
+	intermPolicySkip := append([]string{}, policySkip...)
 	ui.Output(textIntermEnterPolicy)
-	err = util.AskForStruct(&intermPolicy, "toml", true, nil, ui)
+	err = util.AskForStruct(&intermPolicy, "toml", true, &intermPolicySkip, ui)
 	if err != nil {
 		return nil, err
 	}
@@ -236,6 +305,9 @@ func askForNewSpec(ui cli.Ui) (*pki.Spec, error) {
 		metadata[mdClientSkip] = clientSkip
 	}
 
+	// Key usages are not asked and can be changed in spec file
+	defaults := pki.NewSpec()
+
 	spec := &pki.Spec{
 		Root:         root,
 		Interm:       interm,
@@ -243,6 +315,9 @@ func askForNewSpec(ui cli.Ui) (*pki.Spec, error) {
 		Client:       client,
 		RootPolicy:   rootPolicy,
 		IntermPolicy: intermPolicy,
+		IntermUsage:  defaults.IntermUsage,
+		ServerUsage:  defaults.ServerUsage,
+		ClientUsage:  defaults.ClientUsage,
 		Metadata:     metadata,
 	}
 
@@ -80,6 +80,36 @@ func TestLoadWorkspace(t *testing.T) {
 			expectedState:  &pki.State{},
 			expectedSpec:   &pki.Spec{},
 		},
+		{
+			title:          "InvalidAuthority",
+			stateFixture:   "./fixture/loadWorkspace/empty.yaml",
+			specFixture:    "./fixture/loadWorkspace/invalid-authority.toml",
+			expectedStatus: ErrorInvalidSpec,
+			expectedState:  &pki.State{},
+			expectedSpec:   &pki.Spec{},
+		},
+		{
+			title:          "Authority",
+			stateFixture:   "./fixture/loadWorkspace/empty.yaml",
+			specFixture:    "./fixture/loadWorkspace/authority.toml",
+			expectedStatus: 0,
+			expectedState:  &pki.State{},
+			expectedSpec: &pki.Spec{
+				Authorities: map[string]pki.Authority{
+					"ops": pki.Authority{
+						Distribution: pki.Distribution{
+							CRLDistributionPoints:  []string{"http://pki.example.com/crl/ops.crl"},
+							OCSPServers:            []string{"http://ocsp.example.com"},
+							IssuingCertificateURLs: []string{"http://pki.example.com/ops.cert"},
+						},
+						MaxPathLen: new(int),
+						NameConstraints: pki.NameConstraints{
+							PermittedDNSDomains: []string{".ops.example.com"},
+						},
+					},
+				},
+			},
+		},
 		{
 			title:          "Simple",
 			stateFixture:   "./fixture/loadWorkspace/simple.yaml",
@@ -379,6 +409,12 @@ func TestResolveByName(t *testing.T) {
 			"client",
 			pki.Cert{Name: "client", Type: pki.CertTypeClient},
 		},
+		{
+			"ResolveServerWithoutKey",
+			path.Join(pki.DirServer, "external.cert"),
+			"external",
+			pki.Cert{Name: "external", Type: pki.CertTypeServer},
+		},
 	}
 
 	err := pki.NewWorkspace(nil, nil)
@@ -414,6 +450,7 @@ func TestAskForNewState(t *testing.T) {
 		{
 			"ErrorNoInputForInterm",
 			`10
+			rsa
 			4096
 			7300
 			`,
@@ -423,9 +460,11 @@ func TestAskForNewState(t *testing.T) {
 		{
 			"ErrorNoInputForServer",
 			`10
+			rsa
 			4096
 			7300
 			100
+			rsa
 			4096
 			3650
 			`,
@@ -435,12 +474,15 @@ func TestAskForNewState(t *testing.T) {
 		{
 			"ErrorNoInputForClient",
 			`10
+			rsa
 			4096
 			7300
 			100
+			rsa
 			4096
 			3650
 			1000
+			ecdsa
 			2048
 			375
 			`,
@@ -450,9 +492,11 @@ func TestAskForNewState(t *testing.T) {
 		{
 			"SuccessEnterSome",
 			`10
+			rsa
 			4096
 			7300
 			100
+			rsa
 			4096
 			3650
 
@@ -461,56 +505,75 @@ func TestAskForNewState(t *testing.T) {
 
 
 
+
+
 			`,
 			false,
 			&pki.State{
 				Root: pki.Config{
-					Serial: 10,
-					Length: 4096,
-					Days:   7300,
+					Serial:    10,
+					Algorithm: "rsa",
+					Length:    4096,
+					Days:      7300,
 				},
 				Interm: pki.Config{
-					Serial: 100,
-					Length: 4096,
-					Days:   3650,
+					Serial:    100,
+					Algorithm: "rsa",
+					Length:    4096,
+					Days:      3650,
+				},
+				Server: pki.Config{
+					Algorithm: "rsa",
+				},
+				Client: pki.Config{
+					Algorithm: "rsa",
 				},
 			},
 		},
 		{
 			"SuccessEnterAll",
 			`10
+			rsa
 			4096
 			7300
 			100
+			rsa
 			4096
 			3650
 			1000
+			ecdsa
 			2048
 			375
 			10000
+			ed25519
 			2048
 			40
 			`,
 			false,
 			&pki.State{
 				Root: pki.Config{
-					Serial: 10,
-					Length: 4096,
-					Days:   7300,
+					Serial:    10,
+					Algorithm: "rsa",
+					Length:    4096,
+					Days:      7300,
 				},
 				Interm: pki.Config{
-					Serial: 100,
-					Length: 4096,
-					Days:   3650,
+					Serial:    100,
+					Algorithm: "rsa",
+					Length:    4096,
+					Days:      3650,
 				},
 				Server: pki.Config{
-					Serial: 1000,
-					Length: 2048, Days: 375,
+					Serial:    1000,
+					Algorithm: "ecdsa",
+					Length:    2048,
+					Days:      375,
 				},
 				Client: pki.Config{
-					Serial: 10000,
-					Length: 2048,
-					Days:   40,
+					Serial:    10000,
+					Algorithm: "ed25519",
+					Length:    2048,
+					Days:      40,
 				},
 			},
 		},
@@ -548,62 +611,62 @@ func TestAskForNewSpec(t *testing.T) {
 		},
 		{
 			"ErrorNoInputForRoot",
-			"\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n",
 			true,
 			nil,
 		},
 		{
 			"ErrorNoInputForInterm",
-			"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n",
 			true,
 			nil,
 		},
 		{
 			"ErrorNoInputForServer",
-			"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n",
 			true,
 			nil,
 		},
 		{
 			"ErrorNoInputForClient",
-			"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n",
 			true,
 			nil,
 		},
 		{
 			"ErrorNoInputForRootPolicy",
-			"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n",
 			true,
 			nil,
 		},
 		{
 			"ErrorNoInputForIntermPolicy",
-			"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
+			"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
 				"\n\n",
 			true,
 			nil,
 		},
 		{
 			"SuccessSimple",
-			"CA\n\n\nMilad\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n" +
+			"CA\n\n\nMilad\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n" +
 				"\n\n" +
 				"\n\n",
 			false,
@@ -630,16 +693,22 @@ func TestAskForNewSpec(t *testing.T) {
 				IntermPolicy: pki.Policy{
 					Supplied: []string{"CommonName"},
 				},
+				ServerUsage: pki.Usage{
+					ExtKeyUsage: []string{"ServerAuth"},
+				},
+				ClientUsage: pki.Usage{
+					ExtKeyUsage: []string{"ClientAuth"},
+				},
 				Metadata: pki.Metadata{},
 			},
 		},
 		{
 			"SuccessComplex",
-			"CA\nOntario\n\nMilad\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n" +
-				"Ottawa\nSRE\n\n\n\n\n\n" +
-				"Toronto,Montreal\nR&D\nexample.com\n127.0.0.1\n\n\n\n" +
-				"Ottawa\n\n\n\nmilad@example.com\n\n\n" +
+			"CA\nOntario\n\nMilad\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n" +
+				"Ottawa\nSRE\n\n\n\n\n\n\n" +
+				"Toronto,Montreal\nR&D\nexample.com\n127.0.0.1\n\n\n\n\n" +
+				"Ottawa\n\n\n\nmilad@example.com\n\n\n\n" +
 				"Country,Organization\nCommonName\n" +
 				"Organization\nCommonName\n",
 			false,
@@ -680,16 +749,22 @@ func TestAskForNewSpec(t *testing.T) {
 					Match:    []string{"Organization"},
 					Supplied: []string{"CommonName"},
 				},
+				ServerUsage: pki.Usage{
+					ExtKeyUsage: []string{"ServerAuth"},
+				},
+				ClientUsage: pki.Usage{
+					ExtKeyUsage: []string{"ClientAuth"},
+				},
 				Metadata: pki.Metadata{},
 			},
 		},
 		{
 			"SuccessWithSkip",
-			"CA\n\n\nMilad\n\n\n\n\n-\n-\n" +
-				"\n\n\n-\n-\n\n" +
-				"\n\nSRE\n-\n-\n\n" +
-				"\nToronto,Montreal\nR&D\nexample.com\n127.0.0.1\n\n" +
-				"\nOttawa\n\n\n\nmilad@example.com\n" +
+			"CA\n\n\nMilad\n\n\n\n\n\n-\n-\n" +
+				"\n\n\n-\n-\n\n\n" +
+				"\n\nSRE\n-\n-\n\n\n" +
+				"\nToronto,Montreal\nR&D\nexample.com\n127.0.0.1\n\n\n" +
+				"\nOttawa\n\n\n\nmilad@example.com\n\n" +
 				"Country,Organization\nCommonName\n" +
 				"Organization\nCommonName\n",
 			false,
@@ -725,6 +800,12 @@ func TestAskForNewSpec(t *testing.T) {
 					Match:    []string{"Organization"},
 					Supplied: []string{"CommonName"},
 				},
+				ServerUsage: pki.Usage{
+					ExtKeyUsage: []string{"ServerAuth"},
+				},
+				ClientUsage: pki.Usage{
+					ExtKeyUsage: []string{"ClientAuth"},
+				},
 				Metadata: pki.Metadata{
 					mdRootSkip:   []string{"Claim.StreetAddress", "Claim.PostalCode", "Claim.DNSName", "Claim.IPAddress"},
 					mdIntermSkip: []string{"Claim.StreetAddress", "Claim.PostalCode", "Claim.DNSName", "Claim.IPAddress"},
@@ -779,6 +860,7 @@ func TestAskForConfig(t *testing.T) {
 			},
 			nil,
 			`10
+			rsa
 			4096
 			7300
 			secret
@@ -786,10 +868,11 @@ func TestAskForConfig(t *testing.T) {
 			`,
 			false,
 			&pki.Config{
-				Serial:   10,
-				Length:   4096,
-				Days:     7300,
-				Password: "secret",
+				Serial:    10,
+				Algorithm: "rsa",
+				Length:    4096,
+				Days:      7300,
+				Password:  "secret",
 			},
 		},
 		{
@@ -802,15 +885,17 @@ func TestAskForConfig(t *testing.T) {
 			},
 			nil,
 			`100
+			
 			4096
 			3650
 			`,
 			false,
 			&pki.Config{
-				Serial:   100,
-				Length:   4096,
-				Days:     3650,
-				Password: "password",
+				Serial:    100,
+				Algorithm: "rsa",
+				Length:    4096,
+				Days:      3650,
+				Password:  "password",
 			},
 		},
 		{
@@ -821,14 +906,16 @@ func TestAskForConfig(t *testing.T) {
 			},
 			nil,
 			`1000
+			ecdsa
 			2048
 			375
 			`,
 			false,
 			&pki.Config{
-				Serial: 1000,
-				Length: 2048,
-				Days:   375,
+				Serial:    1000,
+				Algorithm: "ecdsa",
+				Length:    2048,
+				Days:      375,
 			},
 		},
 		{
@@ -839,14 +926,16 @@ func TestAskForConfig(t *testing.T) {
 			},
 			nil,
 			`10000
+			ed25519
 			2048
 			40
 			`,
 			false,
 			&pki.Config{
-				Serial: 10000,
-				Length: 2048,
-				Days:   40,
+				Serial:    10000,
+				Algorithm: "ed25519",
+				Length:    2048,
+				Days:      40,
 			},
 		},
 		{
@@ -854,13 +943,15 @@ func TestAskForConfig(t *testing.T) {
 			&pki.Config{},
 			pki.Cert{},
 			[]string{"Config.Serial", "Config.Password"},
-			`2048
+			`ecdsa
+			384
 			365
 			`,
 			false,
 			&pki.Config{
-				Length: 2048,
-				Days:   365,
+				Algorithm: "ecdsa",
+				Length:    384,
+				Days:      365,
 			},
 		},
 	}
@@ -910,7 +1001,7 @@ func TestAskForClaim(t *testing.T) {
 				Type: pki.CertTypeRoot,
 			},
 			[]string{"Claim.StreetAddress", "Claim.PostalCode"},
-			"RootCA\n\n\n\n\n\n\n",
+			"RootCA\n\n\n\n\n\n\n\n",
 			false,
 			&pki.Claim{
 				CommonName:   "RootCA",
@@ -928,7 +1019,7 @@ func TestAskForClaim(t *testing.T) {
 				Type: pki.CertTypeInterm,
 			},
 			[]string{"Claim.StreetAddress", "Claim.PostalCode"},
-			"IntermediateCA\n\n\nSRE\n\n\n\n",
+			"IntermediateCA\n\n\nSRE\n\n\n\n\n",
 			false,
 			&pki.Claim{
 				CommonName:         "IntermediateCA",
@@ -947,7 +1038,7 @@ func TestAskForClaim(t *testing.T) {
 				Type: pki.CertTypeServer,
 			},
 			[]string{"Claim.StreetAddress", "Claim.PostalCode"},
-			"Server\n\n\nR&D\nexample.com\n127.0.0.1,8.8.8.8\n\n",
+			"Server\n\n\nR&D\nexample.com\n127.0.0.1,8.8.8.8\n\n\n",
 			false,
 			&pki.Claim{
 				CommonName:         "Server",
@@ -968,7 +1059,7 @@ func TestAskForClaim(t *testing.T) {
 				Type: pki.CertTypeClient,
 			},
 			[]string{"Claim.StreetAddress", "Claim.PostalCode"},
-			"Client\nOntario\nOttawa\nQE\n\n\n\n",
+			"Client\nOntario\nOttawa\nQE\n\n\n\nspiffe://example.com/client\n",
 			false,
 			&pki.Claim{
 				CommonName:         "Client",
@@ -977,6 +1068,7 @@ func TestAskForClaim(t *testing.T) {
 				Locality:           []string{"Ottawa"},
 				Organization:       []string{"Milad"},
 				OrganizationalUnit: []string{"QE"},
+				URI:                []string{"spiffe://example.com/client"},
 			},
 		},
 	}
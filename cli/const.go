@@ -40,6 +40,8 @@ const (
 	ErrorInvalidCSR = 34
 	// ErrorInvalidCert is returned when an invalid cert is set
 	ErrorInvalidCert = 35
+	// ErrorInvalidSpec is returned when spec has invalid settings
+	ErrorInvalidSpec = 36
 
 	// ErrorCert is returned when generating root ca fails
 	ErrorCert = 41
@@ -49,4 +51,32 @@ const (
 	ErrorSign = 43
 	// ErrorVerify is returned when verifying a cert fails
 	ErrorVerify = 44
+	// ErrorRekey is returned when re-encrypting a key fails
+	ErrorRekey = 45
+	// ErrorRevoke is returned when revoking a cert fails
+	ErrorRevoke = 46
+	// ErrorCRL is returned when generating a crl fails
+	ErrorCRL = 47
+	// ErrorOCSP is returned when running an ocsp responder fails
+	ErrorOCSP = 48
+	// ErrorList is returned when listing certs fails
+	ErrorList = 49
+	// ErrorInspect is returned when inspecting a file fails
+	ErrorInspect = 50
+	// ErrorRenew is returned when renewing a cert fails
+	ErrorRenew = 51
+	// ErrorExport is returned when exporting a cert fails
+	ErrorExport = 52
+	// ErrorBundle is returned when writing a trust bundle fails
+	ErrorBundle = 53
+	// ErrorImport is returned when importing a ca fails
+	ErrorImport = 54
+	// ErrorManifest is returned when reading a manifest fails
+	ErrorManifest = 55
+	// ErrorPlan is returned when planning a manifest fails
+	ErrorPlan = 56
+	// ErrorApply is returned when applying a manifest fails
+	ErrorApply = 57
+	// ErrorCheck is returned when a csr does not satisfy trust policy or checking it fails
+	ErrorCheck = 58
 )
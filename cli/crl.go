@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	crlSuccess     = "\n ✓ Generated certificate revocation list for %s\n"
+	crlEnterNameCA = "\nENTER NAME FOR CERTIFICATE AUTHORITY ..."
+
+	defaultCRLDays = 7
+
+	crlSynopsis = `Generates a certificate revocation list for a certificate authority.`
+	crlHelp     = `
+	You can use this command to generate a new certificate revocation list (CRL) for a certificate authority.
+	The CRL is signed by the certificate authority and includes all certificates revoked by it.
+	Every new CRL gets the next CRL number.
+
+	You will be asked for entering the password for certificate authorithy.
+	Using -non-interactive flag, nothing is asked and -ca and the password are required.
+	These flags can also be set by GOCERT_NON_INTERACTIVE, GOCERT_PASSWORD_FILE, and GOCERT_PASSWORD_ENV environment variables.
+
+	Flags:
+		-ca                 the name of certificate authorithy
+		-days               the number of days until the next update of CRL (default: 7)
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
+	`
+)
+
+// CRLCommand represents the crl command
+type CRLCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+}
+
+// NewCRLCommand creates a new command
+func NewCRLCommand() *CRLCommand {
+	return &CRLCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *CRLCommand) Synopsis() string {
+	return crlSynopsis
+}
+
+// Help returns the long help text for command
+func (c *CRLCommand) Help() string {
+	return crlHelp
+}
+
+// Run executes the command
+func (c *CRLCommand) Run(args []string) int {
+	var fCA string
+	var fDays int
+
+	flags := flag.NewFlagSet("crl", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fCA, "ca", "", "")
+	flags.IntVar(&fDays, "days", defaultCRLDays, "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fCA == "" {
+		c.ui.Output(crlEnterNameCA)
+		fCA, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "CA Name", "string"), "ca")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	state, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	cCA := resolveByName(fCA)
+	if cCA.Type != pki.CertTypeRoot && cCA.Type != pki.CertTypeInterm {
+		c.ui.Error("Certificate authority name is not valid.")
+		return ErrorInvalidCA
+	}
+
+	// Type field is ensured to be valid
+	configCA, _ := state.ConfigFor(cCA.Type)
+	err = in.askForConfig(&configCA, cCA, nil, c.ui)
+	if err != nil {
+		return ErrorEnterConfig
+	}
+
+	err = c.pki.GenCRL(configCA, cCA, fDays)
+	if err != nil {
+		c.ui.Error("Failed to generate certificate revocation list. Error: " + err.Error())
+		return ErrorCRL
+	}
+
+	c.ui.Info(fmt.Sprintf(crlSuccess, cCA.Name))
+
+	return 0
+}
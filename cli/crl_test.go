@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCRLCommand(t *testing.T) {
+	cmd := NewCRLCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+
+	assert.Equal(t, "Generates a certificate revocation list for a certificate authority.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestCRLCommand(t *testing.T) {
+	tests := []struct {
+		title string
+		args  []string
+		input string
+	}{
+		{
+			"Root",
+			[]string{},
+			`root
+			password
+			password
+			`,
+		},
+		{
+			"Intermediate",
+			[]string{"-ca=ops", "-days=30"},
+			`password
+			password
+			`,
+		},
+		{
+			"NonInteractive",
+			[]string{"-non-interactive", "-ca=ops"},
+			"password\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+			})
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &CRLCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.True(t, manager.GenCRLCalled)
+		})
+	}
+}
+
+func TestCRLCommandError(t *testing.T) {
+	tests := []struct {
+		title        string
+		noWorkspace  bool
+		args         []string
+		input        string
+		GenCRLError  error
+		expectedExit int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]string{"-invalid"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoCAName",
+			false,
+			[]string{},
+			``,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoCAName",
+			false,
+			[]string{"-non-interactive"},
+			``,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NoState",
+			true,
+			[]string{"-ca=root"},
+			``,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"InvalidCA",
+			false,
+			[]string{"-ca=server"},
+			``,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"NoPassword",
+			false,
+			[]string{"-ca=root"},
+			``,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"GenCRLError",
+			false,
+			[]string{"-ca=root"},
+			`password
+			password
+			`,
+			errors.New("error"),
+			ErrorCRL,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+
+				writeSignMocks(t, []pki.Cert{
+					pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+					pki.Cert{Name: "server", Type: pki.CertTypeServer},
+				})
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &CRLCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					GenCRLError: test.GenCRLError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
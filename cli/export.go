@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+	"github.com/moorara/gocert/util"
+)
+
+const (
+	exportSuccess       = "\n ✓ Exported %s to %s\n"
+	exportTrustSuccess  = "\n ✓ Exported certificate authorities to %s\n"
+	exportEnterNameCert = "\nENTER NAME FOR CERTIFICATE ..."
+	exportEnterPassword = "\nENTER PASSWORD FOR EXPORTED FILE ..."
+
+	exportSynopsis = `Exports a certificate and its key for other applications.`
+	exportHelp     = `
+	You can use this command to export a certificate, its private key, and its issuing chain for other applications.
+
+	The p12 format is a password-protected PKCS#12 (PFX) bundle used by Windows and Java applications.
+	By default, it is encrypted using AES-256 and PBKDF2 with SHA-256.
+	You can use -legacy flag for older Windows and Java versions that only support 3DES and SHA-1.
+
+	The jks format is a password-protected Java KeyStore with a named private key entry.
+	You can use -alias flag to set the name of private key entry.
+
+	You can use -truststore flag to also export the certificates of all certificate authorities
+	into a separate truststore in the same format and with the same password.
+
+	The secret format is a kubernetes.io/tls Secret manifest for an intermediate, server, or client certificate.
+	tls.crt contains the certificate and its intermediates, tls.key contains the private key, and ca.crt contains the root.
+	The secret of an intermediate certificate authority can be used by cert-manager CA issuers.
+	Secrets are written to standard output unless -out flag is set, and the private key in them is NOT encrypted.
+
+	You will be asked for entering the password for certificate authorithy (if exporting one)
+	and the password for the exported file.
+
+	Using -non-interactive flag, nothing is asked and -name is required.
+	The password is also required, unless a server or client certificate is exported in secret format.
+	It is read from -password-file, -password-env, or a line of standard input,
+	and is used for both the certificate authorithy (if exporting one) and the exported file.
+
+	Flags:
+		-name               the name of certificate
+		-format             the export format: p12, jks, or secret (default: p12)
+		-out                the path to exported file or - for standard output (default: <name>.<format>)
+		-alias              the alias of private key entry for jks (default: <name>)
+		-truststore         the path to truststore file for certificate authorities (optional)
+		-legacy             use legacy encryption for PKCS#12 (default: false)
+		-secret-name        the name of Kubernetes secret (default: <name>)
+		-namespace          the namespace of Kubernetes secret (optional)
+		-labels             the labels of Kubernetes secret as comma-separated key=value pairs (optional)
+		-non-interactive    never prompt for values
+		-password-file      read the password from a file
+		-password-env       read the password from an environment variable
+	`
+)
+
+// exportSecret is the password for protecting exported files
+type exportSecret struct {
+	ExportPassword string `secret:"required,6"`
+}
+
+// parseLabels parses comma-separated key=value pairs
+func parseLabels(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	labels := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, errors.New("invalid label: " + pair)
+		}
+		labels[key] = strings.TrimSpace(kv[1])
+	}
+
+	return labels, nil
+}
+
+// ExportCommand represents the export command
+type ExportCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+}
+
+// NewExportCommand creates a new command
+func NewExportCommand() *ExportCommand {
+	return &ExportCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *ExportCommand) Synopsis() string {
+	return exportSynopsis
+}
+
+// Help returns the long help text for command
+func (c *ExportCommand) Help() string {
+	return exportHelp
+}
+
+// Run executes the command
+func (c *ExportCommand) Run(args []string) int {
+	var fName, fFormat, fOut, fAlias, fTruststore string
+	var fSecretName, fNamespace, fLabels string
+	var fLegacy bool
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fName, "name", "", "")
+	flags.StringVar(&fFormat, "format", pki.ExportFormatP12, "")
+	flags.StringVar(&fOut, "out", "", "")
+	flags.StringVar(&fAlias, "alias", "", "")
+	flags.StringVar(&fTruststore, "truststore", "", "")
+	flags.BoolVar(&fLegacy, "legacy", false, "")
+	flags.StringVar(&fSecretName, "secret-name", "", "")
+	flags.StringVar(&fNamespace, "namespace", "", "")
+	flags.StringVar(&fLabels, "labels", "", "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fFormat != pki.ExportFormatP12 && fFormat != pki.ExportFormatJKS && fFormat != pki.ExportFormatSecret {
+		c.ui.Error("Export format is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	if fFormat == pki.ExportFormatSecret && fTruststore != "" {
+		c.ui.Error("Truststore cannot be exported in secret format.")
+		return ErrorInvalidFlag
+	}
+
+	labels, err := parseLabels(fLabels)
+	if err != nil {
+		c.ui.Error("Labels are not valid. Error: " + err.Error())
+		return ErrorInvalidFlag
+	}
+
+	if fName == "" {
+		c.ui.Output(exportEnterNameCert)
+		fName, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "Cert Name", "string"), "name")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	state, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	cCert := resolveByName(fName)
+	if cCert.Type == 0 {
+		c.ui.Error("Certificate name is not valid.")
+		return ErrorInvalidCert
+	}
+
+	// Secrets are written to standard output by default
+	ui := c.ui
+	if fFormat == pki.ExportFormatSecret && (fOut == "" || fOut == "-") {
+		ui = newStderrUI(c.ui)
+	}
+
+	// Type field is ensured to be valid
+	config, _ := state.ConfigFor(cCert.Type)
+	err = in.askForConfig(&config, cCert, nil, ui)
+	if err != nil {
+		return ErrorEnterConfig
+	}
+
+	if fFormat == pki.ExportFormatSecret {
+		return c.runSecret(config, cCert, fOut, pki.SecretMeta{
+			Name:      fSecretName,
+			Namespace: fNamespace,
+			Labels:    labels,
+		})
+	}
+
+	secret := exportSecret{}
+	c.ui.Output(exportEnterPassword)
+	secret.ExportPassword, err = in.askPassword(c.ui, func() (string, error) {
+		err := util.AskForStruct(&secret, "yaml", false, nil, c.ui)
+		return secret.ExportPassword, err
+	})
+	if err != nil {
+		return ErrorEnterConfig
+	}
+
+	var data []byte
+	if fFormat == pki.ExportFormatJKS {
+		if fAlias == "" {
+			fAlias = cCert.Name
+		}
+		data, err = c.pki.ExportJKS(config, cCert, fAlias, secret.ExportPassword)
+	} else {
+		data, err = c.pki.ExportPKCS12(config, cCert, secret.ExportPassword, fLegacy)
+	}
+
+	if err != nil {
+		c.ui.Error("Failed to export certificate. Error: " + err.Error())
+		return ErrorExport
+	}
+
+	if fOut == "" {
+		fOut = cCert.Name + "." + fFormat
+	}
+
+	// Exported file contains the private key
+	err = os.WriteFile(fOut, data, 0600)
+	if err != nil {
+		c.ui.Error("Failed to write " + fOut + ". Error: " + err.Error())
+		return ErrorExport
+	}
+
+	c.ui.Info(fmt.Sprintf(exportSuccess, cCert.Name, fOut))
+
+	if fTruststore != "" {
+		data, err = pki.ExportTruststore(fFormat, secret.ExportPassword, fLegacy)
+		if err != nil {
+			c.ui.Error("Failed to export truststore. Error: " + err.Error())
+			return ErrorExport
+		}
+
+		err = os.WriteFile(fTruststore, data, 0644)
+		if err != nil {
+			c.ui.Error("Failed to write " + fTruststore + ". Error: " + err.Error())
+			return ErrorExport
+		}
+
+		c.ui.Info(fmt.Sprintf(exportTrustSuccess, fTruststore))
+	}
+
+	return 0
+}
+
+// runSecret exports a certificate as a Kubernetes secret manifest
+func (c *ExportCommand) runSecret(config pki.Config, cCert pki.Cert, out string, meta pki.SecretMeta) int {
+	if meta.Name == "" {
+		meta.Name = cCert.Name
+	}
+
+	data, err := c.pki.ExportSecret(config, cCert, meta)
+	if err != nil {
+		c.ui.Error("Failed to export certificate. Error: " + err.Error())
+		return ErrorExport
+	}
+
+	if out == "" || out == "-" {
+		c.ui.Output(string(data))
+		return 0
+	}
+
+	// Exported manifest contains the unencrypted private key
+	err = os.WriteFile(out, data, 0600)
+	if err != nil {
+		c.ui.Error("Failed to write " + out + ". Error: " + err.Error())
+		return ErrorExport
+	}
+
+	c.ui.Info(fmt.Sprintf(exportSuccess, cCert.Name, out))
+
+	return 0
+}
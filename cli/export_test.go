@@ -0,0 +1,495 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExportCommand(t *testing.T) {
+	cmd := NewExportCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+
+	assert.Equal(t, "Exports a certificate and its key for other applications.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		s              string
+		expectedLabels map[string]string
+		expectedError  string
+	}{
+		{"", nil, ""},
+		{"app=webapp", map[string]string{"app": "webapp"}, ""},
+		{"app=webapp, tier = frontend,empty=", map[string]string{"app": "webapp", "tier": "frontend", "empty": ""}, ""},
+		{"app", nil, "invalid label: app"},
+		{"=webapp", nil, "invalid label: =webapp"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			labels, err := parseLabels(test.s)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedLabels, labels)
+			}
+		})
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	tests := []struct {
+		title        string
+		args         []string
+		input        string
+		expectedFile string
+		expectedData string
+	}{
+		{
+			"Server",
+			[]string{"-name=webapp"},
+			`exportSecret
+			exportSecret
+			`,
+			"webapp.p12",
+			"pkcs12",
+		},
+		{
+			"ClientLegacy",
+			[]string{"-format=p12", "-legacy"},
+			`service
+			exportSecret
+			exportSecret
+			`,
+			"service.p12",
+			"pkcs12",
+		},
+		{
+			"Intermediate",
+			[]string{"-name=ops", "-out=ops-bundle.pfx"},
+			`password
+			password
+			exportSecret
+			exportSecret
+			`,
+			"ops-bundle.pfx",
+			"pkcs12",
+		},
+		{
+			"ServerJKS",
+			[]string{"-name=webapp", "-format=jks", "-alias=tomcat"},
+			`exportSecret
+			exportSecret
+			`,
+			"webapp.jks",
+			"jks",
+		},
+		{
+			"IntermediateNonInteractive",
+			[]string{"-non-interactive", "-name=ops"},
+			"password\n",
+			"ops.p12",
+			"pkcs12",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "webapp", Type: pki.CertTypeServer},
+				pki.Cert{Name: "service", Type: pki.CertTypeClient},
+			})
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &ExportCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+			}
+
+			exit := cmd.Run(test.args)
+			defer os.Remove(test.expectedFile)
+
+			assert.Zero(t, exit)
+			assert.Equal(t, test.expectedData == "pkcs12", manager.ExportPKCS12Called)
+			assert.Equal(t, test.expectedData == "jks", manager.ExportJKSCalled)
+
+			data, err := os.ReadFile(test.expectedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedData, string(data))
+
+			info, err := os.Stat(test.expectedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		})
+	}
+}
+
+func TestExportCommandSecret(t *testing.T) {
+	tests := []struct {
+		title          string
+		args           []string
+		input          string
+		expectedFile   string
+		expectedOutput string
+	}{
+		{
+			"ServerStdout",
+			[]string{"-name=webapp", "-format=secret", "-namespace=web", "-labels=app=webapp"},
+			``,
+			"",
+			"secret",
+		},
+		{
+			"IntermediateFile",
+			[]string{"-name=ops", "-format=secret", "-secret-name=ops-ca", "-out=ops-ca.yaml"},
+			`password
+			password
+			`,
+			"ops-ca.yaml",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "webapp", Type: pki.CertTypeServer},
+			})
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &ExportCommand{
+				ui:  mockUI,
+				pki: manager,
+			}
+
+			exit := cmd.Run(test.args)
+
+			assert.Zero(t, exit)
+			assert.True(t, manager.ExportSecretCalled)
+			assert.False(t, manager.ExportPKCS12Called)
+
+			if test.expectedFile == "" {
+				assert.Equal(t, test.expectedOutput+"\n", mockUI.OutputWriter.String())
+				assert.Contains(t, mockUI.ErrorWriter.String(), "ENTER CONFIGURATIONS")
+				return
+			}
+
+			defer os.Remove(test.expectedFile)
+
+			data, err := os.ReadFile(test.expectedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", string(data))
+
+			info, err := os.Stat(test.expectedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		})
+	}
+}
+
+func TestExportCommandTruststore(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	tests := []struct {
+		title              string
+		args               []string
+		expectedTruststore string
+	}{
+		{"P12", []string{"-name=webapp", "-truststore=truststore.p12"}, "truststore.p12"},
+		{"JKS", []string{"-name=webapp", "-format=jks", "-truststore=truststore.jks"}, "truststore.jks"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeListMocks(t)
+
+			r := strings.NewReader(`exportSecret
+			exportSecret
+			`)
+			mockUI := newMockUI(r)
+			cmd := &ExportCommand{
+				ui:  mockUI,
+				pki: &mockManager{},
+			}
+
+			exit := cmd.Run(test.args)
+			defer os.Remove("webapp.p12")
+			defer os.Remove("webapp.jks")
+			defer os.Remove(test.expectedTruststore)
+
+			assert.Zero(t, exit)
+
+			data, err := os.ReadFile(test.expectedTruststore)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, data)
+		})
+	}
+}
+
+func TestExportCommandError(t *testing.T) {
+	tests := []struct {
+		title             string
+		noWorkspace       bool
+		args              []string
+		input             string
+		ExportPKCS12Error error
+		ExportJKSError    error
+		ExportSecretError error
+		expectedExit      int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]string{"-invalid"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"InvalidFormat",
+			false,
+			[]string{"-name=webapp", "-format=zip"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"InvalidLabels",
+			false,
+			[]string{"-name=webapp", "-format=secret", "-labels=app"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"SecretTruststore",
+			false,
+			[]string{"-name=webapp", "-format=secret", "-truststore=truststore.p12"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoName",
+			false,
+			[]string{},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoName",
+			false,
+			[]string{"-non-interactive"},
+			"webapp\n",
+			nil,
+			nil,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoExportPassword",
+			false,
+			[]string{"-non-interactive", "-name=webapp"},
+			`exportSecret
+			exportSecret
+			`,
+			nil,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoState",
+			true,
+			[]string{"-name=webapp"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"InvalidCert",
+			false,
+			[]string{"-name=unknown"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorInvalidCert,
+		},
+		{
+			"NoPassword",
+			false,
+			[]string{"-name=ops"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoExportPassword",
+			false,
+			[]string{"-name=webapp"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"ShortExportPassword",
+			false,
+			[]string{"-name=webapp"},
+			`short
+			short
+			`,
+			nil,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"ExportPKCS12Error",
+			false,
+			[]string{"-name=webapp"},
+			`exportSecret
+			exportSecret
+			`,
+			errors.New("error"),
+			nil,
+			nil,
+			ErrorExport,
+		},
+		{
+			"ExportJKSError",
+			false,
+			[]string{"-name=webapp", "-format=jks"},
+			`exportSecret
+			exportSecret
+			`,
+			nil,
+			errors.New("error"),
+			nil,
+			ErrorExport,
+		},
+		{
+			"TruststoreError",
+			false,
+			[]string{"-name=webapp", "-truststore=truststore.p12"},
+			`exportSecret
+			exportSecret
+			`,
+			nil,
+			nil,
+			nil,
+			ErrorExport,
+		},
+		{
+			"ExportSecretError",
+			false,
+			[]string{"-name=webapp", "-format=secret"},
+			``,
+			nil,
+			nil,
+			errors.New("error"),
+			ErrorExport,
+		},
+		{
+			"SecretWriteError",
+			false,
+			[]string{"-name=webapp", "-format=secret", "-out=missing/webapp.yaml"},
+			``,
+			nil,
+			nil,
+			nil,
+			ErrorExport,
+		},
+		{
+			"WriteError",
+			false,
+			[]string{"-name=webapp", "-out=missing/webapp.p12"},
+			`exportSecret
+			exportSecret
+			`,
+			nil,
+			nil,
+			nil,
+			ErrorExport,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+
+				writeSignMocks(t, []pki.Cert{
+					pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+					pki.Cert{Name: "webapp", Type: pki.CertTypeServer},
+				})
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &ExportCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					ExportPKCS12Error: test.ExportPKCS12Error,
+					ExportJKSError:    test.ExportJKSError,
+					ExportSecretError: test.ExportSecretError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			defer os.Remove("webapp.p12")
+			defer os.Remove("webapp.jks")
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
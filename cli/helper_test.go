@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 
 	"github.com/mitchellh/cli"
@@ -39,15 +40,39 @@ func (u *mockUI) AskSecret(query string) (string, error) {
 }
 
 type mockManager struct {
-	GenCertError    error
-	GenCSRError     error
-	SignCSRError    error
-	VerifyCertError error
+	GenCertError      error
+	GenCSRError       error
+	SignCSRError      error
+	CheckCSRError     error
+	VerifyCertError   error
+	ReencryptKeyError error
+	RevokeCertError   error
+	GenCRLError       error
+	OCSPHandlerError  error
+	RenewCertError    error
+	ReissueCertError  error
+	ExportPKCS12Error error
+	ExportJKSError    error
+	ExportSecretError error
+	ImportCAError     error
 
-	GenCertCalled    bool
-	GenCSRCalled     bool
-	SignCSRCalled    bool
-	VerifyCertCalled bool
+	GenCertCalled      bool
+	GenCSRCalled       bool
+	SignCSRCalled      bool
+	CheckCSRCalled     bool
+	VerifyCertCalled   bool
+	ReencryptKeyCalled bool
+	RevokeCertCalled   bool
+	GenCRLCalled       bool
+	OCSPHandlerCalled  bool
+	RenewCertCalled    bool
+	ReissueCertCalled  bool
+	ExportPKCS12Called bool
+	ExportJKSCalled    bool
+	ExportSecretCalled bool
+	ImportCACalled     bool
+
+	CheckCSRViolations []pki.Violation
 }
 
 func (m *mockManager) GenCert(pki.Config, pki.Claim, pki.Cert) error {
@@ -60,12 +85,82 @@ func (m *mockManager) GenCSR(pki.Config, pki.Claim, pki.Cert) error {
 	return m.GenCSRError
 }
 
-func (m *mockManager) SignCSR(pki.Config, pki.Cert, pki.Config, pki.Cert, pki.TrustFunc) error {
+func (m *mockManager) SignCSR(pki.Config, pki.Cert, pki.Config, pki.Cert, pki.Usage, pki.TrustFunc) error {
 	m.SignCSRCalled = true
 	return m.SignCSRError
 }
 
+func (m *mockManager) CheckCSR(pki.Cert, pki.Config, pki.Cert, pki.TrustFunc) ([]pki.Violation, error) {
+	m.CheckCSRCalled = true
+	if m.CheckCSRError != nil {
+		return nil, m.CheckCSRError
+	}
+	return m.CheckCSRViolations, nil
+}
+
 func (m *mockManager) VerifyCert(pki.Cert, pki.Cert, string) error {
 	m.VerifyCertCalled = true
 	return m.VerifyCertError
 }
+
+func (m *mockManager) ReencryptKey(pki.Config, pki.Cert) error {
+	m.ReencryptKeyCalled = true
+	return m.ReencryptKeyError
+}
+
+func (m *mockManager) RevokeCert(pki.Cert, pki.Cert, string) error {
+	m.RevokeCertCalled = true
+	return m.RevokeCertError
+}
+
+func (m *mockManager) GenCRL(pki.Config, pki.Cert, int) error {
+	m.GenCRLCalled = true
+	return m.GenCRLError
+}
+
+func (m *mockManager) OCSPHandler(pki.Config, pki.Cert, pki.Cert) (http.Handler, error) {
+	m.OCSPHandlerCalled = true
+	if m.OCSPHandlerError != nil {
+		return nil, m.OCSPHandlerError
+	}
+	return http.NotFoundHandler(), nil
+}
+
+func (m *mockManager) RenewCert(pki.Config, pki.Cert, pki.Config, pki.Cert, bool, pki.TrustFunc) error {
+	m.RenewCertCalled = true
+	return m.RenewCertError
+}
+
+func (m *mockManager) ReissueCert(pki.Config, pki.Cert, pki.Config, pki.Cert, pki.Claim, bool, pki.TrustFunc) error {
+	m.ReissueCertCalled = true
+	return m.ReissueCertError
+}
+
+func (m *mockManager) ExportPKCS12(pki.Config, pki.Cert, string, bool) ([]byte, error) {
+	m.ExportPKCS12Called = true
+	if m.ExportPKCS12Error != nil {
+		return nil, m.ExportPKCS12Error
+	}
+	return []byte("pkcs12"), nil
+}
+
+func (m *mockManager) ExportJKS(pki.Config, pki.Cert, string, string) ([]byte, error) {
+	m.ExportJKSCalled = true
+	if m.ExportJKSError != nil {
+		return nil, m.ExportJKSError
+	}
+	return []byte("jks"), nil
+}
+
+func (m *mockManager) ExportSecret(pki.Config, pki.Cert, pki.SecretMeta) ([]byte, error) {
+	m.ExportSecretCalled = true
+	if m.ExportSecretError != nil {
+		return nil, m.ExportSecretError
+	}
+	return []byte("secret"), nil
+}
+
+func (m *mockManager) ImportCA(pki.Config, pki.Cert, pki.ImportSource) error {
+	m.ImportCACalled = true
+	return m.ImportCAError
+}
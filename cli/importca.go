@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	importCASuccess          = "\n ✓ Imported %s as %s\n"
+	importCAEnterName        = "\nENTER NAME FOR INTERMEDIATE CERTIFICATE AUTHORITY ..."
+	importCAEnterKeyPassword = "\nENTER PASSWORD FOR IMPORTED KEY (LEAVE EMPTY IF NOT ENCRYPTED) ..."
+
+	importCASynopsis = `Imports an existing root or intermediate certificate authority.`
+	importCAHelp     = `
+	You can use this command to import an existing root or intermediate certificate authority into workspace,
+	so you can issue and manage certificates beneath it like a certificate authority created by gocert.
+
+	A self-signed certificate is imported as the root certificate authority (named root).
+	Any other certificate is imported as an intermediate certificate authority and needs its issuing chain.
+	The chain is read from -chain flag, or built from the certificate authority in workspace that has issued it.
+	The chain must end with a root certificate authority.
+
+	You will be asked for entering the password for imported key (if encrypted)
+	and a new password for storing the key in workspace.
+
+	Using -non-interactive flag, nothing is asked and the password (and -name for intermediates) is required.
+	The password is read from -password-file, -password-env, or a line of standard input.
+	It is used for both decrypting the imported key (if encrypted) and storing the key in workspace.
+
+	Flags:
+		-cert               the path to certificate file (PEM)
+		-key                the path to private key file (PEM)
+		-chain              the path to issuing chain file up to a root for intermediates (PEM) (optional)
+		-name               the name of intermediate certificate authority
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
+	`
+)
+
+// ImportCACommand represents the import-ca command
+type ImportCACommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+}
+
+// NewImportCACommand creates a new command
+func NewImportCACommand() *ImportCACommand {
+	return &ImportCACommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *ImportCACommand) Synopsis() string {
+	return importCASynopsis
+}
+
+// Help returns the long help text for command
+func (c *ImportCACommand) Help() string {
+	return importCAHelp
+}
+
+// Run executes the command
+func (c *ImportCACommand) Run(args []string) int {
+	var fCert, fKey, fChain, fName string
+
+	flags := flag.NewFlagSet("import-ca", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fCert, "cert", "", "")
+	flags.StringVar(&fKey, "key", "", "")
+	flags.StringVar(&fChain, "chain", "", "")
+	flags.StringVar(&fName, "name", "", "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fCert == "" || fKey == "" {
+		c.ui.Error("Certificate and key files are required.")
+		return ErrorInvalidFlag
+	}
+
+	state, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	certType, err := pki.DetectCAType(fCert)
+	if err != nil {
+		c.ui.Error("Certificate is not valid. Error: " + err.Error())
+		return ErrorImport
+	}
+
+	cCA := pki.Cert{Name: rootName, Type: certType}
+	if certType == pki.CertTypeInterm {
+		cCA.Name = fName
+		if cCA.Name == "" {
+			c.ui.Output(importCAEnterName)
+			cCA.Name, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "Name", "string"), "name")
+			if err != nil {
+				return ErrorInvalidName
+			}
+		}
+	}
+
+	src := pki.ImportSource{
+		CertPath:  fCert,
+		KeyPath:   fKey,
+		ChainPath: fChain,
+	}
+
+	if !in.isNonInteractive() {
+		c.ui.Output(importCAEnterKeyPassword)
+		src.KeyPassword, err = c.ui.AskSecret(fmt.Sprintf(promptTemplate, "Password", "string"))
+		if err != nil {
+			return ErrorEnterConfig
+		}
+	}
+
+	// Type field is ensured to be valid
+	config, _ := state.ConfigFor(cCA.Type)
+	err = in.askForConfig(&config, cCA, nil, c.ui)
+	if err != nil {
+		return ErrorEnterConfig
+	}
+
+	// An unencrypted key is read regardless of password
+	if in.isNonInteractive() {
+		src.KeyPassword = config.Password
+	}
+
+	err = c.pki.ImportCA(config, cCA, src)
+	if err != nil {
+		c.ui.Error("Failed to import certificate authority. Error: " + err.Error())
+		return ErrorImport
+	}
+
+	c.ui.Info(fmt.Sprintf(importCASuccess, fCert, cCA.Name))
+
+	return 0
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeImportMocks writes a root, an intermediate, and a leaf certificate outside workspace
+func writeImportMocks(t *testing.T) string {
+	dir := t.TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Corporate Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	interm := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Corporate Ops"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(5, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "webapp"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+	}
+
+	for name, cert := range map[string]*x509.Certificate{"root": root, "ops": interm, "webapp": leaf} {
+		parent := root
+		if cert == root {
+			parent = cert
+		}
+
+		data, err := x509.CreateCertificate(rand.Reader, cert, parent, key.Public(), key)
+		assert.NoError(t, err)
+
+		pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: data})
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pemData, 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), []byte("key"), 0600))
+	}
+
+	return dir
+}
+
+func TestNewImportCACommand(t *testing.T) {
+	cmd := NewImportCACommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+
+	assert.Equal(t, "Imports an existing root or intermediate certificate authority.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestImportCACommand(t *testing.T) {
+	dir := writeImportMocks(t)
+
+	tests := []struct {
+		title string
+		args  []string
+		input string
+	}{
+		{
+			"Root",
+			[]string{"-cert=" + filepath.Join(dir, "root.crt"), "-key=" + filepath.Join(dir, "root.key")},
+			`
+			password
+			password
+			`,
+		},
+		{
+			"Intermediate",
+			[]string{"-cert=" + filepath.Join(dir, "ops.crt"), "-key=" + filepath.Join(dir, "ops.key"), "-chain=" + filepath.Join(dir, "root.crt"), "-name=ops"},
+			`corpSecret
+			password
+			password
+			`,
+		},
+		{
+			"IntermediateNoName",
+			[]string{"-cert=" + filepath.Join(dir, "ops.crt"), "-key=" + filepath.Join(dir, "ops.key")},
+			`ops
+
+			password
+			password
+			`,
+		},
+		{
+			"IntermediateNonInteractive",
+			[]string{"-non-interactive", "-cert=" + filepath.Join(dir, "ops.crt"), "-key=" + filepath.Join(dir, "ops.key"), "-name=ops"},
+			"password\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &ImportCACommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.True(t, manager.ImportCACalled)
+		})
+	}
+}
+
+func TestImportCACommandError(t *testing.T) {
+	dir := writeImportMocks(t)
+	rootArgs := []string{"-cert=" + filepath.Join(dir, "root.crt"), "-key=" + filepath.Join(dir, "root.key")}
+
+	tests := []struct {
+		title         string
+		noWorkspace   bool
+		args          []string
+		input         string
+		ImportCAError error
+		expectedExit  int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]string{"-invalid"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoKey",
+			false,
+			[]string{"-cert=" + filepath.Join(dir, "root.crt")},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoState",
+			true,
+			rootArgs,
+			``,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"NotCA",
+			false,
+			[]string{"-cert=" + filepath.Join(dir, "webapp.crt"), "-key=" + filepath.Join(dir, "webapp.key")},
+			``,
+			nil,
+			ErrorImport,
+		},
+		{
+			"NoName",
+			false,
+			[]string{"-cert=" + filepath.Join(dir, "ops.crt"), "-key=" + filepath.Join(dir, "ops.key")},
+			``,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoName",
+			false,
+			[]string{"-non-interactive", "-cert=" + filepath.Join(dir, "ops.crt"), "-key=" + filepath.Join(dir, "ops.key")},
+			"ops\n",
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoPassword",
+			false,
+			append([]string{"-non-interactive"}, rootArgs...),
+			`
+			password
+			password
+			`,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoKeyPassword",
+			false,
+			rootArgs,
+			``,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoPassword",
+			false,
+			rootArgs,
+			`
+			`,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"ImportCAError",
+			false,
+			rootArgs,
+			`
+			password
+			password
+			`,
+			errors.New("error"),
+			ErrorImport,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &ImportCACommand{
+				ui: mockUI,
+				pki: &mockManager{
+					ImportCAError: test.ImportCAError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
@@ -20,6 +20,20 @@ const (
 
 	Best-practice configs are provided by default.
 	You can customize these configs by editing "state.yaml" file.
+
+	Serial numbers are sequential per type of certificate by default.
+	Sequential serial numbers are incremented and saved in "state.yaml" file every time a certificate is issued.
+	You can instead use random 128-bit serial numbers as recommended by CA/Browser Forum.
+
+	Using -non-interactive flag, nothing is asked and the common specs are read from flags or GOCERT_* environment variables
+	(e.g. -organization or GOCERT_ORGANIZATION). Specs not given are left empty.
+
+	Flags:
+		-random-serial      use random serial numbers instead of sequential ones
+		-non-interactive    never prompt for values
+		-country, -province, -locality, -organization, -organizational-unit,
+		-dns-name, -ip-address, -email-address, -uri, -street-address, -postal-code
+		                    set a common spec (lists are comma-separated)
 	`
 )
 
@@ -47,28 +61,45 @@ func (c *InitCommand) Help() string {
 
 // Run executes the command
 func (c *InitCommand) Run(args []string) int {
+	var fRandomSerial bool
+
 	flags := flag.NewFlagSet("init", flag.ContinueOnError)
 	flags.Usage = func() {}
+	flags.BoolVar(&fRandomSerial, "random-serial", false, "")
+	in := newInput(flags, nil, specInputs)
 	err := flags.Parse(args)
 	if err != nil {
 		return ErrorInvalidFlag
 	}
 
-	_, err = util.MkDirs("", pki.DirRoot, pki.DirInterm, pki.DirServer, pki.DirClient, pki.DirCSR)
+	_, err = util.MkDirs("", pki.DirRoot, pki.DirInterm, pki.DirServer, pki.DirClient, pki.DirCSR, pki.DirCRL, pki.DirArchive)
 	if err != nil {
 		c.ui.Error("Failed to create directories. Error: " + err.Error())
 		return ErrorMakeDir
 	}
 
 	state := pki.NewState()
+	state.RandomSerial = fRandomSerial
 	err = pki.SaveState(state, pki.FileState)
 	if err != nil {
 		c.ui.Error("Failed to save configs. Error: " + err.Error())
 		return ErrorWriteState
 	}
 
+	err = pki.SaveIndex(&pki.Index{}, pki.FileIndex)
+	if err != nil {
+		c.ui.Error("Failed to save issuance index. Error: " + err.Error())
+		return ErrorWriteState
+	}
+
 	// Ask user to enter values for spec
-	spec, err := askForNewSpec(c.ui)
+	var spec *pki.Spec
+	if in.isNonInteractive() {
+		spec, err = in.newSpec(c.ui)
+	} else {
+		spec, err = askForNewSpec(c.ui)
+	}
+
 	if err != nil {
 		return ErrorEnterSpec
 	}
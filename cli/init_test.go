@@ -37,24 +37,44 @@ func TestInitCommand(t *testing.T) {
 		{
 			title: "DefaultStateSpec",
 			args:  []string{},
-			input: "\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
-				"\n\n\n\n\n\n\n\n\n\n" +
+			input: "\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
 				"\n\n" +
 				"\n\n",
 			expectedStateFixture: "./fixture/InitCommand/default.yaml",
 			expectedSpecFixture:  "./fixture/InitCommand/default.toml",
 		},
+		{
+			title: "RandomSerial",
+			args:  []string{"-random-serial"},
+			input: "\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n\n\n\n\n\n\n\n\n\n" +
+				"\n\n" +
+				"\n\n",
+			expectedStateFixture: "./fixture/InitCommand/random.yaml",
+			expectedSpecFixture:  "./fixture/InitCommand/default.toml",
+		},
+		{
+			title:                "NonInteractive",
+			args:                 []string{"-non-interactive", "-country=CA", "-organization=Milad", "-organizational-unit=SRE,QE"},
+			input:                "",
+			expectedStateFixture: "./fixture/InitCommand/default.yaml",
+			expectedSpecFixture:  "./fixture/InitCommand/noninteractive.toml",
+		},
 		{
 			title: "CustomStateSpec",
 			args:  []string{},
-			input: "CA\n\n\nMilad\n\n\n\n\n-\n-\n" +
-				"\n\n\n-\n-\n-\n" +
-				"\n\nSRE\n-\n-\n-\n" +
-				"Ontario\nOttawa\nR&D\nexample.org\n127.0.0.1\n\n" +
-				"Ontario\nOttawa\nQE\n\n\n\n" +
+			input: "CA\n\n\nMilad\n\n\n\n\n\n-\n-\n" +
+				"\n\n\n-\n-\n-\n\n" +
+				"\n\nSRE\n-\n-\n-\n\n" +
+				"Ontario\nOttawa\nR&D\nexample.org\n127.0.0.1\n\n\n" +
+				"Ontario\nOttawa\nQE\n\n\n\nspiffe://example.org/qe\n" +
 				"Organization\nCommonName,OrganizationalUnit\n" +
 				"Organization\nCommonName\n",
 			expectedStateFixture: "./fixture/InitCommand/custom.yaml",
@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	envPrefix = "GOCERT_"
+
+	inputNonInteractive = "non-interactive"
+	inputPasswordFile   = "password-file"
+	inputPasswordEnv    = "password-env"
+
+	minPasswordLen = 6
+)
+
+var errMissingPassword = fmt.Errorf("password is required in non-interactive mode (use -%s flag, -%s flag, or standard input)", inputPasswordFile, inputPasswordEnv)
+
+var (
+	// configInputs are names of flags for config fields
+	// Serial numbers are kept in state and are not given as inputs.
+	configInputs = []string{"algorithm", "length", "days", "max-path-len"}
+
+	// specInputs are names of flags for claim fields stored in spec
+	specInputs = []string{
+		"country", "province", "locality", "organization", "organizational-unit",
+		"dns-name", "ip-address", "email-address", "uri", "street-address", "postal-code",
+	}
+
+	// claimInputs are names of flags for all claim fields
+	claimInputs = append([]string{"common-name"}, specInputs...)
+)
+
+// input provides configs, claims, and passwords from flags and environment variables
+// Every flag has an environment variable with GOCERT_ prefix (e.g. -dns-name and GOCERT_DNS_NAME), and flags take precedence.
+// In non-interactive mode, a missing required value is an error instead of a prompt.
+type input struct {
+	nonInteractive bool
+	values         map[string]*string
+	stdin          io.Reader
+	stdinPassword  string
+}
+
+// newInput registers flags for non-interactive mode, passwords, and a list of field names on a flag set
+func newInput(flags *flag.FlagSet, stdin io.Reader, names ...[]string) *input {
+	in := &input{
+		values: map[string]*string{},
+		stdin:  stdin,
+	}
+
+	flags.BoolVar(&in.nonInteractive, inputNonInteractive, false, "")
+	for _, name := range []string{inputPasswordFile, inputPasswordEnv} {
+		in.values[name] = flags.String(name, "", "")
+	}
+
+	for _, list := range names {
+		for _, name := range list {
+			in.values[name] = flags.String(name, "", "")
+		}
+	}
+
+	return in
+}
+
+// envName returns the name of environment variable for a flag
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// lookup returns the value of a flag or its environment variable
+func (in *input) lookup(name string) (string, bool) {
+	v, ok := in.values[name]
+	if !ok {
+		return "", false
+	}
+
+	if *v != "" {
+		return *v, true
+	}
+
+	if env := os.Getenv(envName(name)); env != "" {
+		return env, true
+	}
+
+	return "", false
+}
+
+func (in *input) lookupInt(name string) (int, bool, error) {
+	v, ok := in.lookup(name)
+	if !ok {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s is not a valid integer number: %s", name, v)
+	}
+
+	return n, true, nil
+}
+
+func (in *input) lookupList(name string) ([]string, bool) {
+	v, ok := in.lookup(name)
+	if !ok {
+		return nil, false
+	}
+
+	return strings.Split(v, ","), true
+}
+
+// isNonInteractive determines whether or not prompts are disabled by flag or GOCERT_NON_INTERACTIVE environment variable
+func (in *input) isNonInteractive() bool {
+	if in.nonInteractive {
+		return true
+	}
+
+	b, err := strconv.ParseBool(os.Getenv(envName(inputNonInteractive)))
+	return err == nil && b
+}
+
+// missing returns an error for a missing required value in non-interactive mode
+func (in *input) missing(name string) error {
+	return fmt.Errorf("%s is required in non-interactive mode (use -%s flag or %s environment variable)", name, name, envName(name))
+}
+
+// ask asks for a value only given by a flag in non-interactive mode
+func (in *input) ask(ui cli.Ui, query, flagName string) (string, error) {
+	if in.isNonInteractive() {
+		err := fmt.Errorf("-%s flag is required in non-interactive mode", flagName)
+		ui.Error("Value is not set. Error: " + err.Error())
+		return "", err
+	}
+
+	return ui.Ask(query)
+}
+
+// readPassword reads a password from a file or an environment variable by its name
+func readPassword(file, env string) (string, error) {
+	var password string
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", errors.New("cannot read password file " + file)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	} else {
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", errors.New("password environment variable " + env + " is not set")
+		}
+		password = v
+	}
+
+	return password, checkPassword(password)
+}
+
+func checkPassword(password string) error {
+	if len(password) < minPasswordLen {
+		return fmt.Errorf("password should be at least %d characters", minPasswordLen)
+	}
+
+	return nil
+}
+
+// password reads a password from a file, an environment variable, or a line of standard input in non-interactive mode
+// Standard input is only read once, and the same password is returned for every call.
+func (in *input) password() (string, bool, error) {
+	file, _ := in.lookup(inputPasswordFile)
+	env, _ := in.lookup(inputPasswordEnv)
+	if file != "" || env != "" {
+		password, err := readPassword(file, env)
+		if err != nil {
+			return "", false, err
+		}
+		return password, true, nil
+	}
+
+	if !in.isNonInteractive() || in.stdin == nil {
+		return "", false, nil
+	}
+
+	if in.stdinPassword != "" {
+		return in.stdinPassword, true, nil
+	}
+
+	line, err := bufio.NewReader(in.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false, errors.New("cannot read password from standard input")
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if err := checkPassword(password); err != nil {
+		return "", false, err
+	}
+
+	in.stdinPassword = password
+	return password, true, nil
+}
+
+// askPassword reads a password using password method, and asks for it otherwise
+// In non-interactive mode, a missing password is an error instead of a prompt.
+func (in *input) askPassword(ui cli.Ui, ask func() (string, error)) (string, error) {
+	password, ok, err := in.password()
+	if err != nil {
+		ui.Error("Password is not valid. Error: " + err.Error())
+		return "", err
+	} else if ok {
+		return password, nil
+	}
+
+	if in.isNonInteractive() {
+		ui.Error("Password is not set. Error: " + errMissingPassword.Error())
+		return "", errMissingPassword
+	}
+
+	return ask()
+}
+
+// applyConfig sets config fields given by flags and environment variables
+func (in *input) applyConfig(config *pki.Config) error {
+	if v, ok := in.lookup("algorithm"); ok {
+		config.Algorithm = v
+	}
+
+	for name, field := range map[string]*int{"length": &config.Length, "days": &config.Days} {
+		n, ok, err := in.lookupInt(name)
+		if err != nil {
+			return err
+		} else if ok {
+			*field = n
+		}
+	}
+
+	n, ok, err := in.lookupInt("max-path-len")
+	if err != nil {
+		return err
+	} else if ok {
+		config.MaxPathLen = &n
+	}
+
+	return nil
+}
+
+// applyClaim sets claim fields given by flags and environment variables
+func (in *input) applyClaim(claim *pki.Claim) error {
+	if v, ok := in.lookup("common-name"); ok {
+		claim.CommonName = v
+	}
+
+	lists := map[string]*[]string{
+		"country":             &claim.Country,
+		"province":            &claim.Province,
+		"locality":            &claim.Locality,
+		"organization":        &claim.Organization,
+		"organizational-unit": &claim.OrganizationalUnit,
+		"dns-name":            &claim.DNSName,
+		"email-address":       &claim.EmailAddress,
+		"uri":                 &claim.URI,
+		"street-address":      &claim.StreetAddress,
+		"postal-code":         &claim.PostalCode,
+	}
+
+	for name, field := range lists {
+		if v, ok := in.lookupList(name); ok {
+			*field = v
+		}
+	}
+
+	if vals, ok := in.lookupList("ip-address"); ok {
+		claim.IPAddress = nil
+		for _, v := range vals {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return errors.New("ip-address is not a valid IP address: " + v)
+			}
+			claim.IPAddress = append(claim.IPAddress, ip)
+		}
+	}
+
+	return nil
+}
+
+// askForConfig sets a config from flags and environment variables, and asks for the rest of it
+// In non-interactive mode, the algorithm, length, days, and password of certificate authorities are required.
+func (in *input) askForConfig(config *pki.Config, c pki.Cert, skipList *[]string, ui cli.Ui) error {
+	err := in.applyConfig(config)
+	if err != nil {
+		ui.Error("Config is not valid. Error: " + err.Error())
+		return err
+	}
+
+	isCA := c.Type == pki.CertTypeRoot || c.Type == pki.CertTypeInterm
+	if isCA {
+		password, ok, err := in.password()
+		if err != nil {
+			ui.Error("Password is not valid. Error: " + err.Error())
+			return err
+		} else if ok {
+			config.Password = password
+		}
+	}
+
+	if !in.isNonInteractive() {
+		return askForConfig(config, c, skipList, ui)
+	}
+
+	switch {
+	case config.Algorithm == "":
+		err = in.missing("algorithm")
+	case config.Length == 0:
+		err = in.missing("length")
+	case config.Days == 0:
+		err = in.missing("days")
+	case isCA && config.Password == "":
+		err = errMissingPassword
+	}
+
+	if err != nil {
+		ui.Error("Config is not complete. Error: " + err.Error())
+	}
+
+	return err
+}
+
+// askForClaim sets a claim from flags and environment variables, and asks for the rest of it
+// In non-interactive mode, the common name is required and other fields are left empty.
+func (in *input) askForClaim(claim *pki.Claim, c pki.Cert, skipList *[]string, ui cli.Ui) error {
+	err := in.applyClaim(claim)
+	if err != nil {
+		ui.Error("Claim is not valid. Error: " + err.Error())
+		return err
+	}
+
+	if !in.isNonInteractive() {
+		return askForClaim(claim, c, skipList, ui)
+	}
+
+	if claim.CommonName == "" {
+		err = in.missing("common-name")
+		ui.Error("Claim is not complete. Error: " + err.Error())
+	}
+
+	return err
+}
+
+// newSpec creates a new spec with the claim given by flags and environment variables for all types of certificates
+func (in *input) newSpec(ui cli.Ui) (*pki.Spec, error) {
+	common := pki.Claim{}
+	err := in.applyClaim(&common)
+	if err != nil {
+		ui.Error("Claim is not valid. Error: " + err.Error())
+		return nil, err
+	}
+
+	spec := pki.NewSpec()
+	spec.Root = common.Clone()
+	spec.Interm = common.Clone()
+	spec.Server = common.Clone()
+	spec.Client = common.Clone()
+
+	return spec, nil
+}
@@ -0,0 +1,337 @@
+package cli
+
+import (
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseInput(t *testing.T, args []string, stdin string, names ...[]string) *input {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	in := newInput(flags, strings.NewReader(stdin), names...)
+	assert.NoError(t, flags.Parse(args))
+	return in
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name            string
+		expectedEnvName string
+	}{
+		{"days", "GOCERT_DAYS"},
+		{"dns-name", "GOCERT_DNS_NAME"},
+		{"non-interactive", "GOCERT_NON_INTERACTIVE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedEnvName, envName(test.name))
+		})
+	}
+}
+
+func TestInputLookup(t *testing.T) {
+	t.Setenv("GOCERT_ALGORITHM", "ecdsa")
+	t.Setenv("GOCERT_DAYS", "90")
+	t.Setenv("GOCERT_COUNTRY", "CA")
+
+	in := parseInput(t, []string{"-days=30"}, "", configInputs)
+
+	v, ok := in.lookup("algorithm")
+	assert.True(t, ok)
+	assert.Equal(t, "ecdsa", v)
+
+	// Flags take precedence over environment variables
+	v, ok = in.lookup("days")
+	assert.True(t, ok)
+	assert.Equal(t, "30", v)
+
+	_, ok = in.lookup("length")
+	assert.False(t, ok)
+
+	// Environment variables are only read for registered flags
+	_, ok = in.lookup("country")
+	assert.False(t, ok)
+}
+
+func TestInputIsNonInteractive(t *testing.T) {
+	in := parseInput(t, []string{}, "")
+	assert.False(t, in.isNonInteractive())
+
+	in = parseInput(t, []string{"-non-interactive"}, "")
+	assert.True(t, in.isNonInteractive())
+
+	t.Setenv("GOCERT_NON_INTERACTIVE", "true")
+	in = parseInput(t, []string{}, "")
+	assert.True(t, in.isNonInteractive())
+}
+
+func TestInputPassword(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0600))
+	t.Setenv("CA_PASSWORD", "from-env")
+	t.Setenv("SHORT_PASSWORD", "short")
+
+	tests := []struct {
+		name             string
+		args             []string
+		stdin            string
+		expectedOK       bool
+		expectedPassword string
+		expectedError    string
+	}{
+		{"None", []string{}, "from-stdin\n", false, "", ""},
+		{"File", []string{"-password-file=" + file}, "", true, "from-file", ""},
+		{"Env", []string{"-password-env=CA_PASSWORD"}, "", true, "from-env", ""},
+		{"Stdin", []string{"-non-interactive"}, "from-stdin\n", true, "from-stdin", ""},
+		{"NoFile", []string{"-password-file=" + file + ".missing"}, "", false, "", "cannot read password file " + file + ".missing"},
+		{"NoEnv", []string{"-password-env=NO_PASSWORD"}, "", false, "", "password environment variable NO_PASSWORD is not set"},
+		{"Short", []string{"-password-env=SHORT_PASSWORD"}, "", false, "", "password should be at least 6 characters"},
+		{"EmptyStdin", []string{"-non-interactive"}, "", false, "", "password should be at least 6 characters"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := parseInput(t, test.args, test.stdin)
+			password, ok, err := in.password()
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedOK, ok)
+				assert.Equal(t, test.expectedPassword, password)
+			}
+		})
+	}
+}
+
+func TestInputPasswordStdinOnce(t *testing.T) {
+	in := parseInput(t, []string{"-non-interactive"}, "first-secret\nsecond-secret\n")
+
+	for i := 0; i < 2; i++ {
+		password, ok, err := in.password()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "first-secret", password)
+	}
+}
+
+func TestInputAskPassword(t *testing.T) {
+	ask := func() (string, error) { return "prompted", nil }
+
+	in := parseInput(t, []string{}, "")
+	password, err := in.askPassword(newMockUI(strings.NewReader("")), ask)
+	assert.NoError(t, err)
+	assert.Equal(t, "prompted", password)
+
+	in = parseInput(t, []string{"-non-interactive"}, "from-stdin\n")
+	password, err = in.askPassword(newMockUI(strings.NewReader("")), ask)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-stdin", password)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	in = newInput(flags, nil)
+	assert.NoError(t, flags.Parse([]string{"-non-interactive"}))
+	_, err = in.askPassword(newMockUI(strings.NewReader("")), ask)
+	assert.EqualError(t, err, "password is required in non-interactive mode (use -password-file flag, -password-env flag, or standard input)")
+}
+
+func TestInputApplyConfig(t *testing.T) {
+	two := 2
+
+	tests := []struct {
+		name           string
+		args           []string
+		config         pki.Config
+		expectedConfig pki.Config
+		expectedError  string
+	}{
+		{
+			"None",
+			[]string{},
+			pki.Config{Algorithm: "rsa", Length: 2048, Days: 375},
+			pki.Config{Algorithm: "rsa", Length: 2048, Days: 375},
+			"",
+		},
+		{
+			"Override",
+			[]string{"-algorithm=ecdsa", "-length=256", "-days=30", "-max-path-len=2"},
+			pki.Config{Algorithm: "rsa", Length: 2048, Days: 375},
+			pki.Config{Algorithm: "ecdsa", Length: 256, Days: 30, MaxPathLen: &two},
+			"",
+		},
+		{
+			"InvalidDays",
+			[]string{"-days=month"},
+			pki.Config{},
+			pki.Config{},
+			"days is not a valid integer number: month",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := parseInput(t, test.args, "", configInputs)
+			err := in.applyConfig(&test.config)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedConfig, test.config)
+			}
+		})
+	}
+}
+
+func TestInputApplyClaim(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		claim         pki.Claim
+		expectedClaim pki.Claim
+		expectedError string
+	}{
+		{
+			"None",
+			[]string{},
+			pki.Claim{Organization: []string{"Milad"}},
+			pki.Claim{Organization: []string{"Milad"}},
+			"",
+		},
+		{
+			"Override",
+			[]string{"-common-name=webapp", "-organizational-unit=SRE", "-dns-name=webapp.example.com,webapp", "-ip-address=127.0.0.1", "-uri=spiffe://example.org/webapp"},
+			pki.Claim{Organization: []string{"Milad"}, OrganizationalUnit: []string{"R&D"}},
+			pki.Claim{
+				CommonName:         "webapp",
+				Organization:       []string{"Milad"},
+				OrganizationalUnit: []string{"SRE"},
+				DNSName:            []string{"webapp.example.com", "webapp"},
+				IPAddress:          []net.IP{net.ParseIP("127.0.0.1")},
+				URI:                []string{"spiffe://example.org/webapp"},
+			},
+			"",
+		},
+		{
+			"InvalidIPAddress",
+			[]string{"-ip-address=localhost"},
+			pki.Claim{},
+			pki.Claim{},
+			"ip-address is not a valid IP address: localhost",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := parseInput(t, test.args, "", claimInputs)
+			err := in.applyClaim(&test.claim)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedClaim, test.claim)
+			}
+		})
+	}
+}
+
+func TestInputAskForConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		stdin            string
+		c                pki.Cert
+		config           pki.Config
+		expectedPassword string
+		expectedError    string
+	}{
+		{
+			"RootCA",
+			[]string{"-non-interactive"},
+			"password\n",
+			pki.Cert{Type: pki.CertTypeRoot},
+			pki.Config{Algorithm: "rsa", Length: 4096, Days: 7300},
+			"password",
+			"",
+		},
+		{
+			"Server",
+			[]string{"-non-interactive"},
+			"",
+			pki.Cert{Type: pki.CertTypeServer},
+			pki.Config{Algorithm: "rsa", Length: 2048, Days: 375},
+			"",
+			"",
+		},
+		{
+			"NoDays",
+			[]string{"-non-interactive"},
+			"",
+			pki.Cert{Type: pki.CertTypeServer},
+			pki.Config{Algorithm: "rsa", Length: 2048},
+			"",
+			"days is required in non-interactive mode (use -days flag or GOCERT_DAYS environment variable)",
+		},
+		{
+			"NoPassword",
+			[]string{"-non-interactive"},
+			"",
+			pki.Cert{Type: pki.CertTypeInterm},
+			pki.Config{Algorithm: "rsa", Length: 4096, Days: 3650},
+			"",
+			"password should be at least 6 characters",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := parseInput(t, test.args, test.stdin, configInputs)
+			mockUI := newMockUI(strings.NewReader(""))
+			err := in.askForConfig(&test.config, test.c, nil, mockUI)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedPassword, test.config.Password)
+				assert.Empty(t, mockUI.OutputWriter.String())
+			}
+		})
+	}
+}
+
+func TestInputAskForClaim(t *testing.T) {
+	in := parseInput(t, []string{"-non-interactive", "-dns-name=webapp.example.com"}, "", claimInputs)
+	mockUI := newMockUI(strings.NewReader(""))
+	claim := pki.Claim{}
+	err := in.askForClaim(&claim, pki.Cert{Type: pki.CertTypeServer}, nil, mockUI)
+	assert.EqualError(t, err, "common-name is required in non-interactive mode (use -common-name flag or GOCERT_COMMON_NAME environment variable)")
+
+	in = parseInput(t, []string{"-non-interactive", "-common-name=webapp"}, "", claimInputs)
+	claim = pki.Claim{}
+	err = in.askForClaim(&claim, pki.Cert{Type: pki.CertTypeServer}, nil, mockUI)
+	assert.NoError(t, err)
+	assert.Equal(t, pki.Claim{CommonName: "webapp"}, claim)
+}
+
+func TestInputAsk(t *testing.T) {
+	in := parseInput(t, []string{}, "")
+	mockUI := newMockUI(strings.NewReader("ops\n"))
+	name, err := in.ask(mockUI, "CA Name (type: string):", "ca")
+	assert.NoError(t, err)
+	assert.Equal(t, "ops", name)
+
+	in = parseInput(t, []string{"-non-interactive"}, "")
+	_, err = in.ask(mockUI, "CA Name (type: string):", "ca")
+	assert.EqualError(t, err, "-ca flag is required in non-interactive mode")
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	inspectFormatText = "text"
+	inspectFormatJSON = "json"
+
+	inspectEnterName = "\nENTER NAME OR PATH FOR CERTIFICATE ..."
+
+	inspectSynopsis = `Decodes and prints certificates, requests, chains, and keys.`
+	inspectHelp     = `
+	You can use this command to decode and print certificates, certificate signing requests,
+	certificate chains, certificate revocation lists, and keys in PEM format.
+
+	Each argument can be either the name of a certificate in workspace or the path to a file.
+	For a name, the certificate (or the request if it is not signed yet) and the key are decoded.
+
+	The subject, issuer, subject alternative names, key usage, extended key usage, basic constraints,
+	subject and authority key identifiers, SHA-256 and SHA-1 fingerprints, and validity period are printed.
+	Only the public parts of keys are printed and encrypted keys are never decrypted.
+
+	Flags:
+		-format    the output format (text or json) (default: text)
+
+	Examples:
+		gocert inspect webapp
+		gocert inspect -format=json intermediate/sre.ca.chain
+	`
+)
+
+type (
+	// inspectResult represents the decoded PEM blocks of a file
+	inspectResult struct {
+		Path   string            `json:"path"`
+		Blocks []pki.Description `json:"blocks"`
+	}
+)
+
+// InspectCommand represents the inspect command
+type InspectCommand struct {
+	ui cli.Ui
+}
+
+// NewInspectCommand creates a new command
+func NewInspectCommand() *InspectCommand {
+	return &InspectCommand{
+		ui: newColoredUI(),
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *InspectCommand) Synopsis() string {
+	return inspectSynopsis
+}
+
+// Help returns the long help text for command
+func (c *InspectCommand) Help() string {
+	return inspectHelp
+}
+
+// resolvePaths returns the files for a workspace name or the path itself
+func (c *InspectCommand) resolvePaths(arg string) []string {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		return []string{arg}
+	}
+
+	cert := resolveByName(arg)
+	if cert.Type == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, 2)
+	if _, err := os.Stat(cert.CertPath()); err == nil {
+		paths = append(paths, cert.CertPath())
+	} else if _, err := os.Stat(cert.CSRPath()); err == nil {
+		paths = append(paths, cert.CSRPath())
+	}
+
+	return append(paths, cert.KeyPath())
+}
+
+func formatDescription(d pki.Description) string {
+	var b strings.Builder
+	line := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "    %-22s%s\n", label+":", value)
+		}
+	}
+	list := func(label string, values []string) {
+		line(label, strings.Join(values, ", "))
+	}
+	timestamp := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	fmt.Fprintf(&b, "  %s\n", strings.ToUpper(d.Type[:1])+d.Type[1:])
+	line("Subject", d.Subject)
+	line("Issuer", d.Issuer)
+	line("Serial", d.Serial)
+	line("Not Before", timestamp(d.NotBefore))
+	line("Not After", timestamp(d.NotAfter))
+	list("DNS Names", d.DNSNames)
+	list("IP Addresses", d.IPAddresses)
+	list("Email Addresses", d.EmailAddresses)
+	list("URIs", d.URIs)
+	line("Public Key", d.PublicKey)
+	line("Signature Algorithm", d.SignatureAlgorithm)
+	list("Key Usage", d.KeyUsage)
+	list("Extended Key Usage", d.ExtKeyUsage)
+	if bc := d.BasicConstraints; bc != nil {
+		value := "CA:" + strconv.FormatBool(bc.IsCA)
+		if bc.MaxPathLen != nil {
+			value += ", MaxPathLen:" + strconv.Itoa(*bc.MaxPathLen)
+		}
+		line("Basic Constraints", value)
+	}
+	line("Subject Key ID", d.SubjectKeyID)
+	line("Authority Key ID", d.AuthorityKeyID)
+	line("SHA-256 Fingerprint", d.SHA256Fingerprint)
+	line("SHA-1 Fingerprint", d.SHA1Fingerprint)
+
+	return b.String()
+}
+
+// Run executes the command
+func (c *InspectCommand) Run(args []string) int {
+	var fFormat string
+
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fFormat, "format", inspectFormatText, "")
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fFormat != inspectFormatText && fFormat != inspectFormatJSON {
+		c.ui.Error("Output format is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		c.ui.Output(inspectEnterName)
+		name, err := c.ui.Ask(fmt.Sprintf(promptTemplate, "Name", "string"))
+		if err != nil || name == "" {
+			return ErrorInvalidName
+		}
+		names = []string{name}
+	}
+
+	results := make([]inspectResult, 0)
+	for _, name := range names {
+		paths := c.resolvePaths(name)
+		if len(paths) == 0 {
+			c.ui.Error("No certificate or file found for " + name)
+			return ErrorInvalidCert
+		}
+
+		for _, path := range paths {
+			descs, err := pki.Inspect(path)
+			if err != nil {
+				c.ui.Error(fmt.Sprintf("Failed to inspect %s. Error: %s", path, err))
+				return ErrorInspect
+			}
+			results = append(results, inspectResult{Path: path, Blocks: descs})
+		}
+	}
+
+	if fFormat == inspectFormatJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			c.ui.Error("Failed to format output. Error: " + err.Error())
+			return ErrorInspect
+		}
+		c.ui.Output(string(data))
+		return 0
+	}
+
+	for _, result := range results {
+		c.ui.Output(result.Path)
+		for _, d := range result.Blocks {
+			c.ui.Output(formatDescription(d))
+		}
+	}
+
+	return 0
+}
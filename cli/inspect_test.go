@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInspectCommand(t *testing.T) {
+	cmd := NewInspectCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+
+	assert.Equal(t, "Decodes and prints certificates, requests, chains, and keys.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestInspectCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	writeListMocks(t)
+
+	tests := []struct {
+		title          string
+		args           []string
+		input          string
+		expectedOutput []string
+	}{
+		{
+			"Name",
+			[]string{"webapp"},
+			``,
+			[]string{
+				"server/webapp.cert",
+				"Certificate",
+				"CN=webapp",
+				"CN=SRE CA",
+				"example.com, www.example.com",
+				"ECDSA P-256",
+				"DigitalSignature",
+				"ServerAuth",
+				"SHA-256 Fingerprint:",
+				"server/webapp.key",
+				"Private key",
+			},
+		},
+		{
+			"Prompt",
+			[]string{},
+			"root\n",
+			[]string{"root/root.ca.cert", "CA:true", "Encrypted private key"},
+		},
+		{
+			"PendingRequest",
+			[]string{"pending"},
+			``,
+			[]string{"csr/pending.csr", "Certificate request", "CN=pending"},
+		},
+		{
+			"Chain",
+			[]string{"intermediate/sre.ca.chain"},
+			``,
+			[]string{"CN=SRE CA", "CN=Root CA"},
+		},
+		{
+			"Files",
+			[]string{"root/root.ca.cert", "intermediate/sre.ca.cert"},
+			``,
+			[]string{"CN=Root CA", "CN=SRE CA"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader(test.input))
+			cmd := &InspectCommand{
+				ui: mockUI,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+
+			output := mockUI.OutputWriter.String()
+			for _, expected := range test.expectedOutput {
+				assert.Contains(t, output, expected)
+			}
+		})
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		mockUI := newMockUI(strings.NewReader(""))
+		cmd := &InspectCommand{
+			ui: mockUI,
+		}
+
+		exit := cmd.Run([]string{"-format=json", "sre"})
+		assert.Zero(t, exit)
+
+		var results []inspectResult
+		err := json.Unmarshal(mockUI.OutputWriter.Bytes(), &results)
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+
+		assert.Equal(t, "intermediate/sre.ca.cert", results[0].Path)
+		assert.Len(t, results[0].Blocks, 1)
+		assert.Equal(t, pki.DescTypeCert, results[0].Blocks[0].Type)
+		assert.Equal(t, "CN=SRE CA", results[0].Blocks[0].Subject)
+		assert.Equal(t, "CN=Root CA", results[0].Blocks[0].Issuer)
+		assert.True(t, results[0].Blocks[0].BasicConstraints.IsCA)
+		assert.NotEmpty(t, results[0].Blocks[0].SubjectKeyID)
+		assert.NotEmpty(t, results[0].Blocks[0].AuthorityKeyID)
+
+		assert.Equal(t, "intermediate/sre.ca.key", results[1].Path)
+		assert.Equal(t, pki.DescTypeEncryptedKey, results[1].Blocks[0].Type)
+	})
+}
+
+func TestInspectCommandError(t *testing.T) {
+	tests := []struct {
+		title        string
+		args         []string
+		input        string
+		expectedExit int
+	}{
+		{
+			"InvalidFlag",
+			[]string{"-invalid"},
+			``,
+			ErrorInvalidFlag,
+		},
+		{
+			"InvalidFormat",
+			[]string{"-format=yaml", "root"},
+			``,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoName",
+			[]string{},
+			``,
+			ErrorInvalidName,
+		},
+		{
+			"NotFound",
+			[]string{"missing"},
+			``,
+			ErrorInvalidCert,
+		},
+		{
+			"InvalidFile",
+			[]string{"root"},
+			``,
+			ErrorInspect,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+			})
+
+			mockUI := newMockUI(strings.NewReader(test.input))
+			cmd := &InspectCommand{
+				ui: mockUI,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
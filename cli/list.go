@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	listFormatTable = "table"
+	listFormatJSON  = "json"
+	listFormatCSV   = "csv"
+
+	listNoCert = " No certificate found."
+
+	listSynopsis = `Lists certificates and pending requests in workspace.`
+	listHelp     = `
+	You can use this command to list root and intermediate certificate authorities,
+	server and client certificates, and pending certificate signing requests in workspace.
+
+	For each certificate, the type, issuer, serial number, subject alternative names,
+	expiry date, number of days remaining, and status (valid, revoked, expired, or pending) are listed.
+	Pending requests imported from outside workspace for external signing are marked as external.
+
+	Flags:
+		-type               only list certificates of a type (root, intermediate, server, or client)
+		-issuer             only list certificates issued by a certificate authority
+		-expiring-within    only list valid certificates expiring within a number of days
+		-format             the output format (table, json, or csv) (default: table)
+	`
+)
+
+type (
+	// listRow represents a row in output of list command
+	listRow struct {
+		Name          string     `json:"name"`
+		Type          string     `json:"type"`
+		Issuer        string     `json:"issuer,omitempty"`
+		Serial        string     `json:"serial,omitempty"`
+		SANs          []string   `json:"sans"`
+		NotAfter      *time.Time `json:"not_after,omitempty"`
+		DaysRemaining *int       `json:"days_remaining,omitempty"`
+		Status        string     `json:"status"`
+		External      bool       `json:"external,omitempty"`
+	}
+)
+
+func newListRow(s pki.CertSummary, now time.Time) listRow {
+	row := listRow{
+		Name:     s.Name,
+		Type:     s.Type,
+		Issuer:   s.Issuer,
+		Serial:   s.Serial,
+		SANs:     s.SANs,
+		Status:   s.Status,
+		External: s.External,
+	}
+
+	if row.SANs == nil {
+		row.SANs = []string{}
+	}
+
+	// Pending requests have no validity period
+	if !s.NotAfter.IsZero() {
+		notAfter := s.NotAfter
+		days := int(notAfter.Sub(now).Hours() / 24)
+		row.NotAfter = &notAfter
+		row.DaysRemaining = &days
+	}
+
+	return row
+}
+
+func (r listRow) fields() []string {
+	notAfter, days := "", ""
+	if r.NotAfter != nil {
+		notAfter = r.NotAfter.Format(time.RFC3339)
+		days = strconv.Itoa(*r.DaysRemaining)
+	}
+
+	status := r.Status
+	if r.External {
+		status += " (external)"
+	}
+
+	return []string{r.Name, r.Type, r.Issuer, r.Serial, strings.Join(r.SANs, ","), notAfter, days, status}
+}
+
+// ListCommand represents the list command
+type ListCommand struct {
+	ui  cli.Ui
+	now func() time.Time
+}
+
+// NewListCommand creates a new command
+func NewListCommand() *ListCommand {
+	return &ListCommand{
+		ui:  newColoredUI(),
+		now: time.Now,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *ListCommand) Synopsis() string {
+	return listSynopsis
+}
+
+// Help returns the long help text for command
+func (c *ListCommand) Help() string {
+	return listHelp
+}
+
+func (c *ListCommand) filter(summaries []pki.CertSummary, fType, fIssuer string, fExpiringWithin int, now time.Time) []pki.CertSummary {
+	deadline := now.AddDate(0, 0, fExpiringWithin)
+
+	filtered := make([]pki.CertSummary, 0)
+	for _, s := range summaries {
+		if fType != "" && s.Type != fType {
+			continue
+		}
+
+		if fIssuer != "" && s.Issuer != fIssuer {
+			continue
+		}
+
+		if fExpiringWithin > 0 && (s.Status != pki.StatusValid || s.NotAfter.After(deadline)) {
+			continue
+		}
+
+		filtered = append(filtered, s)
+	}
+
+	return filtered
+}
+
+func (c *ListCommand) format(rows []listRow, format string) (string, error) {
+	buf := new(bytes.Buffer)
+	header := []string{"NAME", "TYPE", "ISSUER", "SERIAL", "SANS", "NOT AFTER", "DAYS", "STATUS"}
+
+	switch format {
+	case listFormatJSON:
+		enc := json.NewEncoder(buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			return "", err
+		}
+
+	case listFormatCSV:
+		w := csv.NewWriter(buf)
+		w.Write([]string{"name", "type", "issuer", "serial", "sans", "not_after", "days_remaining", "status"}) // nolint: errcheck
+		for _, row := range rows {
+			w.Write(row.fields()) // nolint: errcheck
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", err
+		}
+
+	default:
+		w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row.fields(), "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// Run executes the command
+func (c *ListCommand) Run(args []string) int {
+	var fType, fIssuer, fFormat string
+	var fExpiringWithin int
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fType, "type", "", "")
+	flags.StringVar(&fIssuer, "issuer", "", "")
+	flags.IntVar(&fExpiringWithin, "expiring-within", 0, "")
+	flags.StringVar(&fFormat, "format", listFormatTable, "")
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fType != "" && pki.ParseCertType(fType) == 0 {
+		c.ui.Error("Certificate type is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	if fFormat != listFormatTable && fFormat != listFormatJSON && fFormat != listFormatCSV {
+		c.ui.Error("Output format is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	_, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	now := c.now()
+	summaries, err := pki.ListWorkspace(now)
+	if err != nil {
+		c.ui.Error("Failed to list certificates. Error: " + err.Error())
+		return ErrorList
+	}
+
+	summaries = c.filter(summaries, fType, fIssuer, fExpiringWithin, now)
+
+	if len(summaries) == 0 && fFormat == listFormatTable {
+		c.ui.Warn(listNoCert)
+		return 0
+	}
+
+	rows := make([]listRow, 0, len(summaries))
+	for _, s := range summaries {
+		rows = append(rows, newListRow(s, now))
+	}
+
+	out, err := c.format(rows, fFormat)
+	if err != nil {
+		c.ui.Error("Failed to format certificates. Error: " + err.Error())
+		return ErrorList
+	}
+
+	c.ui.Output(out)
+
+	return 0
+}
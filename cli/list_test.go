@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeListMocks(t *testing.T) {
+	configRoot := pki.Config{Algorithm: pki.AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := pki.Config{Algorithm: pki.AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := pki.Config{Algorithm: pki.AlgorithmECDSA, Length: 256, Days: 20}
+	configClient := pki.Config{Algorithm: pki.AlgorithmECDSA, Length: 256, Days: 40}
+
+	cRoot := pki.Cert{Name: rootName, Type: pki.CertTypeRoot}
+	cInterm := pki.Cert{Name: "sre", Type: pki.CertTypeInterm}
+	cServer := pki.Cert{Name: "webapp", Type: pki.CertTypeServer}
+	cClient := pki.Cert{Name: "service", Type: pki.CertTypeClient}
+	cPending := pki.Cert{Name: "pending", Type: pki.CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []pki.Violation { return nil }
+
+	manager := pki.NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, pki.Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, pki.Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, pki.Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, pki.Claim{CommonName: "webapp", DNSName: []string{"example.com", "www.example.com"}}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, pki.Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configClient, pki.Claim{CommonName: "service"}, cClient))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cClient, pki.Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, pki.Claim{CommonName: "pending"}, cPending))
+}
+
+func TestNewListCommand(t *testing.T) {
+	cmd := NewListCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.NotNil(t, cmd.now)
+
+	assert.Equal(t, "Lists certificates and pending requests in workspace.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestListCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	writeListMocks(t)
+
+	tests := []struct {
+		title          string
+		args           []string
+		expectedLines  int
+		expectedOutput []string
+	}{
+		{
+			"Table",
+			[]string{},
+			6,
+			[]string{"NAME", "DAYS", "root", "sre", "webapp", "example.com,www.example.com", "service", "pending"},
+		},
+		{
+			"FilterType",
+			[]string{"-type=server"},
+			3,
+			[]string{"webapp", "pending"},
+		},
+		{
+			"FilterIssuer",
+			[]string{"-issuer=root"},
+			3,
+			[]string{"root", "sre"},
+		},
+		{
+			"FilterExpiringWithin",
+			[]string{"-expiring-within=30"},
+			2,
+			[]string{"webapp"},
+		},
+		{
+			"NoMatch",
+			[]string{"-issuer=ops"},
+			1,
+			[]string{"No certificate found."},
+		},
+		{
+			"CSV",
+			[]string{"-format=csv", "-type=server"},
+			3,
+			[]string{"name,type,issuer,serial,sans,not_after,days_remaining,status", `webapp,server,sre,1001,"example.com,www.example.com",`, "pending,server,,,,,,pending"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &ListCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+
+			output := strings.TrimSpace(mockUI.OutputWriter.String() + mockUI.ErrorWriter.String())
+			assert.Len(t, strings.Split(output, "\n"), test.expectedLines)
+			for _, expected := range test.expectedOutput {
+				assert.Contains(t, output, expected)
+			}
+		})
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		mockUI := newMockUI(strings.NewReader(""))
+		cmd := &ListCommand{
+			ui:  mockUI,
+			now: time.Now,
+		}
+
+		exit := cmd.Run([]string{"-format=json"})
+		assert.Zero(t, exit)
+
+		var rows []listRow
+		err := json.Unmarshal(mockUI.OutputWriter.Bytes(), &rows)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 5)
+
+		assert.Equal(t, "webapp", rows[2].Name)
+		assert.Equal(t, "server", rows[2].Type)
+		assert.Equal(t, "sre", rows[2].Issuer)
+		assert.Equal(t, "1001", rows[2].Serial)
+		assert.Equal(t, []string{"example.com", "www.example.com"}, rows[2].SANs)
+		assert.Equal(t, 19, *rows[2].DaysRemaining)
+		assert.Equal(t, "valid", rows[2].Status)
+
+		assert.Equal(t, "pending", rows[4].Name)
+		assert.Nil(t, rows[4].NotAfter)
+		assert.Nil(t, rows[4].DaysRemaining)
+		assert.Equal(t, "pending", rows[4].Status)
+		assert.False(t, rows[4].External)
+	})
+
+	t.Run("External", func(t *testing.T) {
+		_, data := writeExternalCSR(t, "agent")
+		assert.NoError(t, pki.ImportCSR(pki.Cert{Name: "agent", Type: pki.CertTypeServer}, data))
+
+		mockUI := newMockUI(strings.NewReader(""))
+		cmd := &ListCommand{
+			ui:  mockUI,
+			now: time.Now,
+		}
+
+		exit := cmd.Run([]string{"-format=csv"})
+		assert.Zero(t, exit)
+		assert.Contains(t, mockUI.OutputWriter.String(), "agent,server,,,agent.example.com,,,pending (external)")
+	})
+}
+
+func TestListCommandError(t *testing.T) {
+	tests := []struct {
+		title        string
+		noWorkspace  bool
+		corrupt      bool
+		args         []string
+		expectedExit int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			false,
+			[]string{"-invalid"},
+			ErrorInvalidFlag,
+		},
+		{
+			"InvalidType",
+			false,
+			false,
+			[]string{"-type=invalid"},
+			ErrorInvalidFlag,
+		},
+		{
+			"InvalidFormat",
+			false,
+			false,
+			[]string{"-format=xml"},
+			ErrorInvalidFlag,
+		},
+		{
+			"NoState",
+			true,
+			false,
+			[]string{},
+			ErrorReadState,
+		},
+		{
+			"InvalidCert",
+			false,
+			true,
+			[]string{},
+			ErrorList,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+			}
+
+			if test.corrupt {
+				writeSignMocks(t, []pki.Cert{
+					pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				})
+			}
+
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &ListCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
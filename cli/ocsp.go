@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	ocspServing     = "\n ✓ Serving OCSP responses for %s on %s\n"
+	ocspEnterNameCA = "\nENTER NAME FOR CERTIFICATE AUTHORITY ..."
+
+	defaultOCSPAddr = ":8080"
+
+	ocspSynopsis = `Runs an OCSP responder for a certificate authority.`
+	ocspHelp     = `
+	You can use this command to run an OCSP responder (RFC 6960) for a certificate authority.
+	The responder answers both GET and POST requests with the status of certificates issued by the certificate authority.
+	A certificate is good if it is issued by the certificate authority, revoked if it is revoked, and unknown otherwise.
+	Revocations take effect immediately without restarting the responder.
+
+	Responses are signed by the certificate authority unless a delegated signer is set.
+	A delegated signer is a server or client certificate issued by the certificate authority for OCSP signing.
+	You will be asked for entering the password for certificate authorithy if no delegated signer is set.
+
+	Using -non-interactive flag, nothing is asked and -ca and the password (if no delegated signer is set) are required.
+	The password is read from -password-file, -password-env, or a line of standard input.
+
+	Flags:
+		-ca                 the name of certificate authorithy
+		-addr               the address for listening to OCSP requests (default: :8080)
+		-signer             the name of a delegated OCSP signing certificate
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
+	`
+)
+
+// OCSPServeCommand represents the ocsp-serve command
+type OCSPServeCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+	serve func(string, http.Handler) error
+}
+
+// NewOCSPServeCommand creates a new command
+func NewOCSPServeCommand() *OCSPServeCommand {
+	return &OCSPServeCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+		serve: http.ListenAndServe,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *OCSPServeCommand) Synopsis() string {
+	return ocspSynopsis
+}
+
+// Help returns the long help text for command
+func (c *OCSPServeCommand) Help() string {
+	return ocspHelp
+}
+
+// Run executes the command
+func (c *OCSPServeCommand) Run(args []string) int {
+	var fCA, fAddr, fSigner string
+
+	flags := flag.NewFlagSet("ocsp-serve", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fCA, "ca", "", "")
+	flags.StringVar(&fAddr, "addr", defaultOCSPAddr, "")
+	flags.StringVar(&fSigner, "signer", "", "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fCA == "" {
+		c.ui.Output(ocspEnterNameCA)
+		fCA, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "CA Name", "string"), "ca")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	state, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	cCA := resolveByName(fCA)
+	if cCA.Type != pki.CertTypeRoot && cCA.Type != pki.CertTypeInterm {
+		c.ui.Error("Certificate authority name is not valid.")
+		return ErrorInvalidCA
+	}
+
+	// Type field is ensured to be valid
+	configCA, _ := state.ConfigFor(cCA.Type)
+
+	var cSigner pki.Cert
+	if fSigner == "" {
+		err = in.askForConfig(&configCA, cCA, nil, c.ui)
+		if err != nil {
+			return ErrorEnterConfig
+		}
+	} else {
+		cSigner = resolveByName(fSigner)
+		if cSigner.Type != pki.CertTypeServer && cSigner.Type != pki.CertTypeClient {
+			c.ui.Error("Signer certificate name is not valid.")
+			return ErrorInvalidCert
+		}
+	}
+
+	handler, err := c.pki.OCSPHandler(configCA, cCA, cSigner)
+	if err != nil {
+		c.ui.Error("Failed to create OCSP responder. Error: " + err.Error())
+		return ErrorOCSP
+	}
+
+	c.ui.Info(fmt.Sprintf(ocspServing, cCA.Name, fAddr))
+
+	err = c.serve(fAddr, handler)
+	if err != nil {
+		c.ui.Error("OCSP responder stopped. Error: " + err.Error())
+		return ErrorOCSP
+	}
+
+	return 0
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOCSPServeCommand(t *testing.T) {
+	cmd := NewOCSPServeCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+	assert.NotNil(t, cmd.serve)
+
+	assert.Equal(t, "Runs an OCSP responder for a certificate authority.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestOCSPServeCommand(t *testing.T) {
+	tests := []struct {
+		title        string
+		args         []string
+		input        string
+		expectedAddr string
+	}{
+		{
+			"Root",
+			[]string{},
+			`root
+			password
+			password
+			`,
+			":8080",
+		},
+		{
+			"Intermediate",
+			[]string{"-ca=ops", "-addr=127.0.0.1:9090"},
+			`password
+			password
+			`,
+			"127.0.0.1:9090",
+		},
+		{
+			"DelegatedSigner",
+			[]string{"-ca=ops", "-signer=ocsp"},
+			``,
+			":8080",
+		},
+		{
+			"NonInteractive",
+			[]string{"-non-interactive", "-ca=ops"},
+			"password\n",
+			":8080",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "ocsp", Type: pki.CertTypeServer},
+			})
+
+			var addr string
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &OCSPServeCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+				serve: func(a string, h http.Handler) error {
+					addr = a
+					return nil
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.True(t, manager.OCSPHandlerCalled)
+			assert.Equal(t, test.expectedAddr, addr)
+		})
+	}
+}
+
+func TestOCSPServeCommandError(t *testing.T) {
+	tests := []struct {
+		title            string
+		noWorkspace      bool
+		args             []string
+		input            string
+		OCSPHandlerError error
+		serveError       error
+		expectedExit     int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]string{"-invalid"},
+			``,
+			nil,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoCAName",
+			false,
+			[]string{},
+			``,
+			nil,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoCAName",
+			false,
+			[]string{"-non-interactive"},
+			`root
+			password
+			password
+			`,
+			nil,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoPassword",
+			false,
+			[]string{"-non-interactive", "-ca=root"},
+			`password
+			password
+			`,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoState",
+			true,
+			[]string{"-ca=root"},
+			``,
+			nil,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"InvalidCA",
+			false,
+			[]string{"-ca=server"},
+			``,
+			nil,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"NoPassword",
+			false,
+			[]string{"-ca=root"},
+			``,
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"InvalidSigner",
+			false,
+			[]string{"-ca=root", "-signer=missing"},
+			``,
+			nil,
+			nil,
+			ErrorInvalidCert,
+		},
+		{
+			"OCSPHandlerError",
+			false,
+			[]string{"-ca=root", "-signer=server"},
+			``,
+			errors.New("error"),
+			nil,
+			ErrorOCSP,
+		},
+		{
+			"ServeError",
+			false,
+			[]string{"-ca=root", "-signer=server"},
+			``,
+			nil,
+			errors.New("error"),
+			ErrorOCSP,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+
+				writeSignMocks(t, []pki.Cert{
+					pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+					pki.Cert{Name: "server", Type: pki.CertTypeServer},
+				})
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &OCSPServeCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					OCSPHandlerError: test.OCSPHandlerError,
+				},
+				serve: func(string, http.Handler) error {
+					return test.serveError
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
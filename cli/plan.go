@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	defaultManifest = "manifest.yaml"
+
+	planAction   = " %s %-7s %s (%s): %s"
+	planSummary  = "\nPlan: %d to create, %d to renew, %d to reissue, %d to revoke.\n"
+	planUpToDate = "\nNo changes. Workspace is up to date with manifest.\n"
+
+	planSynopsis = `Shows the changes required to converge workspace to a manifest.`
+	planHelp     = `
+	You can use this command to see which certificates would be created, renewed, reissued, or revoked by applying a manifest.
+	Nothing is changed in workspace.
+
+	A manifest describes the certificates of a workspace in a YAML or TOML (.toml extension) file.
+	Certificate authorities are listed before the certificates they issue,
+	and claim fields not set in manifest are read from "spec.toml" file.
+
+		renew_before: 30
+		certs:
+		  - name: root
+		    type: root
+		    common_name: Root CA
+		    password_file: /run/secrets/root
+		  - name: sre
+		    type: intermediate
+		    ca: root
+		    common_name: SRE CA
+		    password_env: SRE_PASSWORD
+		  - name: webapp
+		    type: server
+		    ca: sre
+		    common_name: webapp.example.com
+		    dns_names: [webapp.example.com, webapp]
+		  - name: legacy
+		    type: client
+		    ca: sre
+		    revoke: superseded
+
+	A certificate not in workspace is created, a certificate expiring within renew_before days (default: 30) is renewed,
+	and a certificate with a revocation reason is revoked. Certificates in workspace not listed in manifest are left untouched.
+	A certificate is reissued if its subject or subject alternative names differ from the claim fields set for it in manifest.
+	When a certificate authority is reissued with a new subject, the certificates in manifest it has issued are renewed after it.
+
+	Flags:
+		-file    the path to manifest file (default: manifest.yaml)
+	`
+)
+
+// PlanCommand represents the plan command
+type PlanCommand struct {
+	ui  cli.Ui
+	now func() time.Time
+}
+
+// NewPlanCommand creates a new command
+func NewPlanCommand() *PlanCommand {
+	return &PlanCommand{
+		ui:  newColoredUI(),
+		now: time.Now,
+	}
+}
+
+// formatAction returns a line describing an action
+func formatAction(a pki.Action) string {
+	symbols := map[string]string{
+		pki.ActionCreate:  "+",
+		pki.ActionRenew:   "~",
+		pki.ActionReissue: "±",
+		pki.ActionRevoke:  "-",
+	}
+
+	return fmt.Sprintf(planAction, symbols[a.Type], a.Type, a.Cert.Name, a.Cert.TypeName(), a.Reason)
+}
+
+// loadManifest reads a manifest and the actions for converging workspace to it
+func loadManifest(ui cli.Ui, file string, now time.Time) (*pki.Manifest, []pki.Action, int) {
+	manifest, err := pki.LoadManifest(file)
+	if err != nil {
+		ui.Error("Failed to read manifest from " + file + ". Error: " + err.Error())
+		return nil, nil, ErrorManifest
+	}
+
+	// There should be only one root ca with a default name
+	for _, mc := range manifest.Certs {
+		if mc.Cert().Type == pki.CertTypeRoot && mc.Name != rootName {
+			ui.Error(fmt.Sprintf("Manifest is not valid. Error: root certificate authority should be named %s", rootName))
+			return nil, nil, ErrorManifest
+		}
+	}
+
+	actions, err := manifest.Plan(now)
+	if err != nil {
+		ui.Error("Failed to plan manifest. Error: " + err.Error())
+		return nil, nil, ErrorPlan
+	}
+
+	return manifest, actions, 0
+}
+
+// Synopsis returns the short help text for command
+func (c *PlanCommand) Synopsis() string {
+	return planSynopsis
+}
+
+// Help returns the long help text for command
+func (c *PlanCommand) Help() string {
+	return planHelp
+}
+
+// Run executes the command
+func (c *PlanCommand) Run(args []string) int {
+	var fFile string
+
+	flags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fFile, "file", defaultManifest, "")
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	_, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	_, actions, status := loadManifest(c.ui, fFile, c.now())
+	if status != 0 {
+		return status
+	}
+
+	if len(actions) == 0 {
+		c.ui.Info(planUpToDate)
+		return 0
+	}
+
+	counts := map[string]int{}
+	c.ui.Output("")
+	for _, a := range actions {
+		counts[a.Type]++
+		c.ui.Output(formatAction(a))
+	}
+
+	c.ui.Output(fmt.Sprintf(planSummary, counts[pki.ActionCreate], counts[pki.ActionRenew], counts[pki.ActionReissue], counts[pki.ActionRevoke]))
+
+	return 0
+}
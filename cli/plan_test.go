@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	planManifest = `
+certs:
+  - name: root
+    type: root
+  - name: sre
+    type: intermediate
+    ca: root
+  - name: webapp
+    type: server
+    ca: sre
+  - name: service
+    type: client
+    ca: sre
+    revoke: keyCompromise
+  - name: api
+    type: server
+    ca: sre
+    common_name: api.example.com
+`
+
+	driftManifest = `
+certs:
+  - name: root
+    type: root
+  - name: sre
+    type: intermediate
+    ca: root
+  - name: service
+    type: client
+    ca: sre
+    common_name: auth.service
+    email_addresses: [auth@example.com]
+`
+
+	upToDateManifest = `
+certs:
+  - name: root
+    type: root
+  - name: sre
+    type: intermediate
+    ca: root
+  - name: service
+    type: client
+    ca: sre
+`
+)
+
+func writeManifest(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestNewPlanCommand(t *testing.T) {
+	cmd := NewPlanCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.NotNil(t, cmd.now)
+
+	assert.Equal(t, "Shows the changes required to converge workspace to a manifest.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestFormatAction(t *testing.T) {
+	tests := []struct {
+		name           string
+		action         pki.Action
+		expectedOutput string
+	}{
+		{
+			"Create",
+			pki.Action{Type: pki.ActionCreate, Cert: pki.Cert{Name: "webapp", Type: pki.CertTypeServer}, Reason: "does not exist"},
+			" + create  webapp (server): does not exist",
+		},
+		{
+			"Renew",
+			pki.Action{Type: pki.ActionRenew, Cert: pki.Cert{Name: "sre", Type: pki.CertTypeInterm}, Reason: "is revoked"},
+			" ~ renew   sre (intermediate): is revoked",
+		},
+		{
+			"Reissue",
+			pki.Action{Type: pki.ActionReissue, Cert: pki.Cert{Name: "webapp", Type: pki.CertTypeServer}, Reason: "has changed dns_names"},
+			" ± reissue webapp (server): has changed dns_names",
+		},
+		{
+			"Revoke",
+			pki.Action{Type: pki.ActionRevoke, Cert: pki.Cert{Name: "service", Type: pki.CertTypeClient}, Reason: "superseded"},
+			" - revoke  service (client): superseded",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedOutput, formatAction(test.action))
+		})
+	}
+}
+
+func TestPlanCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	writeListMocks(t)
+
+	tests := []struct {
+		title          string
+		manifest       string
+		expectedOutput []string
+	}{
+		{
+			"Changes",
+			planManifest,
+			[]string{
+				" ~ renew   webapp (server): expires on ",
+				" - revoke  service (client): keyCompromise",
+				" + create  api (server): does not exist",
+				"Plan: 1 to create, 1 to renew, 0 to reissue, 1 to revoke.",
+			},
+		},
+		{
+			"Drift",
+			driftManifest,
+			[]string{
+				" ± reissue service (client): has changed common_name, email_addresses",
+				"Plan: 0 to create, 0 to renew, 1 to reissue, 0 to revoke.",
+			},
+		},
+		{
+			"UpToDate",
+			upToDateManifest,
+			[]string{
+				"No changes. Workspace is up to date with manifest.",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &PlanCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run([]string{"-file=" + writeManifest(t, test.manifest)})
+			assert.Zero(t, exit)
+
+			output := mockUI.OutputWriter.String()
+			for _, expected := range test.expectedOutput {
+				assert.Contains(t, output, expected)
+			}
+		})
+	}
+}
+
+func TestPlanCommandError(t *testing.T) {
+	tests := []struct {
+		title         string
+		args          []string
+		manifest      string
+		expectedExit  int
+		expectedError string
+	}{
+		{
+			"InvalidFlag",
+			[]string{"-invalid"},
+			"",
+			ErrorInvalidFlag,
+			"",
+		},
+		{
+			"NoManifest",
+			[]string{"-file=manifest.yaml"},
+			"",
+			ErrorManifest,
+			"Failed to read manifest from manifest.yaml",
+		},
+		{
+			"InvalidManifest",
+			nil,
+			"certs:\n  - name: webapp\n    type: server\n    ca: sre\n",
+			ErrorManifest,
+			"issuer of webapp is not listed before it",
+		},
+		{
+			"InvalidRootName",
+			nil,
+			"certs:\n  - name: ca\n    type: root\n",
+			ErrorManifest,
+			"root certificate authority should be named root",
+		},
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			args := test.args
+			if args == nil {
+				args = []string{"-file=" + writeManifest(t, test.manifest)}
+			}
+
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &PlanCommand{
+				ui:  mockUI,
+				now: time.Now,
+			}
+
+			exit := cmd.Run(args)
+			assert.Equal(t, test.expectedExit, exit)
+			assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+		})
+	}
+}
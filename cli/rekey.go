@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	rekeySuccess       = " ✓ Re-encrypted %s"
+	rekeyFailure       = " ✗ Failed to re-encrypt %s. Error: %s"
+	rekeyNoCA          = " No certificate authority found."
+	rekeyEnterPassword = "\nENTER PASSWORD FOR %s ..."
+
+	rekeySynopsis = `Re-encrypts private keys of certificate authorities.`
+	rekeyHelp     = `
+	You can use this command to migrate private keys of certificate authorities to the current storage format.
+	Each private key is re-encrypted in place as a PKCS#8 encrypted private key using PBES2 (PBKDF2-HMAC-SHA256 and AES-256-CBC).
+	Both legacy PEM-encrypted private keys and PKCS#8 encrypted private keys can be read.
+
+	You will be asked for entering the password for each certificate authorithy.
+	The passwords of certificate authorities will not change.
+
+	Using -non-interactive flag, nothing is asked and the password is required.
+	The password is read from -password-file, -password-env, or a line of standard input,
+	and is used for every certificate authorithy.
+
+	Flags:
+		-name               the names of certificate authorities (all certificate authorities by default)
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authorities from a file
+		-password-env       read the password of certificate authorities from an environment variable
+	`
+)
+
+// RekeyStorageCommand represents the command for re-encrypting private keys
+type RekeyStorageCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+}
+
+// NewRekeyStorageCommand creates a new command
+func NewRekeyStorageCommand() *RekeyStorageCommand {
+	return &RekeyStorageCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+func (c *RekeyStorageCommand) resolveCAs(names string) ([]pki.Cert, int) {
+	if names == "" {
+		roots, err := pki.ListCerts(pki.CertTypeRoot)
+		if err != nil {
+			c.ui.Error("Failed to list root certificate authorities. Error: " + err.Error())
+			return nil, ErrorInvalidCA
+		}
+
+		interms, err := pki.ListCerts(pki.CertTypeInterm)
+		if err != nil {
+			c.ui.Error("Failed to list intermediate certificate authorities. Error: " + err.Error())
+			return nil, ErrorInvalidCA
+		}
+
+		return append(roots, interms...), 0
+	}
+
+	certs := make([]pki.Cert, 0)
+	for _, name := range strings.Split(names, ",") {
+		cCA := resolveByName(name)
+		if cCA.Type != pki.CertTypeRoot && cCA.Type != pki.CertTypeInterm {
+			c.ui.Error("Certificate authority name is not valid.")
+			return nil, ErrorInvalidCA
+		}
+		certs = append(certs, cCA)
+	}
+
+	return certs, 0
+}
+
+// Synopsis returns the short help text for command
+func (c *RekeyStorageCommand) Synopsis() string {
+	return rekeySynopsis
+}
+
+// Help returns the long help text for command
+func (c *RekeyStorageCommand) Help() string {
+	return rekeyHelp
+}
+
+// Run executes the command
+func (c *RekeyStorageCommand) Run(args []string) (exit int) {
+	var fName string
+
+	flags := flag.NewFlagSet("rekey-storage", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fName, "name", "", "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	state, _, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	certs, status := c.resolveCAs(fName)
+	if status != 0 {
+		return status
+	}
+
+	if len(certs) == 0 {
+		c.ui.Warn(rekeyNoCA)
+		return 0
+	}
+
+	for _, cCA := range certs {
+		// Type field is ensured to be valid
+		config, _ := state.ConfigFor(cCA.Type)
+
+		c.ui.Output(fmt.Sprintf(rekeyEnterPassword, cCA.Name))
+		config.Password, err = in.askPassword(c.ui, func() (string, error) {
+			return c.ui.AskSecret(fmt.Sprintf(promptTemplate, "Password", "string"))
+		})
+		if err != nil {
+			return ErrorEnterConfig
+		}
+
+		err = c.pki.ReencryptKey(config, cCA)
+		if err != nil {
+			c.ui.Error(fmt.Sprintf(rekeyFailure, cCA.Name, err.Error()))
+			exit = ErrorRekey
+		} else {
+			c.ui.Info(fmt.Sprintf(rekeySuccess, cCA.Name))
+		}
+	}
+
+	c.ui.Output("")
+
+	return exit
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRekeyStorageCommand(t *testing.T) {
+	cmd := NewRekeyStorageCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+
+	assert.Equal(t, "Re-encrypts private keys of certificate authorities.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestRekeyStorageCommand(t *testing.T) {
+	tests := []struct {
+		title         string
+		mocks         []pki.Cert
+		args          []string
+		input         string
+		expectedCalls bool
+	}{
+		{
+			"NoCA",
+			[]pki.Cert{},
+			[]string{},
+			``,
+			false,
+		},
+		{
+			"AllCAs",
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "sre", Type: pki.CertTypeInterm},
+			},
+			[]string{},
+			`rootSecret
+			opsSecret
+			sreSecret
+			`,
+			true,
+		},
+		{
+			"NamedCAs",
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "sre", Type: pki.CertTypeInterm},
+			},
+			[]string{"-name=ops,sre"},
+			`opsSecret
+			sreSecret
+			`,
+			true,
+		},
+		{
+			"NonInteractive",
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+			},
+			[]string{"-non-interactive"},
+			"sharedSecret\n",
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, test.mocks)
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &RekeyStorageCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.Equal(t, test.expectedCalls, manager.ReencryptKeyCalled)
+		})
+	}
+}
+
+func TestRekeyStorageCommandError(t *testing.T) {
+	tests := []struct {
+		title             string
+		noWorkspace       bool
+		mocks             []pki.Cert
+		args              []string
+		input             string
+		ReencryptKeyError error
+		expectedExit      int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]pki.Cert{},
+			[]string{"-invalid"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoState",
+			true,
+			[]pki.Cert{},
+			[]string{},
+			``,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"InvalidCA",
+			false,
+			[]pki.Cert{
+				pki.Cert{Name: "server", Type: pki.CertTypeServer},
+			},
+			[]string{"-name=server"},
+			``,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"NoPassword",
+			false,
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+			},
+			[]string{},
+			``,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NonInteractiveNoPassword",
+			false,
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+			},
+			[]string{"-non-interactive"},
+			`rootSecret
+			`,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"ReencryptKeyError",
+			false,
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+			},
+			[]string{"-name=root"},
+			`rootSecret
+			`,
+			errors.New("error"),
+			ErrorRekey,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+
+				writeSignMocks(t, test.mocks)
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &RekeyStorageCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					ReencryptKeyError: test.ReencryptKeyError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
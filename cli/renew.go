@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	renewSuccess       = "\n ✓ Renewed %s (issued by %s)\n"
+	renewViolated      = " ✗ Failed to renew %s. Error: certificate does not satisfy trust policy of %s"
+	renewEnterNameCert = "\nENTER NAME FOR CERTIFICATE ..."
+
+	renewSynopsis = `Renews an existing certificate.`
+	renewHelp     = `
+	You can use this command to renew an existing certificate before it expires.
+	The new certificate has the same subject and subject alternative names and is issued by the same certificate authority.
+	The previous certificate is moved to archive directory with its serial number, and the issuance index is updated.
+	Certificate chains of intermediate certificate authorities are updated too.
+
+	By default, the existing key is reused. You can use -rekey flag to generate a new key for the new certificate.
+	Renewing a certificate authority with a new key requires renewing the certificates it has issued.
+	A certificate signed from an external request has no key in workspace and cannot be renewed,
+	so a new request should be signed for it instead.
+
+	The certificate is only renewed if it satisfies the current trust policy of its certificate authority,
+	since the policy may have been tightened after the certificate was issued.
+	You can use -ignore-policy flag to renew a certificate regardless of trust policy.
+
+	You will be asked for entering the password for certificate authorithy.
+
+	Using -non-interactive flag, nothing is asked and -name and the password are required.
+	The password is read from -password-file, -password-env, or a line of standard input.
+	When renewing an intermediate certificate authority, the same password is used for it and its issuer.
+
+	Flags:
+		-name               the name of certificate
+		-rekey              generate a new key for the new certificate (default: false)
+		-ignore-policy      renew the certificate even if it does not satisfy trust policy (default: false)
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
+	`
+)
+
+// RenewCommand represents the renew command
+type RenewCommand struct {
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+}
+
+// NewRenewCommand creates a new command
+func NewRenewCommand() *RenewCommand {
+	return &RenewCommand{
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *RenewCommand) Synopsis() string {
+	return renewSynopsis
+}
+
+// Help returns the long help text for command
+func (c *RenewCommand) Help() string {
+	return renewHelp
+}
+
+// Run executes the command
+func (c *RenewCommand) Run(args []string) int {
+	var fName string
+	var fRekey, fIgnorePolicy bool
+
+	flags := flag.NewFlagSet("renew", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fName, "name", "", "")
+	flags.BoolVar(&fRekey, "rekey", false, "")
+	flags.BoolVar(&fIgnorePolicy, "ignore-policy", false, "")
+	in := newInput(flags, c.stdin)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fName == "" {
+		c.ui.Output(renewEnterNameCert)
+		fName, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "Cert Name", "string"), "name")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	state, spec, status := loadWorkspace(c.ui)
+	if status != 0 {
+		return status
+	}
+
+	cCert := resolveByName(fName)
+	if cCert.Type == 0 {
+		c.ui.Error("Certificate name is not valid.")
+		return ErrorInvalidCert
+	}
+
+	cCA, err := pki.FindIssuer(cCert)
+	if err != nil {
+		c.ui.Error("Failed to find certificate authority. Error: " + err.Error())
+		return ErrorInvalidCA
+	}
+
+	// Type fields are ensured to be valid
+	configCA, _ := state.ConfigFor(cCA.Type)
+	err = in.askForConfig(&configCA, cCA, nil, c.ui)
+	if err != nil {
+		return ErrorEnterConfig
+	}
+
+	// Root certificate authority is its own issuer
+	configCert := configCA
+	if cCert != cCA {
+		configCert, _ = state.ConfigFor(cCert.Type)
+		err = in.askForConfig(&configCert, cCert, nil, c.ui)
+		if err != nil {
+			return ErrorEnterConfig
+		}
+	}
+
+	// An empty policy does not evaluate any rule
+	var policyCA pki.Policy
+	if !fIgnorePolicy {
+		// Type field is ensured to be valid
+		policyCA, _ = spec.PolicyForCA(cCA)
+	}
+
+	var policyErr *pki.PolicyError
+	err = c.pki.RenewCert(configCA, cCA, configCert, cCert, fRekey, pki.PolicyTrustFunc(policyCA, cCert.Type))
+	if errors.As(err, &policyErr) {
+		c.ui.Error(fmt.Sprintf(renewViolated, cCert.Name, cCA.Name))
+		printViolations(c.ui, policyErr.Violations)
+		return ErrorRenew
+	} else if err != nil {
+		c.ui.Error("Failed to renew certificate. Error: " + err.Error())
+		return ErrorRenew
+	}
+
+	c.ui.Info(fmt.Sprintf(renewSuccess, cCert.Name, cCA.Name))
+
+	return 0
+}
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRenewCommand(t *testing.T) {
+	cmd := NewRenewCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+	assert.Equal(t, os.Stdin, cmd.stdin)
+
+	assert.Equal(t, "Renews an existing certificate.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestRenewCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	tests := []struct {
+		title string
+		args  []string
+		input string
+	}{
+		{
+			"Root",
+			[]string{"-name=root"},
+			`password
+			password
+			`,
+		},
+		{
+			"Intermediate",
+			[]string{"-name=sre", "-rekey"},
+			`password
+			password
+			password
+			password
+			`,
+		},
+		{
+			"Server",
+			[]string{},
+			`webapp
+			password
+			password
+			`,
+		},
+		{
+			"Client",
+			[]string{"-name=service", "-rekey"},
+			`password
+			password
+			`,
+		},
+		{
+			"NonInteractive",
+			[]string{"-non-interactive", "-name=sre"},
+			"password\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeListMocks(t)
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &RenewCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(test.input),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.True(t, manager.RenewCertCalled)
+		})
+	}
+}
+
+func TestRenewCommandError(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	tests := []struct {
+		title          string
+		noWorkspace    bool
+		args           []string
+		input          string
+		RenewCertError error
+		expectedExit   int
+	}{
+		{
+			"InvalidFlag",
+			false,
+			[]string{"-invalid"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoName",
+			false,
+			[]string{},
+			``,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoName",
+			false,
+			[]string{"-non-interactive"},
+			"webapp\n",
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoPassword",
+			false,
+			[]string{"-non-interactive", "-name=webapp"},
+			`password
+			password
+			`,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NoState",
+			true,
+			[]string{"-name=webapp"},
+			``,
+			nil,
+			ErrorReadState,
+		},
+		{
+			"PendingRequest",
+			false,
+			[]string{"-name=pending"},
+			``,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"UnknownCert",
+			false,
+			[]string{"-name=unknown"},
+			``,
+			nil,
+			ErrorInvalidCert,
+		},
+		{
+			"NoPassword",
+			false,
+			[]string{"-name=webapp"},
+			``,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"RenewCertError",
+			false,
+			[]string{"-name=webapp"},
+			`password
+			password
+			`,
+			errors.New("error"),
+			ErrorRenew,
+		},
+		{
+			"PolicyViolated",
+			false,
+			[]string{"-name=webapp"},
+			`password
+			password
+			`,
+			&pki.PolicyError{Violations: []pki.Violation{{Field: "Days", Expected: "at most 90", Actual: "375", Rule: "max_days"}}},
+			ErrorRenew,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if !test.noWorkspace {
+				err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+				assert.NoError(t, err)
+				defer pki.CleanupWorkspace() // nolint: errcheck
+
+				writeListMocks(t)
+			}
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &RenewCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					RenewCertError: test.RenewCertError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
+
+func TestRenewCommandPolicy(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	state := pki.NewState()
+	for _, config := range []*pki.Config{&state.Root, &state.Interm} {
+		config.Algorithm = pki.AlgorithmECDSA
+		config.Length = 256
+		config.Password = "password"
+	}
+	state.Server.Algorithm = pki.AlgorithmECDSA
+	state.Server.Length = 256
+
+	spec := pki.NewSpec()
+	err := pki.NewWorkspace(state, spec)
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	usage, _ := spec.UsageFor(pki.CertTypeInterm)
+	manager := pki.NewX509Manager()
+	cRoot := pki.Cert{Name: rootName, Type: pki.CertTypeRoot}
+	cOps := pki.Cert{Name: "ops", Type: pki.CertTypeInterm}
+	cServer := pki.Cert{Name: "webapp", Type: pki.CertTypeServer}
+	trust := pki.PolicyTrustFunc(pki.Policy{}, pki.CertTypeServer)
+	assert.NoError(t, manager.GenCert(state.Root, pki.Claim{CommonName: "Root"}, cRoot))
+	assert.NoError(t, manager.GenCSR(state.Interm, pki.Claim{CommonName: "Ops"}, cOps))
+	assert.NoError(t, manager.SignCSR(state.Root, cRoot, state.Interm, cOps, usage, trust))
+	assert.NoError(t, manager.GenCSR(state.Server, pki.Claim{CommonName: "webapp", DNSName: []string{"webapp.example.com"}}, cServer))
+	assert.NoError(t, manager.SignCSR(state.Interm, cOps, state.Server, cServer, pki.Usage{}, trust))
+
+	// The ops intermediate only signs servers in its own subdomain after the server is issued
+	spec.Authorities = map[string]pki.Authority{
+		"ops": pki.Authority{
+			Policy: &pki.Policy{DNSNames: []string{"*.ops.example.com"}},
+		},
+	}
+	assert.NoError(t, pki.SaveSpec(spec, pki.FileSpec))
+
+	tests := []struct {
+		title         string
+		args          []string
+		expectedExit  int
+		expectedError string
+	}{
+		{"Violated", []string{"-name=webapp"}, ErrorRenew, "DNSNames: expected *.ops.example.com, got webapp.example.com (dns_names)"},
+		{"IgnorePolicy", []string{"-name=webapp", "-ignore-policy"}, 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			mockUI := newMockUI(strings.NewReader("password\npassword\n"))
+			cmd := &RenewCommand{
+				ui:  mockUI,
+				pki: manager,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+			assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"text/template"
 
@@ -34,26 +36,41 @@ const (
 
 	You can enter a list by comma-separating values.
 	If you don't want to use any of the specs, leave it empty.
-	{{if ne .Type 1}}
+
+	Configs and specs can also be given as flags or GOCERT_* environment variables (e.g. -dns-name or GOCERT_DNS_NAME),
+	and the same goes for the flags below (e.g. GOCERT_NON_INTERACTIVE and GOCERT_PASSWORD_FILE).
+	Using -non-interactive flag, nothing is asked and a missing common name, config, or password is an error.
+
 	Flags:
-		-name    set a name for the new certificate
+	{{- if ne .Type 1}}
+		-name               set a name for the new certificate
 	{{- end}}
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
+		-common-name, -country, -province, -locality, -organization, -organizational-unit,
+		-dns-name, -ip-address, -email-address, -uri, -street-address, -postal-code
+		                    set a claim (lists are comma-separated)
+		-algorithm, -length, -days, -max-path-len
+		                    set a config
 	`
 )
 
 // ReqCommand represents the command for generating a new csr
 type ReqCommand struct {
-	ui  cli.Ui
-	pki pki.Manager
-	c   pki.Cert
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
+	c     pki.Cert
 }
 
 // NewReqCommand creates a new command
 func NewReqCommand(c pki.Cert) *ReqCommand {
 	return &ReqCommand{
-		ui:  newColoredUI(),
-		pki: pki.NewX509Manager(),
-		c:   c,
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+		c:     c,
 	}
 }
 
@@ -98,6 +115,7 @@ func (c *ReqCommand) Run(args []string) int {
 	flags := flag.NewFlagSet("req", flag.ContinueOnError)
 	flags.Usage = func() {}
 	flags.StringVar(&c.c.Name, "name", "", "")
+	in := newInput(flags, c.stdin, configInputs, claimInputs)
 	err := flags.Parse(args)
 	if err != nil {
 		return ErrorInvalidFlag
@@ -110,7 +128,7 @@ func (c *ReqCommand) Run(args []string) int {
 
 	if c.c.Name == "" {
 		c.output(reqEnterName)
-		c.c.Name, err = c.ui.Ask(fmt.Sprintf(promptTemplate, "Name", "string"))
+		c.c.Name, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "Name", "string"), "name")
 		if err != nil {
 			return ErrorInvalidName
 		}
@@ -127,13 +145,18 @@ func (c *ReqCommand) Run(args []string) int {
 		return ErrorInvalidCert
 	}
 
-	err = askForConfig(&config, c.c, nil, c.ui)
+	// Path length of a certificate authority by its name takes precedence over its type
+	if maxPathLen := spec.AuthorityFor(c.c.Name).MaxPathLen; maxPathLen != nil {
+		config.MaxPathLen = maxPathLen
+	}
+
+	err = in.askForConfig(&config, c.c, nil, c.ui)
 	if err != nil {
 		return ErrorEnterConfig
 	}
 
 	skipList := c.getSkipList(spec)
-	err = askForClaim(&claim, c.c, &skipList, c.ui)
+	err = in.askForClaim(&claim, c.c, &skipList, c.ui)
 	if err != nil {
 		return ErrorEnterClaim
 	}
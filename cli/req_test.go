@@ -2,6 +2,7 @@ package cli
 
 import (
 	"errors"
+	"os"
 	"strings"
 	"testing"
 
@@ -37,6 +38,7 @@ func TestNewReqCommand(t *testing.T) {
 
 		assert.Equal(t, newColoredUI(), cmd.ui)
 		assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+		assert.Equal(t, os.Stdin, cmd.stdin)
 		assert.Equal(t, test.c, cmd.c)
 
 		assert.Equal(t, test.expectedSynopsis, cmd.Synopsis())
@@ -60,7 +62,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeRoot},
 			[]string{},
 			"password\npassword\n" +
-				"RootCA\n\n\n\n\n\n\n\n\n\n\n",
+				"RootCA\n\n\n\n\n\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateRootCAWithCustomSpec",
@@ -75,7 +77,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeRoot},
 			[]string{},
 			"password\npassword\n" +
-				"RootCA\n\n\n\n\n\n\n\n\n",
+				"RootCA\n\n\n\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateRootCAWithCustomSpecAndSkip",
@@ -93,7 +95,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeRoot},
 			[]string{},
 			"password\npassword\n" +
-				"RootCA\n\n\n\n",
+				"RootCA\n\n\n\n\n",
 		},
 		{
 			"GenerateIntermediateCAWithDefaultSpec",
@@ -103,7 +105,7 @@ func TestReqCommand(t *testing.T) {
 			[]string{},
 			"sre\n" +
 				"password\npassword\n" +
-				"SRE CA\n\n\n\n\n\n\n\n\n\n\n",
+				"SRE CA\n\n\n\n\n\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateIntermediateCAWithCustomSpec",
@@ -118,7 +120,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeInterm},
 			[]string{"-name=sre"},
 			"password\npassword\n" +
-				"SRE CA\nOttawa\nSRE\n\n\n\n\n\n",
+				"SRE CA\nOttawa\nSRE\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateIntermediateCAWithCustomSpecAndSkip",
@@ -136,7 +138,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeInterm},
 			[]string{"-name=sre"},
 			"password\npassword\n" +
-				"SRE CA\nOttawa\nSRE\n",
+				"SRE CA\nOttawa\nSRE\n\n",
 		},
 		{
 			"GenerateServerCertWithDefaultSpec",
@@ -145,7 +147,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeServer},
 			[]string{},
 			"webapp\n" +
-				"webapp.com\n\n\n\n\n\n\n\n\n\n\n",
+				"webapp.com\n\n\n\n\n\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateServerCertWithCustomSpec",
@@ -160,7 +162,7 @@ func TestReqCommand(t *testing.T) {
 			},
 			pki.Cert{Type: pki.CertTypeServer},
 			[]string{"-name=webapp"},
-			"webapp.com\nR&D\nwebapp.com\n\n\n\n\n",
+			"webapp.com\nR&D\nwebapp.com\n\n\n\n\n\n",
 		},
 		{
 			"GenerateServerCertWithCustomSpecAndSkip",
@@ -178,7 +180,7 @@ func TestReqCommand(t *testing.T) {
 			},
 			pki.Cert{Type: pki.CertTypeServer},
 			[]string{"-name=webapp"},
-			"webapp.com\nR&D\nwebapp.com\n127.0.0.1\n\n",
+			"webapp.com\nR&D\nwebapp.com\n127.0.0.1\n\n\n",
 		},
 		{
 			"GenerateClientCertWithDefaultSpec",
@@ -187,7 +189,7 @@ func TestReqCommand(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeClient},
 			[]string{},
 			"myservice\n" +
-				"MyService\n\n\n\n\n\n\n\n\n\n\n",
+				"MyService\n\n\n\n\n\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateClientCertWithCustomSpec",
@@ -202,7 +204,7 @@ func TestReqCommand(t *testing.T) {
 			},
 			pki.Cert{Type: pki.CertTypeClient},
 			[]string{"-name=myservice"},
-			"MyService\nR&D\n\n\n\n\n\n",
+			"MyService\nR&D\n\n\n\n\n\n\n",
 		},
 		{
 			"GenerateClientCertWithCustomSpecAndSkip",
@@ -220,7 +222,23 @@ func TestReqCommand(t *testing.T) {
 			},
 			pki.Cert{Type: pki.CertTypeClient},
 			[]string{"-name=myservice"},
-			"MyService\nQE\n\n\n\n",
+			"MyService\nQE\n\n\n\n\n",
+		},
+		{
+			"GenerateRootCANonInteractive",
+			pki.NewState(),
+			pki.NewSpec(),
+			pki.Cert{Type: pki.CertTypeRoot},
+			[]string{"-non-interactive", "-common-name=Root CA", "-algorithm=ecdsa", "-length=384"},
+			"password\n",
+		},
+		{
+			"GenerateServerCertNonInteractive",
+			pki.NewState(),
+			pki.NewSpec(),
+			pki.Cert{Type: pki.CertTypeServer},
+			[]string{"-non-interactive", "-name=webapp", "-common-name=webapp.example.com", "-dns-name=webapp.example.com,webapp"},
+			"",
 		},
 	}
 
@@ -232,9 +250,10 @@ func TestReqCommand(t *testing.T) {
 			r := strings.NewReader(test.input)
 			mockUI := newMockUI(r)
 			cmd := &ReqCommand{
-				ui:  mockUI,
-				pki: &mockManager{},
-				c:   test.c,
+				ui:    mockUI,
+				pki:   &mockManager{},
+				stdin: strings.NewReader(test.input),
+				c:     test.c,
 			}
 
 			exit := cmd.Run(test.args)
@@ -335,6 +354,39 @@ func TestReqCommandError(t *testing.T) {
 			nil,
 			ErrorEnterClaim,
 		},
+		{
+			"NonInteractiveNoName",
+			pki.NewState(),
+			pki.NewSpec(),
+			pki.Cert{Type: pki.CertTypeServer},
+			[]string{"-non-interactive"},
+			"",
+			nil,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoPassword",
+			pki.NewState(),
+			pki.NewSpec(),
+			pki.Cert{Type: pki.CertTypeRoot},
+			[]string{"-non-interactive", "-common-name=Root CA"},
+			"",
+			nil,
+			nil,
+			ErrorEnterConfig,
+		},
+		{
+			"NonInteractiveNoCommonName",
+			pki.NewState(),
+			pki.NewSpec(),
+			pki.Cert{Type: pki.CertTypeRoot},
+			[]string{"-non-interactive"},
+			"password\n",
+			nil,
+			nil,
+			ErrorEnterClaim,
+		},
 		{
 			"GenCertFails",
 			pki.NewState(),
@@ -342,7 +394,7 @@ func TestReqCommandError(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeRoot},
 			[]string{},
 			"password\npassword\n" +
-				"\n\n\n\n\n\n\n\n\n\n\n",
+				"\n\n\n\n\n\n\n\n\n\n\n\n",
 			errors.New("error"),
 			nil,
 			ErrorCert,
@@ -354,7 +406,7 @@ func TestReqCommandError(t *testing.T) {
 			pki.Cert{Type: pki.CertTypeInterm},
 			[]string{"-name=sre"},
 			"password\npassword\n" +
-				"\n\n\n\n\n\n\n\n\n\n\n",
+				"\n\n\n\n\n\n\n\n\n\n\n\n",
 			nil,
 			errors.New("error"),
 			ErrorCSR,
@@ -365,7 +417,7 @@ func TestReqCommandError(t *testing.T) {
 			pki.NewSpec(),
 			pki.Cert{Type: pki.CertTypeServer},
 			[]string{"-name=webapp"},
-			"\n\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n\n",
 			nil,
 			errors.New("error"),
 			ErrorCSR,
@@ -376,7 +428,7 @@ func TestReqCommandError(t *testing.T) {
 			pki.NewSpec(),
 			pki.Cert{Type: pki.CertTypeClient},
 			[]string{"-name", "myservice"},
-			"\n\n\n\n\n\n\n\n\n\n\n",
+			"\n\n\n\n\n\n\n\n\n\n\n\n",
 			nil,
 			errors.New("error"),
 			ErrorCSR,
@@ -396,7 +448,8 @@ func TestReqCommandError(t *testing.T) {
 					GenCertError: test.GenCertError,
 					GenCSRError:  test.GenCSRError,
 				},
-				c: test.c,
+				stdin: strings.NewReader(test.input),
+				c:     test.c,
 			}
 
 			exit := cmd.Run(test.args)
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/moorara/gocert/pki"
+)
+
+const (
+	revokeSuccess       = " ✓ Revoked %s"
+	revokeFailure       = " ✗ Failed to revoke %s. Error: %s"
+	revokeEnterNameCA   = "\nENTER NAME FOR CERTIFICATE AUTHORITY ..."
+	revokeEnterNameCert = "\nENTER NAME FOR CERTIFICATE ..."
+
+	revokeSynopsis = `Revokes a certificate issued by a certificate authority.`
+	revokeHelp     = `
+	You can use this command to revoke a certificate issued by a certificate authority.
+	The revocation is recorded in the workspace and will be included in the next certificate revocation list of certificate authority.
+	The root certificate authorithy can revoke intermediate certificate authorities.
+	Intermediate certificate authorities can revoke server/client certificates they have signed.
+
+	Using -non-interactive flag, nothing is asked and -ca and -name are required.
+
+	Flags:
+		-ca                 the name of certificate authorithy
+		-name               the name of certificate
+		-reason             the reason for revocation (default: unspecified)
+		                    unspecified, keyCompromise, caCompromise, affiliationChanged, superseded,
+		                    cessationOfOperation, certificateHold, privilegeWithdrawn, aACompromise
+		-non-interactive    never prompt for values
+	`
+)
+
+// RevokeCommand represents the revoke command
+type RevokeCommand struct {
+	ui  cli.Ui
+	pki pki.Manager
+}
+
+// NewRevokeCommand creates a new command
+func NewRevokeCommand() *RevokeCommand {
+	return &RevokeCommand{
+		ui:  newColoredUI(),
+		pki: pki.NewX509Manager(),
+	}
+}
+
+// Synopsis returns the short help text for command
+func (c *RevokeCommand) Synopsis() string {
+	return revokeSynopsis
+}
+
+// Help returns the long help text for command
+func (c *RevokeCommand) Help() string {
+	return revokeHelp
+}
+
+// Run executes the command
+func (c *RevokeCommand) Run(args []string) (exit int) {
+	var fCA, fName, fReason string
+
+	flags := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.StringVar(&fCA, "ca", "", "")
+	flags.StringVar(&fName, "name", "", "")
+	flags.StringVar(&fReason, "reason", pki.ReasonUnspecified, "")
+	in := newInput(flags, nil)
+	err := flags.Parse(args)
+	if err != nil {
+		return ErrorInvalidFlag
+	}
+
+	if fCA == "" {
+		c.ui.Output(revokeEnterNameCA)
+		fCA, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "CA Name", "string"), "ca")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	if fName == "" {
+		c.ui.Output(revokeEnterNameCert)
+		fName, err = in.ask(c.ui, fmt.Sprintf(promptTemplate, "Cert Name", "string list"), "name")
+		if err != nil {
+			return ErrorInvalidName
+		}
+	}
+
+	cCA := resolveByName(fCA)
+	if cCA.Type != pki.CertTypeRoot && cCA.Type != pki.CertTypeInterm {
+		c.ui.Error("Certificate authority name is not valid.")
+		return ErrorInvalidCA
+	}
+
+	c.ui.Output("")
+
+	certNames := strings.Split(fName, ",")
+
+	for _, certName := range certNames {
+		cCert := resolveByName(certName)
+
+		if cCert.Type == 0 || cCert.Type == pki.CertTypeRoot {
+			c.ui.Error(fmt.Sprintf(revokeFailure, certName, "certificate name is not valid"))
+			exit = ErrorInvalidCert
+			continue
+		}
+
+		err = c.pki.RevokeCert(cCA, cCert, fReason)
+		if err != nil {
+			c.ui.Error(fmt.Sprintf(revokeFailure, cCert.Name, err.Error()))
+			exit = ErrorRevoke
+		} else {
+			c.ui.Info(fmt.Sprintf(revokeSuccess, cCert.Name))
+		}
+	}
+
+	c.ui.Output("")
+
+	return exit
+}
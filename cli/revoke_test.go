@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/moorara/gocert/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRevokeCommand(t *testing.T) {
+	cmd := NewRevokeCommand()
+
+	assert.Equal(t, newColoredUI(), cmd.ui)
+	assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+
+	assert.Equal(t, "Revokes a certificate issued by a certificate authority.", cmd.Synopsis())
+	assert.NotEmpty(t, cmd.Help())
+}
+
+func TestRevokeCommand(t *testing.T) {
+	tests := []struct {
+		title string
+		args  []string
+		input string
+	}{
+		{
+			"RootRevokesIntermediate",
+			[]string{},
+			`root
+			ops
+			`,
+		},
+		{
+			"IntermediateRevokesServer",
+			[]string{"-ca=ops"},
+			`server
+			`,
+		},
+		{
+			"IntermediateRevokesServerClient",
+			[]string{"-ca=ops", "-name=server,client", "-reason=keyCompromise"},
+			``,
+		},
+		{
+			"NonInteractive",
+			[]string{"-non-interactive", "-ca=ops", "-name=server"},
+			``,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, []pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "server", Type: pki.CertTypeServer},
+				pki.Cert{Name: "client", Type: pki.CertTypeClient},
+			})
+
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			manager := &mockManager{}
+			cmd := &RevokeCommand{
+				ui:  mockUI,
+				pki: manager,
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Zero(t, exit)
+			assert.True(t, manager.RevokeCertCalled)
+		})
+	}
+}
+
+func TestRevokeCommandError(t *testing.T) {
+	tests := []struct {
+		title           string
+		args            []string
+		input           string
+		RevokeCertError error
+		expectedExit    int
+	}{
+		{
+			"InvalidFlag",
+			[]string{"-invalid"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NoCAName",
+			[]string{},
+			``,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NoCertName",
+			[]string{},
+			`ops
+			`,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoCAName",
+			[]string{"-non-interactive", "-name=server"},
+			`ops
+			`,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"NonInteractiveNoCertName",
+			[]string{"-non-interactive", "-ca=ops"},
+			`server
+			`,
+			nil,
+			ErrorInvalidName,
+		},
+		{
+			"InvalidCA",
+			[]string{"-ca=server", "-name=client"},
+			``,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"InvalidCert",
+			[]string{"-ca=ops", "-name=root"},
+			``,
+			nil,
+			ErrorInvalidCert,
+		},
+		{
+			"RevokeCertError",
+			[]string{"-ca=ops", "-name=server"},
+			``,
+			errors.New("error"),
+			ErrorRevoke,
+		},
+	}
+
+	err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	writeSignMocks(t, []pki.Cert{
+		pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+		pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+		pki.Cert{Name: "server", Type: pki.CertTypeServer},
+		pki.Cert{Name: "client", Type: pki.CertTypeClient},
+	})
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			r := strings.NewReader(test.input)
+			mockUI := newMockUI(r)
+			cmd := &RevokeCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					RevokeCertError: test.RevokeCertError,
+				},
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/mitchellh/cli"
@@ -12,36 +16,110 @@ import (
 const (
 	signSuccess        = " ✓ Signed %s"
 	signFailure        = " ✗ Failed to sign %s. Error: %s"
+	signViolated       = " ✗ Failed to sign %s. Error: CSR does not satisfy trust policy of %s"
+	signViolation      = "     - %s"
+	checkSuccess       = " ✓ %s satisfies trust policy of %s"
+	checkFailure       = " ✗ Failed to check %s. Error: %s"
+	checkViolated      = " ✗ %s does not satisfy trust policy of %s"
 	signEnterNameCA    = "\nENTER NAME FOR CERTIFICATE AUTHORITY ..."
 	signEnterNameCSR   = "\nENTER NAME FOR CERTIFICATE SIGNING REQUEST ..."
 	signEnterConfigCA  = "\nENTER CONFIGURATIONS FOR CERTIFICATE AUTHORITY ..."
 	signEnterConfigCSR = "\nENTER CONFIGURATIONS FOR NEW CERTIFICATE ..."
 
+	signPrintCert      = "cert"
+	signPrintFullchain = "fullchain"
+
 	signSynopsis = `Signs a certificate signing request.`
 	signHelp     = `
 	You can use this command to sign a certificate signing request (CSR) and create a new certificate.
 
 	You will be asked for entering the password for certificate authorithy.
 	The root certificate authorithy can only sign intermediate certificate authorities.
-	Intermediate certificate authorities can then sign other intermediate certificate authorities or server/client certificates.
+	Intermediate certificate authorities can then sign server/client certificates.
+	By default, intermediates have a path length of 0 and cannot sign other intermediate certificate authorities,
+	unless max_path_len is set for the intermediate in state or for the authority in [authority.<name>] table in spec.
+	A request is only signed if it satisfies the trust policy of certificate authority,
+	and every field not satisfying a policy rule is reported otherwise with its expected and actual values.
+	Using -check flag, requests are only evaluated against the trust policy and nothing is signed,
+	so the password of certificate authority is not needed.
+	The policy of a certificate authority is read from [authority.<name>.policy] table in spec if it is set,
+	and from [root_policy] or [intermediate_policy] table otherwise.
+
+	A certificate signing request generated outside workspace (e.g. by openssl on another host) can be signed using -csr flag.
+	The request is read from a file or from standard input (-csr=-), and is stored in workspace under -name and -type.
+	Its private key never leaves its host, so the issued certificate has no key in workspace.
+	Using -print flag, the issued certificate (cert) or full chain (fullchain) is written to standard output,
+	while prompts and messages are written to standard error.
+
+	Name constraints of an intermediate certificate authority are read from [authority.<name>.name_constraints] table in spec,
+	or from -permit and -exclude flags as comma-separated type:value pairs (types are dns, ip, email, and uri).
+	An intermediate certificate authority cannot sign certificates for names outside of its constraints.
+
+	CRL distribution points, OCSP servers, and issuing certificate URLs of a certificate authority are read from
+	[authority.<name>] table in spec, and are added to every certificate it signs.
+
+	The path length of an intermediate certificate authority (max_path_len) is read from state or [authority.<name>] table in spec.
+	It is limited by the certificate authorities above it, and intermediates with path length 0 cannot sign other intermediates.
+
+	Using -non-interactive flag, nothing is asked and -ca, -name, and the password are required.
+	The password is read from -password-file, -password-env, or a line of standard input (after the request for -csr=-).
+	These flags can also be set by GOCERT_NON_INTERACTIVE, GOCERT_PASSWORD_FILE, and GOCERT_PASSWORD_ENV environment variables.
 
 	Flags:
-		-ca      the name of certificate authorithy
-		-name    the name of certificate signing request
+		-ca                 the name of certificate authorithy
+		-name               the name of certificate signing request
+		-csr                the path to an external certificate signing request (PEM), or - for standard input
+		-type               the type of external certificate signing request: intermediate, server, or client
+		-print              writes the issued certificate to standard output: cert or fullchain
+		-check              evaluates requests against trust policy of certificate authority without signing them
+		-permit             permitted name constraints for intermediates (e.g. dns:.sre.example.com,ip:10.0.0.0/8)
+		-exclude            excluded name constraints for intermediates (e.g. dns:internal.example.com,email:example.org)
+		-non-interactive    never prompt for values
+		-password-file      read the password of certificate authority from a file
+		-password-env       read the password of certificate authority from an environment variable
 	`
 )
 
 // SignCommand represents the sign command for signing a csr
 type SignCommand struct {
-	ui  cli.Ui
-	pki pki.Manager
+	ui    cli.Ui
+	pki   pki.Manager
+	stdin io.Reader
 }
 
 // NewSignCommand creates a new command
 func NewSignCommand() *SignCommand {
 	return &SignCommand{
-		ui:  newColoredUI(),
-		pki: pki.NewX509Manager(),
+		ui:    newColoredUI(),
+		pki:   pki.NewX509Manager(),
+		stdin: os.Stdin,
+	}
+}
+
+// readPEMBlock reads a single PEM block from a reader without consuming anything after it
+// The rest of input stays available for the prompts following the request.
+func readPEMBlock(r io.Reader) ([]byte, error) {
+	var data, line []byte
+	b := make([]byte, 1)
+
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			line = append(line, b[0])
+			if b[0] == '\n' {
+				data = append(data, line...)
+				if bytes.HasPrefix(bytes.TrimSpace(line), []byte("-----END")) {
+					return data, nil
+				}
+				line = line[:0]
+			}
+		}
+
+		if err == io.EOF {
+			return append(data, line...), nil
+		} else if err != nil {
+			return nil, err
+		}
 	}
 }
 
@@ -56,22 +134,19 @@ func (c *SignCommand) resolveCA(state *pki.State, spec *pki.Spec, nameCA string)
 
 	// Type field is ensured to be valid
 	configCA, _ = state.ConfigFor(cCA.Type)
-	policyCA, _ = spec.PolicyFor(cCA.Type)
+	policyCA, _ = spec.PolicyForCA(cCA)
 
 	return
 }
 
-func (c *SignCommand) resolveCSR(state *pki.State, spec *pki.Spec, nameCSR string) (configCSR pki.Config, cCSR pki.Cert, status int) {
-	cCSR = resolveByName(nameCSR)
-
+func (c *SignCommand) resolveCSR(state *pki.State, spec *pki.Spec, cCA, cCSR pki.Cert) (configCSR pki.Config, usageCSR pki.Usage, status int) {
 	if cCSR.Type == 0 || cCSR.Type == pki.CertTypeRoot {
 		c.ui.Error("Certificate name is not valid.")
 		status = ErrorInvalidCSR
 		return
 	}
 
-	// Type field is ensured to be valid
-	configCSR, _ = state.ConfigFor(cCSR.Type)
+	configCSR, usageCSR = issuanceFor(state, spec, cCA, cCSR)
 
 	return
 }
@@ -88,39 +163,92 @@ func (c *SignCommand) Help() string {
 
 // Run executes the command
 func (c *SignCommand) Run(args []string) (exit int) {
-	var fCA, fName string
+	var fCA, fName, fCSR, fType, fPrint, fPermit, fExclude string
+	var fCheck bool
 
 	flags := flag.NewFlagSet("sign", flag.ContinueOnError)
 	flags.Usage = func() {}
 	flags.StringVar(&fCA, "ca", "", "")
 	flags.StringVar(&fName, "name", "", "")
+	flags.StringVar(&fCSR, "csr", "", "")
+	flags.StringVar(&fType, "type", "", "")
+	flags.StringVar(&fPrint, "print", "", "")
+	flags.StringVar(&fPermit, "permit", "", "")
+	flags.StringVar(&fExclude, "exclude", "", "")
+	flags.BoolVar(&fCheck, "check", false, "")
+	in := newInput(flags, c.stdin)
 	err := flags.Parse(args)
 	if err != nil {
 		return ErrorInvalidFlag
 	}
 
+	var permitted, excluded []string
+	if fPermit != "" {
+		permitted = strings.Split(fPermit, ",")
+	}
+	if fExclude != "" {
+		excluded = strings.Split(fExclude, ",")
+	}
+
+	var nc pki.NameConstraints
+	if permitted != nil || excluded != nil {
+		nc, err = pki.ParseNameConstraints(permitted, excluded)
+		if err != nil {
+			c.ui.Error("Name constraints are not valid. Error: " + err.Error())
+			return ErrorInvalidFlag
+		}
+	}
+
+	certType := pki.ParseCertType(fType)
+	if fCSR != "" && (certType == 0 || certType == pki.CertTypeRoot) {
+		c.ui.Error("Certificate type is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	if fPrint != "" && fPrint != signPrintCert && fPrint != signPrintFullchain {
+		c.ui.Error("Print option is not valid.")
+		return ErrorInvalidFlag
+	}
+
+	if fPrint != "" && fCheck {
+		c.ui.Error("Print option cannot be used with -check flag.")
+		return ErrorInvalidFlag
+	}
+
+	// Standard output is reserved for the issued certificate
+	ui := c.ui
+	if fPrint != "" {
+		ui = newStderrUI(c.ui)
+	}
+
 	if fCA == "" {
-		c.ui.Output(signEnterNameCA)
-		fCA, err = c.ui.Ask(fmt.Sprintf(promptTemplate, "CA Name", "string"))
+		ui.Output(signEnterNameCA)
+		fCA, err = in.ask(ui, fmt.Sprintf(promptTemplate, "CA Name", "string"), "ca")
 		if err != nil {
 			return ErrorInvalidCA
 		}
 	}
 
 	if fName == "" {
-		c.ui.Output(signEnterNameCSR)
-		fName, err = c.ui.Ask(fmt.Sprintf(promptTemplate, "CSR Name", "string list"))
+		ui.Output(signEnterNameCSR)
+		fName, err = in.ask(ui, fmt.Sprintf(promptTemplate, "CSR Name", "string list"), "name")
 		if err != nil {
 			return ErrorInvalidCSR
 		}
 	}
 
 	if fCA == fName {
-		c.ui.Error("CA name and request name cannot be the same.")
+		ui.Error("CA name and request name cannot be the same.")
 		return ErrorInvalidName
 	}
 
-	state, spec, status := loadWorkspace(c.ui)
+	csrNames := strings.Split(fName, ",")
+	if (fCSR != "" || fPrint != "") && len(csrNames) > 1 {
+		ui.Error("Only one certificate signing request can be signed with -csr or -print flags.")
+		return ErrorInvalidCSR
+	}
+
+	state, spec, status := loadWorkspace(ui)
 	if status != 0 {
 		return status
 	}
@@ -130,39 +258,148 @@ func (c *SignCommand) Run(args []string) (exit int) {
 		return status
 	}
 
-	c.ui.Output(signEnterConfigCA)
-	err = askForConfig(&configCA, cCA, nil, c.ui)
-	if err != nil {
-		return ErrorEnterConfig
+	var external pki.Cert
+	if fCSR != "" {
+		external = pki.Cert{Name: fName, Type: certType}
+		if status := c.importCSR(ui, external, fCSR); status != 0 {
+			return status
+		}
+
+		// An external request is not kept in workspace if it is not signed
+		defer func() {
+			if exit != 0 || fCheck {
+				_ = os.Remove(external.CSRPath())
+			}
+		}()
 	}
 
-	trustFunc := pki.PolicyTrustFunc(policyCA)
-	csrNames := strings.Split(fName, ",")
+	// Checking a request does not use the key of certificate authority
+	if !fCheck {
+		ui.Output(signEnterConfigCA)
+		err = in.askForConfig(&configCA, cCA, nil, ui)
+		if err != nil {
+			return ErrorEnterConfig
+		}
+	}
 
-	c.ui.Output("")
+	ui.Output("")
 
+	var cCSR pki.Cert
 	for _, csrName := range csrNames {
-		configCSR, cCSR, status := c.resolveCSR(state, spec, csrName)
+		cCSR = external
+		if fCSR == "" {
+			cCSR = resolveByName(csrName)
+		}
+
+		configCSR, usageCSR, status := c.resolveCSR(state, spec, cCA, cCSR)
 		if status != 0 {
 			return status
 		}
 
+		// Name constraints from flags take precedence over spec
+		if !nc.IsEmpty() {
+			if cCSR.Type != pki.CertTypeInterm {
+				ui.Error("Name constraints can only be set for intermediate certificate authorities.")
+				return ErrorInvalidFlag
+			}
+			usageCSR.NameConstraints = nc
+		}
+
 		// Root CA only signs intermediate CAs, and intermediate CA cannot sign root CA
 		if cCA.Type == pki.CertTypeRoot && cCSR.Type != pki.CertTypeInterm {
-			c.ui.Error("Root CA can only sign an intermediate ca.")
+			ui.Error("Root CA can only sign an intermediate ca.")
 			return ErrorInvalidCSR
 		}
 
-		err = c.pki.SignCSR(configCA, cCA, configCSR, cCSR, trustFunc)
-		if err != nil {
-			c.ui.Error(fmt.Sprintf(signFailure, cCSR.Name, err.Error()))
+		trustFunc := pki.PolicyTrustFunc(policyCA, cCSR.Type)
+
+		if fCheck {
+			violations, err := c.pki.CheckCSR(cCA, configCSR, cCSR, trustFunc)
+			if err != nil {
+				ui.Error(fmt.Sprintf(checkFailure, cCSR.Name, err.Error()))
+				exit = ErrorCheck
+			} else if len(violations) > 0 {
+				ui.Error(fmt.Sprintf(checkViolated, cCSR.Name, cCA.Name))
+				printViolations(ui, violations)
+				exit = ErrorCheck
+			} else {
+				ui.Info(fmt.Sprintf(checkSuccess, cCSR.Name, cCA.Name))
+			}
+			continue
+		}
+
+		var policyErr *pki.PolicyError
+		err = c.pki.SignCSR(configCA, cCA, configCSR, cCSR, usageCSR, trustFunc)
+		if errors.As(err, &policyErr) {
+			ui.Error(fmt.Sprintf(signViolated, cCSR.Name, cCA.Name))
+			printViolations(ui, policyErr.Violations)
+			exit = ErrorSign
+		} else if err != nil {
+			ui.Error(fmt.Sprintf(signFailure, cCSR.Name, err.Error()))
 			exit = ErrorSign
 		} else {
-			c.ui.Info(fmt.Sprintf(signSuccess, cCSR.Name))
+			ui.Info(fmt.Sprintf(signSuccess, cCSR.Name))
 		}
 	}
 
-	c.ui.Output("")
+	ui.Output("")
+
+	if exit == 0 && fPrint != "" {
+		exit = c.print(cCSR, fPrint)
+	}
 
 	return exit
 }
+
+// printViolations prints every field of a request not satisfying trust policy of a certificate authority
+func printViolations(ui cli.Ui, violations []pki.Violation) {
+	for _, v := range violations {
+		ui.Error(fmt.Sprintf(signViolation, v.String()))
+	}
+}
+
+// importCSR reads an external certificate signing request from a file or standard input and writes it into workspace
+func (c *SignCommand) importCSR(ui cli.Ui, cCSR pki.Cert, path string) int {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = readPEMBlock(c.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		ui.Error("Failed to read certificate signing request. Error: " + err.Error())
+		return ErrorInvalidCSR
+	}
+
+	err = pki.ImportCSR(cCSR, data)
+	if err != nil {
+		ui.Error("Certificate signing request is not valid. Error: " + err.Error())
+		return ErrorInvalidCSR
+	}
+
+	return 0
+}
+
+// print writes an issued certificate or its full chain to standard output
+func (c *SignCommand) print(cCSR pki.Cert, what string) int {
+	path := cCSR.CertPath()
+	if what == signPrintFullchain {
+		path = cCSR.FullchainPath()
+		if cCSR.Type == pki.CertTypeInterm {
+			path = cCSR.ChainPath()
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.ui.Error("Failed to read certificate. Error: " + err.Error())
+		return ErrorSign
+	}
+
+	c.ui.Output(strings.TrimSuffix(string(data), "\n"))
+
+	return 0
+}
@@ -1,8 +1,15 @@
 package cli
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -23,6 +30,51 @@ func writeSignMocks(t *testing.T, mocks []pki.Cert) {
 	}
 }
 
+// writeExternalCSR writes a certificate signing request generated outside workspace
+func writeExternalCSR(t *testing.T, commonName string) (string, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	data, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: []string{commonName + ".example.com"},
+	}, key)
+	assert.NoError(t, err)
+
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: data})
+	path := filepath.Join(t.TempDir(), commonName+".csr")
+	assert.NoError(t, os.WriteFile(path, pemData, 0644))
+
+	return path, pemData
+}
+
+func TestReadPEMBlock(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedData string
+		expectedRest string
+	}{
+		{"Empty", "", "", ""},
+		{"NoEnd", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v", ""},
+		{"Block", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v\n-----END CERTIFICATE REQUEST-----\n", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v\n-----END CERTIFICATE REQUEST-----\n", ""},
+		{"BlockAndPassword", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v\n-----END CERTIFICATE REQUEST-----\npassword\n", "-----BEGIN CERTIFICATE REQUEST-----\nZm9v\n-----END CERTIFICATE REQUEST-----\n", "password\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := strings.NewReader(test.input)
+			data, err := readPEMBlock(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedData, string(data))
+
+			rest := make([]byte, r.Len())
+			_, _ = r.Read(rest)
+			assert.Equal(t, test.expectedRest, string(rest))
+		})
+	}
+}
+
 func TestNewSignCommand(t *testing.T) {
 	tests := []struct {
 		expectedSynopsis string
@@ -40,6 +92,7 @@ func TestNewSignCommand(t *testing.T) {
 
 		assert.Equal(t, newColoredUI(), cmd.ui)
 		assert.Equal(t, pki.NewX509Manager(), cmd.pki)
+		assert.Equal(t, os.Stdin, cmd.stdin)
 
 		assert.Equal(t, test.expectedSynopsis, cmd.Synopsis())
 		assert.NotEmpty(t, cmd.Help())
@@ -68,6 +121,55 @@ func TestSignCommand(t *testing.T) {
 			password
 			`,
 		},
+		{
+			"RootSignsConstrainedIntermediate",
+			pki.NewState(),
+			&pki.Spec{
+				Authorities: map[string]pki.Authority{
+					"ops": pki.Authority{
+						NameConstraints: pki.NameConstraints{PermittedDNSDomains: []string{".ops.example.com"}},
+					},
+				},
+			},
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+			},
+			[]string{"-ca=root", "-name=ops"},
+			`password
+			password
+			`,
+		},
+		{
+			"RootSignsIntermediateWithPathLen",
+			pki.NewState(),
+			&pki.Spec{
+				Authorities: map[string]pki.Authority{
+					"ops": pki.Authority{MaxPathLen: new(int)},
+				},
+			},
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+			},
+			[]string{"-ca=root", "-name=ops"},
+			`password
+			password
+			`,
+		},
+		{
+			"RootSignsIntermediateWithConstraintFlags",
+			pki.NewState(),
+			pki.NewSpec(),
+			[]pki.Cert{
+				pki.Cert{Name: rootName, Type: pki.CertTypeRoot},
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+			},
+			[]string{"-ca=root", "-name=ops", "-permit=dns:.ops.example.com,ip:10.0.0.0/8", "-exclude=email:example.org"},
+			`password
+			password
+			`,
+		},
 		{
 			"IntermediateSignsServer",
 			pki.NewState(),
@@ -97,6 +199,34 @@ func TestSignCommand(t *testing.T) {
 			password
 			`,
 		},
+		{
+			"IntermediateSignsClientInSPIFFEMode",
+			pki.NewState(),
+			&pki.Spec{
+				Authorities: map[string]pki.Authority{
+					"ops": pki.Authority{SPIFFETrustDomain: "example.org"},
+				},
+			},
+			[]pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "client", Type: pki.CertTypeClient},
+			},
+			[]string{"-ca=ops", "-name=client"},
+			`password
+			password
+			`,
+		},
+		{
+			"IntermediateSignsServerNonInteractive",
+			pki.NewState(),
+			pki.NewSpec(),
+			[]pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "server", Type: pki.CertTypeServer},
+			},
+			[]string{"-non-interactive", "-ca=ops", "-name=server"},
+			"password\n",
+		},
 		{
 			"IntermediateSignsServerClient",
 			pki.NewState(),
@@ -123,8 +253,9 @@ func TestSignCommand(t *testing.T) {
 			r := strings.NewReader(test.input)
 			mockUI := newMockUI(r)
 			cmd := &SignCommand{
-				ui:  mockUI,
-				pki: &mockManager{},
+				ui:    mockUI,
+				pki:   &mockManager{},
+				stdin: strings.NewReader(test.input),
 			}
 
 			exit := cmd.Run(test.args)
@@ -157,6 +288,31 @@ func TestSignCommandError(t *testing.T) {
 			nil,
 			ErrorInvalidFlag,
 		},
+		{
+			"InvalidNameConstraints",
+			nil,
+			nil,
+			nil,
+			[]string{"-permit=host:example.com"},
+			``,
+			nil,
+			ErrorInvalidFlag,
+		},
+		{
+			"NameConstraintsForServer",
+			pki.NewState(),
+			pki.NewSpec(),
+			[]pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "server", Type: pki.CertTypeServer},
+			},
+			[]string{"-ca=ops", "-name=server", "-permit=dns:example.com"},
+			`password
+			password
+			`,
+			nil,
+			ErrorInvalidFlag,
+		},
 		{
 			"NoCAName",
 			nil,
@@ -178,6 +334,29 @@ func TestSignCommandError(t *testing.T) {
 			nil,
 			ErrorInvalidCSR,
 		},
+		{
+			"NonInteractiveNoCAName",
+			nil,
+			nil,
+			nil,
+			[]string{"-non-interactive", "-name=server"},
+			``,
+			nil,
+			ErrorInvalidCA,
+		},
+		{
+			"NonInteractiveNoPassword",
+			pki.NewState(),
+			pki.NewSpec(),
+			[]pki.Cert{
+				pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+				pki.Cert{Name: "server", Type: pki.CertTypeServer},
+			},
+			[]string{"-non-interactive", "-ca=ops", "-name=server"},
+			``,
+			nil,
+			ErrorEnterConfig,
+		},
 		{
 			"SameCACertName",
 			nil,
@@ -363,6 +542,7 @@ func TestSignCommandError(t *testing.T) {
 				pki: &mockManager{
 					SignCSRError: test.SignCSRError,
 				},
+				stdin: strings.NewReader(test.input),
 			}
 
 			exit := cmd.Run(test.args)
@@ -373,3 +553,373 @@ func TestSignCommandError(t *testing.T) {
 		})
 	}
 }
+
+func TestSignCommandCheck(t *testing.T) {
+	mocks := []pki.Cert{
+		pki.Cert{Name: "ops", Type: pki.CertTypeInterm},
+		pki.Cert{Name: "server", Type: pki.CertTypeServer},
+	}
+
+	tests := []struct {
+		title          string
+		args           []string
+		manager        *mockManager
+		expectedExit   int
+		expectedOutput string
+		expectedError  string
+	}{
+		{
+			"CheckWithPrint",
+			[]string{"-ca=ops", "-name=server", "-check", "-print=cert"},
+			&mockManager{},
+			ErrorInvalidFlag,
+			"",
+			"Print option cannot be used with -check flag.",
+		},
+		{
+			"Satisfied",
+			[]string{"-ca=ops", "-name=server", "-check"},
+			&mockManager{},
+			0,
+			"server satisfies trust policy of ops",
+			"",
+		},
+		{
+			"NotSatisfied",
+			[]string{"-ca=ops", "-name=server", "-check"},
+			&mockManager{
+				CheckCSRViolations: []pki.Violation{
+					{Field: "CommonName", Expected: "*.ops.example.com", Actual: "server", Rule: "common_names"},
+				},
+			},
+			ErrorCheck,
+			"",
+			"server does not satisfy trust policy of ops\n     - CommonName: expected *.ops.example.com, got server (common_names)",
+		},
+		{
+			"CheckCSRFails",
+			[]string{"-ca=ops", "-name=server", "-check"},
+			&mockManager{CheckCSRError: errors.New("error")},
+			ErrorCheck,
+			"",
+			"Failed to check server. Error: error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, mocks)
+
+			// No password is asked for checking requests
+			mockUI := newMockUI(strings.NewReader(""))
+			cmd := &SignCommand{
+				ui:    mockUI,
+				pki:   test.manager,
+				stdin: strings.NewReader(""),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+			assert.Contains(t, mockUI.OutputWriter.String(), test.expectedOutput)
+			assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+			assert.False(t, test.manager.SignCSRCalled)
+		})
+	}
+}
+
+func TestSignCommandExternal(t *testing.T) {
+	csrPath, csrPEM := writeExternalCSR(t, "webapp")
+
+	tests := []struct {
+		title        string
+		mocks        []pki.Cert
+		args         []string
+		stdin        string
+		input        string
+		SignCSRError error
+		expectedExit int
+		expectedCSR  bool
+	}{
+		{
+			"InvalidType",
+			nil,
+			[]string{"-ca=ops", "-name=webapp", "-csr=" + csrPath, "-type=root"},
+			``,
+			``,
+			nil,
+			ErrorInvalidFlag,
+			false,
+		},
+		{
+			"InvalidPrint",
+			nil,
+			[]string{"-ca=ops", "-name=webapp", "-print=key"},
+			``,
+			``,
+			nil,
+			ErrorInvalidFlag,
+			false,
+		},
+		{
+			"MultipleNames",
+			nil,
+			[]string{"-ca=ops", "-name=webapp,service", "-csr=" + csrPath, "-type=server"},
+			``,
+			``,
+			nil,
+			ErrorInvalidCSR,
+			false,
+		},
+		{
+			"NoFile",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=missing.csr", "-type=server"},
+			``,
+			``,
+			nil,
+			ErrorInvalidCSR,
+			false,
+		},
+		{
+			"InvalidCSR",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=-", "-type=server"},
+			`invalid`,
+			``,
+			nil,
+			ErrorInvalidCSR,
+			false,
+		},
+		{
+			"EnterNoPassword",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=" + csrPath, "-type=server"},
+			``,
+			``,
+			nil,
+			ErrorEnterConfig,
+			false,
+		},
+		{
+			"SignCSRFails",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=" + csrPath, "-type=server"},
+			``,
+			`password
+			password
+			`,
+			errors.New("error"),
+			ErrorSign,
+			false,
+		},
+		{
+			"FromFile",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=" + csrPath, "-type=server"},
+			``,
+			`password
+			password
+			`,
+			nil,
+			0,
+			true,
+		},
+		{
+			"FromStdin",
+			[]pki.Cert{pki.Cert{Name: "ops", Type: pki.CertTypeInterm}},
+			[]string{"-ca=ops", "-name=webapp", "-csr=-", "-type=client"},
+			string(csrPEM),
+			`password
+			password
+			`,
+			nil,
+			0,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := pki.NewWorkspace(pki.NewState(), pki.NewSpec())
+			assert.NoError(t, err)
+			defer pki.CleanupWorkspace() // nolint: errcheck
+
+			writeSignMocks(t, test.mocks)
+
+			mockUI := newMockUI(strings.NewReader(test.input))
+			cmd := &SignCommand{
+				ui: mockUI,
+				pki: &mockManager{
+					SignCSRError: test.SignCSRError,
+				},
+				stdin: strings.NewReader(test.stdin),
+			}
+
+			exit := cmd.Run(test.args)
+			assert.Equal(t, test.expectedExit, exit)
+
+			// External requests are only kept in workspace when signed
+			csrFiles, _ := filepath.Glob("./*/webapp.csr")
+			assert.Equal(t, test.expectedCSR, len(csrFiles) == 1)
+		})
+	}
+}
+
+func TestSignCommandPrint(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	state := pki.NewState()
+	for _, config := range []*pki.Config{&state.Root, &state.Interm} {
+		config.Algorithm = pki.AlgorithmECDSA
+		config.Length = 256
+		config.Password = "password"
+	}
+
+	err := pki.NewWorkspace(state, pki.NewSpec())
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	usage, _ := pki.NewSpec().UsageFor(pki.CertTypeInterm)
+	manager := pki.NewX509Manager()
+	cRoot := pki.Cert{Name: rootName, Type: pki.CertTypeRoot}
+	cOps := pki.Cert{Name: "ops", Type: pki.CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []pki.Violation { return nil }
+	assert.NoError(t, manager.GenCert(state.Root, pki.Claim{CommonName: "Root"}, cRoot))
+	assert.NoError(t, manager.GenCSR(state.Interm, pki.Claim{CommonName: "Ops"}, cOps))
+	assert.NoError(t, manager.SignCSR(state.Root, cRoot, state.Interm, cOps, usage, trust))
+
+	tests := []struct {
+		title          string
+		name           string
+		print          string
+		expectedBlocks int
+	}{
+		{"Cert", "webapp", "cert", 1},
+		{"Fullchain", "service", "fullchain", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			_, csrPEM := writeExternalCSR(t, test.name)
+
+			mockUI := newMockUI(strings.NewReader("password\npassword\n"))
+			cmd := &SignCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(string(csrPEM)),
+			}
+
+			exit := cmd.Run([]string{"-ca=ops", "-name=" + test.name, "-csr=-", "-type=server", "-print=" + test.print})
+			assert.Zero(t, exit)
+
+			// Messages are written to error stream
+			assert.NotContains(t, mockUI.OutputWriter.String(), "Signed")
+			assert.Contains(t, mockUI.ErrorWriter.String(), "Signed "+test.name)
+
+			// Prompts of mock ui are written to output stream
+			output := mockUI.OutputWriter.String()
+			data := []byte(output[strings.Index(output, "-----BEGIN"):])
+			blocks := 0
+			for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+				assert.Equal(t, "CERTIFICATE", block.Type)
+				blocks++
+			}
+			assert.Equal(t, test.expectedBlocks, blocks)
+
+			// Issued certificate has no key in workspace
+			c := pki.Cert{Name: test.name, Type: pki.CertTypeServer}
+			assert.FileExists(t, c.CertPath())
+			assert.NoFileExists(t, c.KeyPath())
+		})
+	}
+}
+
+func TestSignCommandPolicy(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	state := pki.NewState()
+	for _, config := range []*pki.Config{&state.Root, &state.Interm} {
+		config.Algorithm = pki.AlgorithmECDSA
+		config.Length = 256
+		config.Password = "password"
+	}
+
+	// The ops intermediate only signs servers in its own subdomain
+	spec := pki.NewSpec()
+	spec.Authorities = map[string]pki.Authority{
+		"ops": pki.Authority{
+			Policy: &pki.Policy{
+				Types:    []string{"server"},
+				DNSNames: []string{"*.ops.example.com"},
+			},
+		},
+	}
+
+	err := pki.NewWorkspace(state, spec)
+	assert.NoError(t, err)
+	defer pki.CleanupWorkspace() // nolint: errcheck
+
+	usage, _ := spec.UsageFor(pki.CertTypeInterm)
+	manager := pki.NewX509Manager()
+	cRoot := pki.Cert{Name: rootName, Type: pki.CertTypeRoot}
+	cOps := pki.Cert{Name: "ops", Type: pki.CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []pki.Violation { return nil }
+	assert.NoError(t, manager.GenCert(state.Root, pki.Claim{CommonName: "Root"}, cRoot))
+	assert.NoError(t, manager.GenCSR(state.Interm, pki.Claim{CommonName: "Ops"}, cOps))
+	assert.NoError(t, manager.SignCSR(state.Root, cRoot, state.Interm, cOps, usage, trust))
+
+	tests := []struct {
+		title         string
+		name          string
+		commonName    string
+		certType      string
+		check         bool
+		expectedExit  int
+		expectedError string
+	}{
+		{"CheckAllowed", "check", "check.ops", "server", true, 0, ""},
+		{"CheckNotAllowed", "check", "check", "client", true, ErrorCheck, "Type: expected server, got client (types)\n     - DNSNames: expected *.ops.example.com, got check.example.com (dns_names)"},
+		{"Allowed", "api", "api.ops", "server", false, 0, ""},
+		{"NameNotAllowed", "webapp", "webapp", "server", false, ErrorSign, "DNSNames: expected *.ops.example.com, got webapp.example.com (dns_names)"},
+		{"TypeNotAllowed", "service", "service.ops", "client", false, ErrorSign, "Type: expected server, got client (types)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			path, _ := writeExternalCSR(t, test.commonName)
+
+			args := []string{"-ca=ops", "-name=" + test.name, "-csr=" + path, "-type=" + test.certType}
+			input := "password\npassword\n"
+			if test.check {
+				args = append(args, "-check")
+				input = ""
+			}
+
+			mockUI := newMockUI(strings.NewReader(input))
+			cmd := &SignCommand{
+				ui:    mockUI,
+				pki:   manager,
+				stdin: strings.NewReader(""),
+			}
+
+			exit := cmd.Run(args)
+			assert.Equal(t, test.expectedExit, exit)
+			assert.Contains(t, mockUI.ErrorWriter.String(), test.expectedError)
+
+			// A checked request is never signed nor kept in workspace
+			c := pki.Cert{Name: test.name, Type: pki.CertTypeServer}
+			if test.check {
+				assert.NoFileExists(t, c.CertPath())
+				assert.NoFileExists(t, c.CSRPath())
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ const (
 	verifyHelp     = `
 	You can use this command to verify a certificate using its certificate authority
 	This command tries to verify the specified certificate by checking the certificate trust chain.
+	Path length constraints of certificate authorities in the chain are reported if the chain is deeper than they allow.
 
 	Flags:
 		-ca      the name of certificate authorithy
@@ -0,0 +1,114 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"time"
+)
+
+const (
+	// BundleFormatPEM is the format for concatenated PEM certificates
+	BundleFormatPEM = "pem"
+	// BundleFormatDER is the format for DER-encoded PKCS#7 certificate bundles (p7b)
+	BundleFormatDER = "der"
+)
+
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+type (
+	// pkcs7ContentInfo represents a PKCS#7 ContentInfo
+	pkcs7ContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"optional"`
+	}
+
+	// pkcs7SignedData represents a PKCS#7 SignedData without any signer
+	// https://tools.ietf.org/html/rfc2315#section-9.1
+	pkcs7SignedData struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		ContentInfo      pkcs7ContentInfo
+		Certificates     asn1.RawValue   `asn1:"optional"`
+		SignerInfos      []asn1.RawValue `asn1:"set"`
+	}
+)
+
+// encodePKCS7 encodes a list of certificates as a degenerate certificates-only PKCS#7 SignedData
+func encodePKCS7(certs []*x509.Certificate) ([]byte, error) {
+	certsData := []byte{}
+	for _, cert := range certs {
+		certsData = append(certsData, cert.Raw...)
+	}
+
+	signedData, err := asn1.Marshal(pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certsData},
+		SignerInfos:      []asn1.RawValue{},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+// TrustBundle returns the certificates of all active certificate authorities in current workspace as a trust bundle
+// A certificate authority is active if it is neither revoked nor expired at a point in time.
+// Root certificate authorities come first followed by intermediate certificate authorities.
+func TrustBundle(format string, now time.Time) ([]byte, error) {
+	if format != BundleFormatPEM && format != BundleFormatDER {
+		return nil, errors.New("invalid bundle format: " + format)
+	}
+
+	index, err := LoadIndex(FileIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+	for _, certType := range []int{CertTypeRoot, CertTypeInterm} {
+		cCAs, err := ListCerts(certType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cCA := range cCAs {
+			summary, err := summarizeCert(cCA, index, now)
+			if err != nil {
+				return nil, err
+			}
+
+			if summary.Status != StatusValid {
+				continue
+			}
+
+			certCA, err := readCertificate(cCA.CertPath())
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, certCA)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no active certificate authority found")
+	}
+
+	if format == BundleFormatDER {
+		return encodePKCS7(certs)
+	}
+
+	return encodeCertificates(certs), nil
+}
@@ -0,0 +1,136 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodePKCS7(t *testing.T, data []byte) []*x509.Certificate {
+	contentInfo := pkcs7ContentInfo{}
+	rest, err := asn1.Unmarshal(data, &contentInfo)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.True(t, oidSignedData.Equal(contentInfo.ContentType))
+
+	signedData := pkcs7SignedData{}
+	_, err = asn1.Unmarshal(contentInfo.Content.Bytes, &signedData)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, signedData.Version)
+	assert.True(t, oidData.Equal(signedData.ContentInfo.ContentType))
+	assert.Empty(t, signedData.SignerInfos)
+
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	assert.NoError(t, err)
+
+	return certs
+}
+
+func TestEncodePKCS7(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	mockWorkspaceWithCA(t)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	certRoot, err := readCertificate(Cert{Name: "root", Type: CertTypeRoot}.CertPath())
+	assert.NoError(t, err)
+	certOps, err := readCertificate(Cert{Name: "ops", Type: CertTypeInterm}.CertPath())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		certs []*x509.Certificate
+	}{
+		{"Empty", []*x509.Certificate{}},
+		{"One", []*x509.Certificate{certRoot}},
+		{"Two", []*x509.Certificate{certRoot, certOps}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := encodePKCS7(test.certs)
+			assert.NoError(t, err)
+
+			certs := decodePKCS7(t, data)
+			assert.Len(t, certs, len(test.certs))
+			for i := range certs {
+				assert.Equal(t, test.certs[i].Raw, certs[i].Raw)
+			}
+		})
+	}
+}
+
+func TestTrustBundle(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	now := time.Now()
+
+	t.Run("NoCA", func(t *testing.T) {
+		_, err := TrustBundle(BundleFormatPEM, now)
+		assert.EqualError(t, err, "no active certificate authority found")
+	})
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cSRE := Cert{Name: "sre", Type: CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "Ops CA"}, cOps))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cOps, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cSRE))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cSRE, Usage{}, trust))
+	assert.NoError(t, manager.RevokeCert(cRoot, cOps, "keyCompromise"))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	certSRE, err := readCertificate(cSRE.CertPath())
+	assert.NoError(t, err)
+
+	t.Run("PEM", func(t *testing.T) {
+		data, err := TrustBundle(BundleFormatPEM, now)
+		assert.NoError(t, err)
+		assert.Equal(t, encodeCertificates([]*x509.Certificate{certRoot, certSRE}), data)
+	})
+
+	t.Run("DER", func(t *testing.T) {
+		data, err := TrustBundle(BundleFormatDER, now)
+		assert.NoError(t, err)
+
+		certs := decodePKCS7(t, data)
+		assert.Len(t, certs, 2)
+		assert.Equal(t, certRoot.Raw, certs[0].Raw)
+		assert.Equal(t, certSRE.Raw, certs[1].Raw)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		// Intermediate CA is expired while root CA is still valid
+		data, err := TrustBundle(BundleFormatPEM, now.AddDate(15, 0, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, encodeCertificates([]*x509.Certificate{certRoot}), data)
+
+		_, err = TrustBundle(BundleFormatPEM, now.AddDate(25, 0, 0))
+		assert.EqualError(t, err, "no active certificate authority found")
+	})
+
+	t.Run("InvalidFormat", func(t *testing.T) {
+		_, err := TrustBundle("p12", now)
+		assert.EqualError(t, err, "invalid bundle format: p12")
+	})
+}
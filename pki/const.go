@@ -11,6 +11,15 @@ const (
 	CertTypeClient
 )
 
+const (
+	// AlgorithmRSA represents the RSA public-key algorithm
+	AlgorithmRSA = "rsa"
+	// AlgorithmECDSA represents the ECDSA public-key algorithm
+	AlgorithmECDSA = "ecdsa"
+	// AlgorithmEd25519 represents the Ed25519 public-key algorithm
+	AlgorithmEd25519 = "ed25519"
+)
+
 const (
 	// DirRoot is the name of directory for root certificate authority
 	DirRoot = "root"
@@ -22,9 +31,19 @@ const (
 	DirClient = "client"
 	// DirCSR is the name of directory for certificate signing requests
 	DirCSR = "csr"
+	// DirCRL is the name of directory for certificate revocation lists
+	DirCRL = "crl"
+	// DirArchive is the name of directory for superseded certificates and keys
+	DirArchive = "archive"
 
 	// FileState is the name of state file
 	FileState = "state.yaml"
+	// FileStateLock is the name of lock file for state
+	FileStateLock = "state.yaml.lock"
 	// FileSpec is the name of spec file
 	FileSpec = "spec.toml"
+	// FileIndex is the name of issuance index file
+	FileIndex = "index.yaml"
+	// FileIndexLock is the name of lock file for issuance index
+	FileIndexLock = "index.yaml.lock"
 )
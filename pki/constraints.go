@@ -0,0 +1,262 @@
+package pki
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	constraintDNS   = "dns"
+	constraintIP    = "ip"
+	constraintEmail = "email"
+	constraintURI   = "uri"
+)
+
+// ParseNameConstraints parses permitted and excluded name constraints in type:value notation
+// Types are dns, ip, email, and uri (e.g. dns:.example.com, ip:10.0.0.0/8, email:example.com, uri:spiffe.example.com).
+func ParseNameConstraints(permitted, excluded []string) (NameConstraints, error) {
+	nc := NameConstraints{}
+
+	for _, list := range []struct {
+		values               []string
+		dns, ip, email, uris *[]string
+	}{
+		{permitted, &nc.PermittedDNSDomains, &nc.PermittedIPRanges, &nc.PermittedEmailDomains, &nc.PermittedURIDomains},
+		{excluded, &nc.ExcludedDNSDomains, &nc.ExcludedIPRanges, &nc.ExcludedEmailDomains, &nc.ExcludedURIDomains},
+	} {
+		for _, value := range list.values {
+			i := strings.Index(value, ":")
+			if i < 0 {
+				return NameConstraints{}, errors.New("invalid name constraint: " + value)
+			}
+
+			switch name := value[i+1:]; value[:i] {
+			case constraintDNS:
+				*list.dns = append(*list.dns, name)
+			case constraintIP:
+				*list.ip = append(*list.ip, name)
+			case constraintEmail:
+				*list.email = append(*list.email, name)
+			case constraintURI:
+				*list.uris = append(*list.uris, name)
+			default:
+				return NameConstraints{}, errors.New("invalid name constraint: " + value)
+			}
+		}
+	}
+
+	if err := nc.Validate(); err != nil {
+		return NameConstraints{}, err
+	}
+
+	return nc, nil
+}
+
+// parseIPRanges parses IP ranges in CIDR notation
+func parseIPRanges(ranges []string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, r := range ranges {
+		_, ipNet, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, errors.New("invalid IP range: " + r)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+
+	return ipNets, nil
+}
+
+// nameConstraintsFromCert returns name constraints of an existing certificate
+func nameConstraintsFromCert(cert *x509.Certificate) NameConstraints {
+	ipRanges := func(ipNets []*net.IPNet) []string {
+		var ranges []string
+		for _, ipNet := range ipNets {
+			ranges = append(ranges, ipNet.String())
+		}
+		return ranges
+	}
+
+	return NameConstraints{
+		PermittedDNSDomains:   cert.PermittedDNSDomains,
+		ExcludedDNSDomains:    cert.ExcludedDNSDomains,
+		PermittedIPRanges:     ipRanges(cert.PermittedIPRanges),
+		ExcludedIPRanges:      ipRanges(cert.ExcludedIPRanges),
+		PermittedEmailDomains: cert.PermittedEmailAddresses,
+		ExcludedEmailDomains:  cert.ExcludedEmailAddresses,
+		PermittedURIDomains:   cert.PermittedURIDomains,
+		ExcludedURIDomains:    cert.ExcludedURIDomains,
+	}
+}
+
+// IsEmpty determines whether or not any name is constrained
+func (nc NameConstraints) IsEmpty() bool {
+	return len(nc.PermittedDNSDomains) == 0 && len(nc.ExcludedDNSDomains) == 0 &&
+		len(nc.PermittedIPRanges) == 0 && len(nc.ExcludedIPRanges) == 0 &&
+		len(nc.PermittedEmailDomains) == 0 && len(nc.ExcludedEmailDomains) == 0 &&
+		len(nc.PermittedURIDomains) == 0 && len(nc.ExcludedURIDomains) == 0
+}
+
+// Validate checks if name constraints are well-formed
+func (nc NameConstraints) Validate() error {
+	for _, ranges := range [][]string{nc.PermittedIPRanges, nc.ExcludedIPRanges} {
+		if _, err := parseIPRanges(ranges); err != nil {
+			return err
+		}
+	}
+
+	for _, domains := range [][]string{
+		nc.PermittedDNSDomains, nc.ExcludedDNSDomains,
+		nc.PermittedEmailDomains, nc.ExcludedEmailDomains,
+		nc.PermittedURIDomains, nc.ExcludedURIDomains,
+	} {
+		for _, domain := range domains {
+			if domain == "" || strings.ContainsAny(domain, " /:") {
+				return errors.New("invalid name constraint domain: " + domain)
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply encodes name constraints into a certificate authority template
+// The extension is marked critical, so relying parties that do not understand it reject the certificate.
+func (nc NameConstraints) apply(cert *x509.Certificate) error {
+	if nc.IsEmpty() {
+		return nil
+	}
+
+	if err := nc.Validate(); err != nil {
+		return err
+	}
+
+	// Ranges are ensured to be valid
+	cert.PermittedIPRanges, _ = parseIPRanges(nc.PermittedIPRanges)
+	cert.ExcludedIPRanges, _ = parseIPRanges(nc.ExcludedIPRanges)
+
+	cert.PermittedDNSDomainsCritical = true
+	cert.PermittedDNSDomains = nc.PermittedDNSDomains
+	cert.ExcludedDNSDomains = nc.ExcludedDNSDomains
+	cert.PermittedEmailAddresses = nc.PermittedEmailDomains
+	cert.ExcludedEmailAddresses = nc.ExcludedEmailDomains
+	cert.PermittedURIDomains = nc.PermittedURIDomains
+	cert.ExcludedURIDomains = nc.ExcludedURIDomains
+
+	return nil
+}
+
+// matchDomain determines whether or not a domain name is within a domain constraint
+func matchDomain(name, constraint string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	constraint = strings.ToLower(constraint)
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(name, constraint)
+	}
+
+	return name == constraint || strings.HasSuffix(name, "."+constraint)
+}
+
+// matchEmail determines whether or not an email address is within an email constraint
+func matchEmail(email, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+
+	return matchDomain(email[i+1:], constraint)
+}
+
+// matchURI determines whether or not the host of a URI is within a domain constraint
+func matchURI(uri *url.URL, constraint string) bool {
+	host := uri.Hostname()
+	if host == "" || net.ParseIP(host) != nil {
+		return false
+	}
+
+	return matchDomain(host, constraint)
+}
+
+// matchIP determines whether or not an IP address is within an IP range
+func matchIP(ip net.IP, ipRange string) bool {
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	return err == nil && ipNet.Contains(ip)
+}
+
+// checkNames checks names of one type against permitted and excluded constraints of a certificate authority
+func checkNames(certCA *x509.Certificate, kind string, names []string, permitted, excluded []string, match func(int, string) bool) error {
+	for i, name := range names {
+		for _, constraint := range excluded {
+			if match(i, constraint) {
+				return fmt.Errorf("%s %q is excluded by name constraints of %s", kind, name, certCA.Subject.CommonName)
+			}
+		}
+
+		permittedName := len(permitted) == 0
+		for _, constraint := range permitted {
+			if match(i, constraint) {
+				permittedName = true
+				break
+			}
+		}
+
+		if !permittedName {
+			return fmt.Errorf("%s %q is not permitted by name constraints of %s", kind, name, certCA.Subject.CommonName)
+		}
+	}
+
+	return nil
+}
+
+// checkNameConstraints checks the names of a certificate request against name constraints of every certificate authority in a chain
+func checkNameConstraints(chain []*x509.Certificate, csr *x509.CertificateRequest) error {
+	var ips, uris []string
+	for _, ip := range csr.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	for _, certCA := range chain {
+		nc := nameConstraintsFromCert(certCA)
+
+		err := checkNames(certCA, "DNS name", csr.DNSNames, nc.PermittedDNSDomains, nc.ExcludedDNSDomains, func(i int, c string) bool {
+			return matchDomain(csr.DNSNames[i], c)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = checkNames(certCA, "IP address", ips, nc.PermittedIPRanges, nc.ExcludedIPRanges, func(i int, c string) bool {
+			return matchIP(csr.IPAddresses[i], c)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = checkNames(certCA, "email address", csr.EmailAddresses, nc.PermittedEmailDomains, nc.ExcludedEmailDomains, func(i int, c string) bool {
+			return matchEmail(csr.EmailAddresses[i], c)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = checkNames(certCA, "URI", uris, nc.PermittedURIDomains, nc.ExcludedURIDomains, func(i int, c string) bool {
+			return matchURI(csr.URIs[i], c)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNameConstraints(t *testing.T) {
+	tests := []struct {
+		name          string
+		permitted     []string
+		excluded      []string
+		expectedNC    NameConstraints
+		expectedError string
+	}{
+		{
+			"Empty",
+			nil,
+			nil,
+			NameConstraints{},
+			"",
+		},
+		{
+			"NoType",
+			[]string{"example.com"},
+			nil,
+			NameConstraints{},
+			"invalid name constraint: example.com",
+		},
+		{
+			"InvalidType",
+			[]string{"host:example.com"},
+			nil,
+			NameConstraints{},
+			"invalid name constraint: host:example.com",
+		},
+		{
+			"InvalidIPRange",
+			nil,
+			[]string{"ip:10.0.0.1"},
+			NameConstraints{},
+			"invalid IP range: 10.0.0.1",
+		},
+		{
+			"InvalidDomain",
+			[]string{"dns:"},
+			nil,
+			NameConstraints{},
+			"invalid name constraint domain: ",
+		},
+		{
+			"AllTypes",
+			[]string{"dns:.sre.example.com", "ip:10.0.0.0/8", "email:example.com", "uri:spiffe.example.com"},
+			[]string{"dns:internal.sre.example.com", "ip:10.10.0.0/16", "email:root@example.com", "uri:legacy.example.com"},
+			NameConstraints{
+				PermittedDNSDomains:   []string{".sre.example.com"},
+				ExcludedDNSDomains:    []string{"internal.sre.example.com"},
+				PermittedIPRanges:     []string{"10.0.0.0/8"},
+				ExcludedIPRanges:      []string{"10.10.0.0/16"},
+				PermittedEmailDomains: []string{"example.com"},
+				ExcludedEmailDomains:  []string{"root@example.com"},
+				PermittedURIDomains:   []string{"spiffe.example.com"},
+				ExcludedURIDomains:    []string{"legacy.example.com"},
+			},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nc, err := ParseNameConstraints(test.permitted, test.excluded)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedNC, nc)
+				assert.Equal(t, test.permitted == nil && test.excluded == nil, nc.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestNameConstraintsApply(t *testing.T) {
+	nc := NameConstraints{
+		PermittedDNSDomains: []string{"example.com"},
+		PermittedIPRanges:   []string{"10.0.0.0/8"},
+	}
+
+	cert := &x509.Certificate{}
+	assert.NoError(t, nc.apply(cert))
+	assert.True(t, cert.PermittedDNSDomainsCritical)
+	assert.Equal(t, []string{"example.com"}, cert.PermittedDNSDomains)
+	assert.Equal(t, nc, nameConstraintsFromCert(cert))
+
+	cert = &x509.Certificate{}
+	assert.NoError(t, NameConstraints{}.apply(cert))
+	assert.False(t, cert.PermittedDNSDomainsCritical)
+
+	assert.EqualError(t, NameConstraints{ExcludedIPRanges: []string{"invalid"}}.apply(cert), "invalid IP range: invalid")
+}
+
+func TestMatchNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		match      func() bool
+		expectedOK bool
+	}{
+		{"DomainExact", func() bool { return matchDomain("example.com", "example.com") }, true},
+		{"DomainSubdomain", func() bool { return matchDomain("api.Example.com", "example.com") }, true},
+		{"DomainSuffixOnly", func() bool { return matchDomain("badexample.com", "example.com") }, false},
+		{"DomainLeadingPeriod", func() bool { return matchDomain("example.com", ".example.com") }, false},
+		{"DomainLeadingPeriodSubdomain", func() bool { return matchDomain("api.example.com", ".example.com") }, true},
+		{"EmailDomain", func() bool { return matchEmail("sre@example.com", "example.com") }, true},
+		{"EmailSubdomain", func() bool { return matchEmail("sre@mail.example.com", ".example.com") }, true},
+		{"EmailMailbox", func() bool { return matchEmail("SRE@example.com", "sre@example.com") }, true},
+		{"EmailOtherMailbox", func() bool { return matchEmail("ops@example.com", "sre@example.com") }, false},
+		{"EmailInvalid", func() bool { return matchEmail("example.com", "example.com") }, false},
+		{"URIHost", func() bool { return matchURI(&url.URL{Scheme: "spiffe", Host: "sre.example.com"}, "example.com") }, true},
+		{"URIOtherHost", func() bool { return matchURI(&url.URL{Scheme: "spiffe", Host: "example.org"}, "example.com") }, false},
+		{"URIIPHost", func() bool { return matchURI(&url.URL{Scheme: "https", Host: "10.0.0.1"}, "example.com") }, false},
+		{"IPInRange", func() bool { return matchIP(net.ParseIP("10.1.2.3"), "10.0.0.0/8") }, true},
+		{"IPOutOfRange", func() bool { return matchIP(net.ParseIP("192.168.1.1"), "10.0.0.0/8") }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedOK, test.match())
+		})
+	}
+}
+
+func TestCheckNameConstraints(t *testing.T) {
+	root := &x509.Certificate{}
+	interm := &x509.Certificate{
+		PermittedDNSDomains:     []string{".sre.example.com"},
+		ExcludedDNSDomains:      []string{"internal.sre.example.com"},
+		PermittedEmailAddresses: []string{"example.com"},
+		PermittedURIDomains:     []string{"sre.example.com"},
+	}
+	interm.Subject.CommonName = "SRE CA"
+	interm.PermittedIPRanges, _ = parseIPRanges([]string{"10.0.0.0/8"})
+
+	spiffe, _ := url.Parse("spiffe://sre.example.com/webapp")
+	other, _ := url.Parse("spiffe://example.org/webapp")
+
+	tests := []struct {
+		name          string
+		csr           *x509.CertificateRequest
+		expectedError string
+	}{
+		{"NoNames", &x509.CertificateRequest{}, ""},
+		{"Permitted", &x509.CertificateRequest{
+			DNSNames:       []string{"api.sre.example.com"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+			EmailAddresses: []string{"sre@example.com"},
+			URIs:           []*url.URL{spiffe},
+		}, ""},
+		{"DNSNotPermitted", &x509.CertificateRequest{DNSNames: []string{"api.example.com"}}, `DNS name "api.example.com" is not permitted by name constraints of SRE CA`},
+		{"DNSExcluded", &x509.CertificateRequest{DNSNames: []string{"db.internal.sre.example.com"}}, `DNS name "db.internal.sre.example.com" is excluded by name constraints of SRE CA`},
+		{"IPNotPermitted", &x509.CertificateRequest{IPAddresses: []net.IP{net.ParseIP("192.168.0.1")}}, `IP address "192.168.0.1" is not permitted by name constraints of SRE CA`},
+		{"EmailNotPermitted", &x509.CertificateRequest{EmailAddresses: []string{"sre@example.org"}}, `email address "sre@example.org" is not permitted by name constraints of SRE CA`},
+		{"URINotPermitted", &x509.CertificateRequest{URIs: []*url.URL{other}}, `URI "spiffe://example.org/webapp" is not permitted by name constraints of SRE CA`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkNameConstraints([]*x509.Certificate{interm, root}, test.csr)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSignCSRNameConstraints(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	config := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 30, Password: "password"}
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cSRE := Cert{Name: "sre", Type: CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(config, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "SRE CA"}, cSRE))
+
+	nc := NameConstraints{PermittedDNSDomains: []string{".sre.example.com"}}
+	assert.NoError(t, manager.SignCSR(config, cRoot, config, cSRE, Usage{NameConstraints: nc}, trust))
+
+	cert, err := readCertificate(cSRE.CertPath())
+	assert.NoError(t, err)
+	assert.True(t, cert.PermittedDNSDomainsCritical)
+	assert.Equal(t, nc, usageFromCert(cert).NameConstraints)
+
+	tests := []struct {
+		name          string
+		dnsNames      []string
+		expectedError string
+	}{
+		{"webapp", []string{"webapp.sre.example.com"}, ""},
+		{"payments", []string{"payments.example.com"}, `DNS name "payments.example.com" is not permitted by name constraints of SRE CA`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Cert{Name: test.name, Type: CertTypeServer}
+			assert.NoError(t, manager.GenCSR(config, Claim{CommonName: test.name, DNSName: test.dnsNames}, c))
+
+			err := manager.SignCSR(config, cSRE, config, c, Usage{}, trust)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.NoFileExists(t, c.CertPath())
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, manager.VerifyCert(cSRE, c, test.dnsNames[0]))
+			}
+		})
+	}
+}
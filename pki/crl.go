@@ -0,0 +1,95 @@
+/*
+ * https://tools.ietf.org/html/rfc5280#section-5
+ */
+
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+)
+
+const (
+	pemTypeCRL = "X509 CRL"
+
+	// ReasonUnspecified is the default reason for revoking a certificate
+	ReasonUnspecified = "unspecified"
+)
+
+var (
+	// Revocation reason codes (RFC 5280 5.3.1)
+	reasonCodes = map[string]int{
+		"unspecified":          0,
+		"keyCompromise":        1,
+		"caCompromise":         2,
+		"affiliationChanged":   3,
+		"superseded":           4,
+		"cessationOfOperation": 5,
+		"certificateHold":      6,
+		"privilegeWithdrawn":   9,
+		"aACompromise":         10,
+	}
+)
+
+func reasonCode(reason string) (int, error) {
+	if reason == "" {
+		reason = ReasonUnspecified
+	}
+
+	code, ok := reasonCodes[reason]
+	if !ok {
+		return 0, errors.New("invalid revocation reason " + reason)
+	}
+
+	return code, nil
+}
+
+// revocationEntries returns the entries of a certificate revocation list for revoked certificates
+func revocationEntries(store *StatusStore) ([]x509.RevocationListEntry, error) {
+	entries := make([]x509.RevocationListEntry, 0)
+	for _, cs := range store.Certificates {
+		if cs.Status != StatusRevoked {
+			continue
+		}
+
+		serial, ok := new(big.Int).SetString(cs.Serial, 10)
+		if !ok {
+			return nil, errors.New("invalid serial number " + cs.Serial)
+		}
+
+		code, err := reasonCode(cs.Reason)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: cs.RevokedAt,
+			ReasonCode:     code,
+		})
+	}
+
+	return entries, nil
+}
+
+func readCRL(path string) (*x509.RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	crlPem, _ := pem.Decode(data)
+	if crlPem == nil {
+		return nil, errors.New("decoding certificate revocation list failed")
+	}
+
+	crl, err := x509.ParseRevocationList(crlPem.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return crl, nil
+}
@@ -0,0 +1,110 @@
+package pki
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReasonCode(t *testing.T) {
+	tests := []struct {
+		reason       string
+		expectError  bool
+		expectedCode int
+	}{
+		{"", false, 0},
+		{"unspecified", false, 0},
+		{"keyCompromise", false, 1},
+		{"caCompromise", false, 2},
+		{"superseded", false, 4},
+		{"aACompromise", false, 10},
+		{"removeFromCRL", true, 0},
+		{"invalid", true, 0},
+	}
+
+	for _, test := range tests {
+		code, err := reasonCode(test.reason)
+
+		if test.expectError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedCode, code)
+		}
+	}
+}
+
+func TestRevocationEntries(t *testing.T) {
+	revokedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		store           *StatusStore
+		expectError     bool
+		expectedSerials []*big.Int
+		expectedCodes   []int
+	}{
+		{
+			"Empty",
+			&StatusStore{},
+			false,
+			[]*big.Int{},
+			[]int{},
+		},
+		{
+			"GoodAndRevoked",
+			&StatusStore{
+				Certificates: []CertStatus{
+					{Name: "webapp", Serial: "1001", Status: StatusRevoked, Reason: "keyCompromise", RevokedAt: revokedAt},
+					{Name: "service", Serial: "1002", Status: StatusGood},
+					{Name: "worker", Serial: "10001", Status: StatusRevoked, Reason: "superseded", RevokedAt: revokedAt},
+				},
+			},
+			false,
+			[]*big.Int{big.NewInt(1001), big.NewInt(10001)},
+			[]int{1, 4},
+		},
+		{
+			"InvalidSerial",
+			&StatusStore{
+				Certificates: []CertStatus{
+					{Name: "webapp", Serial: "invalid", Status: StatusRevoked},
+				},
+			},
+			true,
+			nil,
+			nil,
+		},
+		{
+			"InvalidReason",
+			&StatusStore{
+				Certificates: []CertStatus{
+					{Name: "webapp", Serial: "1001", Status: StatusRevoked, Reason: "invalid"},
+				},
+			},
+			true,
+			nil,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := revocationEntries(test.store)
+
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, entries, len(test.expectedSerials))
+				for i, entry := range entries {
+					assert.Equal(t, test.expectedSerials[i], entry.SerialNumber)
+					assert.Equal(t, test.expectedCodes[i], entry.ReasonCode)
+					assert.Equal(t, revokedAt, entry.RevocationTime)
+				}
+			}
+		})
+	}
+}
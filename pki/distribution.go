@@ -0,0 +1,57 @@
+package pki
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/url"
+)
+
+// validateURL checks if a string is an absolute URL that relying parties can fetch
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid URL: " + s)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "ldap":
+		return nil
+	default:
+		return errors.New("unsupported URL scheme: " + s)
+	}
+}
+
+// distributionFromCert returns distribution URLs of an existing certificate
+func distributionFromCert(cert *x509.Certificate) Distribution {
+	return Distribution{
+		CRLDistributionPoints:  cert.CRLDistributionPoints,
+		OCSPServers:            cert.OCSPServer,
+		IssuingCertificateURLs: cert.IssuingCertificateURL,
+	}
+}
+
+// Validate checks if distribution URLs are well-formed
+func (d Distribution) Validate() error {
+	for _, urls := range [][]string{d.CRLDistributionPoints, d.OCSPServers, d.IssuingCertificateURLs} {
+		for _, u := range urls {
+			if err := validateURL(u); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply sets CRL distribution points and authority information access extensions of a certificate template
+func (d Distribution) apply(cert *x509.Certificate) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	cert.CRLDistributionPoints = d.CRLDistributionPoints
+	cert.OCSPServer = d.OCSPServers
+	cert.IssuingCertificateURL = d.IssuingCertificateURLs
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package pki
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url           string
+		expectedError string
+	}{
+		{"http://pki.example.com/crl/ops.crl", ""},
+		{"https://ocsp.example.com", ""},
+		{"ldap://ldap.example.com/cn=ops", ""},
+		{"pki.example.com/crl/ops.crl", "invalid URL: pki.example.com/crl/ops.crl"},
+		{"http://[::1", "invalid URL: http://[::1"},
+		{"ftp://pki.example.com/ops.crl", "unsupported URL scheme: ftp://pki.example.com/ops.crl"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			err := validateURL(test.url)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDistributionApply(t *testing.T) {
+	d := Distribution{
+		CRLDistributionPoints:  []string{"http://pki.example.com/crl/ops.crl"},
+		OCSPServers:            []string{"http://ocsp.example.com"},
+		IssuingCertificateURLs: []string{"http://pki.example.com/ops.cert"},
+	}
+
+	cert := &x509.Certificate{}
+	assert.NoError(t, d.apply(cert))
+	assert.Equal(t, d.CRLDistributionPoints, cert.CRLDistributionPoints)
+	assert.Equal(t, d.OCSPServers, cert.OCSPServer)
+	assert.Equal(t, d.IssuingCertificateURLs, cert.IssuingCertificateURL)
+	assert.Equal(t, d, distributionFromCert(cert))
+
+	err := Distribution{OCSPServers: []string{"ocsp"}}.apply(&x509.Certificate{})
+	assert.EqualError(t, err, "invalid URL: ocsp")
+}
+
+func TestSpecValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          *Spec
+		expectedError string
+	}{
+		{"Empty", &Spec{}, ""},
+		{"Valid", &Spec{Authorities: map[string]Authority{
+			"ops": Authority{
+				Distribution:    Distribution{CRLDistributionPoints: []string{"http://pki.example.com/crl/ops.crl"}},
+				NameConstraints: NameConstraints{PermittedIPRanges: []string{"10.0.0.0/8"}},
+			},
+		}}, ""},
+		{"InvalidURL", &Spec{Authorities: map[string]Authority{
+			"ops": Authority{Distribution: Distribution{IssuingCertificateURLs: []string{"ops.cert"}}},
+		}}, "authority ops: invalid URL: ops.cert"},
+		{"InvalidNameConstraints", &Spec{Authorities: map[string]Authority{
+			"ops": Authority{NameConstraints: NameConstraints{ExcludedIPRanges: []string{"10.0.0.1"}}},
+		}}, "authority ops: invalid IP range: 10.0.0.1"},
+		{"InvalidRootPolicy", &Spec{RootPolicy: Policy{IPRanges: []string{"10.0.0.1"}}}, "root_policy: invalid IP range: 10.0.0.1"},
+		{"InvalidIntermPolicy", &Spec{IntermPolicy: Policy{Algorithms: []string{"dsa"}}}, "intermediate_policy: invalid algorithm: dsa"},
+		{"InvalidAuthorityPolicy", &Spec{Authorities: map[string]Authority{
+			"ops": Authority{Policy: &Policy{Types: []string{"root"}}},
+		}}, "authority ops: policy: invalid certificate type: root"},
+		{"InvalidSPIFFETrustDomain", &Spec{Authorities: map[string]Authority{
+			"ops": Authority{SPIFFETrustDomain: "Example.org"},
+		}}, "authority ops: invalid SPIFFE trust domain: Example.org"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.spec.Validate()
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSignCSRDistribution(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	config := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 30, Password: "password"}
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	d := Distribution{
+		CRLDistributionPoints:  []string{"http://pki.example.com/crl/ops.crl"},
+		OCSPServers:            []string{"http://ocsp.example.com"},
+		IssuingCertificateURLs: []string{"http://pki.example.com/ops.cert"},
+	}
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(config, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "Ops CA"}, cOps))
+	assert.NoError(t, manager.SignCSR(config, cRoot, config, cOps, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(config, cOps, config, cServer, Usage{Distribution: d}, trust))
+
+	cert, err := readCertificate(cServer.CertPath())
+	assert.NoError(t, err)
+	assert.Equal(t, d, usageFromCert(cert).Distribution)
+
+	// Renewed certificates keep distribution URLs of the previous certificate
+	assert.NoError(t, manager.RenewCert(config, cOps, config, cServer, false, trust))
+	cert, err = readCertificate(cServer.CertPath())
+	assert.NoError(t, err)
+	assert.Equal(t, d, distributionFromCert(cert))
+}
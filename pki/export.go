@@ -0,0 +1,215 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	// ExportFormatP12 is the format for PKCS#12 (PFX) bundles
+	ExportFormatP12 = "p12"
+	// ExportFormatJKS is the format for Java KeyStores
+	ExportFormatJKS = "jks"
+	// ExportFormatSecret is the format for Kubernetes TLS secret manifests
+	ExportFormatSecret = "secret"
+
+	jksCertType = "X509"
+)
+
+// issuingChain returns the certificates of certificate authorities that have issued a certificate
+// The chain starts from the issuer and ends with the root certificate authority.
+func issuingChain(c Cert) ([]*x509.Certificate, error) {
+	switch c.Type {
+	case CertTypeRoot:
+		return nil, nil
+	case CertTypeInterm:
+		chain, err := readCertificateChain(c.ChainPath())
+		if err != nil {
+			return nil, err
+		}
+		if len(chain) == 0 {
+			return nil, errors.New("certificate chain of " + c.Name + " is empty")
+		}
+		return chain[1:], nil
+	case CertTypeServer, CertTypeClient:
+		cCA, err := FindIssuer(c)
+		if err != nil {
+			return nil, err
+		}
+		return readCertificateChain(cCA.ChainPath())
+	default:
+		return nil, errors.New("invalid certificate type")
+	}
+}
+
+// readKeyPair reads the private key and the certificate of a certificate and ensures they match
+func readKeyPair(config Config, c Cert) (crypto.Signer, *x509.Certificate, error) {
+	key, err := readPrivateKey(config.Password, c.KeyPath())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !keyMatches(key, cert) {
+		return nil, nil, errors.New("private key does not match certificate of " + c.Name)
+	}
+
+	return key, cert, nil
+}
+
+// keyMatches determines whether or not a private key belongs to a certificate
+func keyMatches(key crypto.Signer, cert *x509.Certificate) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
+
+// ExportPKCS12 bundles the certificate, its private key, and its issuing chain into a password-protected PKCS#12 file
+// Modern encryption (AES-256 and PBKDF2 with SHA-256) is used unless legacy is set.
+// The legacy encryption (3DES and SHA-1) is compatible with older Windows and Java versions.
+func (m *x509Manager) ExportPKCS12(config Config, c Cert, password string, legacy bool) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("password is not set")
+	}
+
+	key, cert, err := readKeyPair(config, c)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := issuingChain(c)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := pkcs12.Modern2023
+	if legacy {
+		encoder = pkcs12.LegacyDES
+	}
+
+	return encoder.Encode(key, cert, chain, password)
+}
+
+// ExportJKS writes the certificate, its private key, and its issuing chain as a named private key entry into a Java KeyStore
+// The same password protects both the keystore and the private key entry.
+func (m *x509Manager) ExportJKS(config Config, c Cert, alias, password string) ([]byte, error) {
+	if alias == "" {
+		return nil, errors.New("alias is not set")
+	}
+
+	if password == "" {
+		return nil, errors.New("password is not set")
+	}
+
+	key, cert, err := readKeyPair(config, c)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := issuingChain(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := keystore.PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       keyData,
+		CertificateChain: []keystore.Certificate{{Type: jksCertType, Content: cert.Raw}},
+	}
+
+	for _, certCA := range chain {
+		entry.CertificateChain = append(entry.CertificateChain, keystore.Certificate{Type: jksCertType, Content: certCA.Raw})
+	}
+
+	ks := keystore.New()
+	err = ks.SetPrivateKeyEntry(alias, entry, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	err = ks.Store(buf, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ExportTruststore writes the certificates of all certificate authorities in current workspace into a truststore
+// A truststore is either a PKCS#12 file (p12) or a Java KeyStore (jks), and every certificate is named after its certificate authority.
+// For PKCS#12 truststores, modern encryption is used unless legacy is set.
+func ExportTruststore(format, password string, legacy bool) ([]byte, error) {
+	if format != ExportFormatP12 && format != ExportFormatJKS {
+		return nil, errors.New("invalid truststore format: " + format)
+	}
+
+	if password == "" {
+		return nil, errors.New("password is not set")
+	}
+
+	entries := []pkcs12.TrustStoreEntry{}
+	for _, certType := range []int{CertTypeRoot, CertTypeInterm} {
+		cCAs, err := ListCerts(certType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cCA := range cCAs {
+			certCA, err := readCertificate(cCA.CertPath())
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, pkcs12.TrustStoreEntry{
+				Cert:         certCA,
+				FriendlyName: cCA.Name,
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no certificate authority found")
+	}
+
+	if format == ExportFormatP12 {
+		encoder := pkcs12.Modern2023
+		if legacy {
+			encoder = pkcs12.LegacyDES
+		}
+		return encoder.EncodeTrustStoreEntries(entries, password)
+	}
+
+	ks := keystore.New(keystore.WithOrderedAliases())
+	for _, entry := range entries {
+		err := ks.SetTrustedCertificateEntry(entry.FriendlyName, keystore.TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate:  keystore.Certificate{Type: jksCertType, Content: entry.Cert.Raw},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err := ks.Store(buf, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
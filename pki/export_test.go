@@ -0,0 +1,230 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestIssuingChain(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	assert.NoError(t, NewX509Manager().GenCert(Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}, Claim{CommonName: "Root CA"}, cRoot))
+
+	chain, err := issuingChain(cRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, chain)
+
+	// An empty chain file is not sliced
+	assert.NoError(t, os.WriteFile(cInterm.ChainPath(), nil, 0644))
+	_, err = issuingChain(cInterm)
+	assert.EqualError(t, err, "certificate chain of sre is empty")
+
+	// A chain of imported intermediate may only have the intermediate itself
+	data, err := os.ReadFile(cRoot.CertPath())
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(cInterm.ChainPath(), data, 0644))
+	chain, err = issuingChain(cInterm)
+	assert.NoError(t, err)
+	assert.Empty(t, chain)
+}
+
+func TestExportPKCS12(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmRSA, Length: 2048, Days: 375}
+	configClient := Config{Algorithm: AlgorithmEd25519, Days: 40}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cClient := Cert{Name: "service", Type: CertTypeClient}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configClient, Claim{CommonName: "service"}, cClient))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cClient, Usage{}, trust))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	certInterm, err := readCertificate(cInterm.CertPath())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		config        Config
+		c             Cert
+		legacy        bool
+		expectedCN    string
+		expectedChain []*x509.Certificate
+	}{
+		{"Root", configRoot, cRoot, false, "Root CA", []*x509.Certificate{}},
+		{"Intermediate", configInterm, cInterm, false, "SRE CA", []*x509.Certificate{certRoot}},
+		{"Server", configServer, cServer, false, "webapp", []*x509.Certificate{certInterm, certRoot}},
+		{"ServerLegacy", configServer, cServer, true, "webapp", []*x509.Certificate{certInterm, certRoot}},
+		{"Client", configClient, cClient, false, "service", []*x509.Certificate{certInterm, certRoot}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := manager.ExportPKCS12(test.config, test.c, "exportSecret", test.legacy)
+			assert.NoError(t, err)
+
+			key, cert, chain, err := pkcs12.DecodeChain(data, "exportSecret")
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+			assert.Equal(t, test.expectedCN, cert.Subject.CommonName)
+			assert.Len(t, chain, len(test.expectedChain))
+			for i := range chain {
+				assert.Equal(t, test.expectedChain[i].Raw, chain[i].Raw)
+			}
+
+			_, _, _, err = pkcs12.DecodeChain(data, "wrongSecret")
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := manager.ExportPKCS12(configServer, cServer, "", false)
+		assert.EqualError(t, err, "password is not set")
+
+		_, err = manager.ExportPKCS12(Config{Password: "wrong"}, cInterm, "exportSecret", false)
+		assert.Error(t, err)
+
+		_, err = manager.ExportPKCS12(configServer, Cert{Name: "unknown", Type: CertTypeServer}, "exportSecret", false)
+		assert.Error(t, err)
+
+		// Private key of client does not match certificate of server
+		assert.NoError(t, copyFile(cClient.KeyPath(), cServer.KeyPath(), 0600))
+		_, err = manager.ExportPKCS12(configServer, cServer, "exportSecret", false)
+		assert.EqualError(t, err, "private key does not match certificate of webapp")
+	})
+}
+
+func TestExportJKSAndTruststore(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	t.Run("NoCA", func(t *testing.T) {
+		_, err := ExportTruststore(ExportFormatJKS, "exportSecret", false)
+		assert.EqualError(t, err, "no certificate authority found")
+	})
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	certInterm, err := readCertificate(cInterm.CertPath())
+	assert.NoError(t, err)
+	certServer, err := readCertificate(cServer.CertPath())
+	assert.NoError(t, err)
+
+	t.Run("Keystore", func(t *testing.T) {
+		data, err := manager.ExportJKS(configServer, cServer, "webapp", "exportSecret")
+		assert.NoError(t, err)
+
+		ks := keystore.New()
+		assert.NoError(t, ks.Load(bytes.NewReader(data), []byte("exportSecret")))
+		assert.Equal(t, []string{"webapp"}, ks.Aliases())
+
+		entry, err := ks.GetPrivateKeyEntry("webapp", []byte("exportSecret"))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, entry.PrivateKey)
+		assert.Len(t, entry.CertificateChain, 3)
+		assert.Equal(t, certServer.Raw, entry.CertificateChain[0].Content)
+		assert.Equal(t, certInterm.Raw, entry.CertificateChain[1].Content)
+		assert.Equal(t, certRoot.Raw, entry.CertificateChain[2].Content)
+	})
+
+	t.Run("KeystoreErrors", func(t *testing.T) {
+		_, err := manager.ExportJKS(configServer, cServer, "", "exportSecret")
+		assert.EqualError(t, err, "alias is not set")
+
+		_, err = manager.ExportJKS(configServer, cServer, "webapp", "")
+		assert.EqualError(t, err, "password is not set")
+
+		_, err = manager.ExportJKS(Config{Password: "wrong"}, cInterm, "sre", "exportSecret")
+		assert.Error(t, err)
+	})
+
+	t.Run("TruststoreJKS", func(t *testing.T) {
+		data, err := ExportTruststore(ExportFormatJKS, "exportSecret", false)
+		assert.NoError(t, err)
+
+		ks := keystore.New(keystore.WithOrderedAliases())
+		assert.NoError(t, ks.Load(bytes.NewReader(data), []byte("exportSecret")))
+		assert.Equal(t, []string{"root", "sre"}, ks.Aliases())
+
+		entry, err := ks.GetTrustedCertificateEntry("sre")
+		assert.NoError(t, err)
+		assert.Equal(t, certInterm.Raw, entry.Certificate.Content)
+	})
+
+	t.Run("TruststoreP12", func(t *testing.T) {
+		for _, legacy := range []bool{false, true} {
+			data, err := ExportTruststore(ExportFormatP12, "exportSecret", legacy)
+			assert.NoError(t, err)
+
+			certs, err := pkcs12.DecodeTrustStore(data, "exportSecret")
+			assert.NoError(t, err)
+			assert.Len(t, certs, 2)
+			assert.Equal(t, certRoot.Raw, certs[0].Raw)
+			assert.Equal(t, certInterm.Raw, certs[1].Raw)
+		}
+	})
+
+	t.Run("TruststoreErrors", func(t *testing.T) {
+		_, err := ExportTruststore("pem", "exportSecret", false)
+		assert.EqualError(t, err, "invalid truststore format: pem")
+
+		_, err = ExportTruststore(ExportFormatJKS, "", false)
+		assert.EqualError(t, err, "password is not set")
+	})
+}
@@ -0,0 +1,191 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"time"
+)
+
+type (
+	// ImportSource represents the files of an external certificate authority
+	// ChainPath is optional and contains the issuing certificate authorities of an intermediate up to a root.
+	ImportSource struct {
+		CertPath    string
+		KeyPath     string
+		ChainPath   string
+		KeyPassword string
+	}
+)
+
+// isSelfSigned determines whether or not a certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// DetectCAType reads the certificate of an external certificate authority and determines its type
+// A self-signed certificate is a root certificate authority and any other one is an intermediate.
+func DetectCAType(certPath string) (int, error) {
+	cert, err := readCertificate(certPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if !cert.BasicConstraintsValid || !cert.IsCA {
+		return 0, errors.New(certPath + " is not a certificate authority")
+	}
+
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return 0, errors.New(certPath + " is not allowed to sign certificates")
+	}
+
+	if isSelfSigned(cert) {
+		return CertTypeRoot, nil
+	}
+
+	return CertTypeInterm, nil
+}
+
+// verifyImportedChain verifies an intermediate certificate against its issuing certificate authorities
+// The returned chain starts from the intermediate and ends with a self-signed root.
+func verifyImportedChain(cert *x509.Certificate, chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, certCA := range chain {
+		if isSelfSigned(certCA) {
+			roots.AddCert(certCA)
+		} else {
+			intermediates.AddCert(certCA)
+		}
+	}
+
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return chains[0], nil
+}
+
+// ImportCA imports an external root or intermediate certificate authority into current workspace
+// The private key is re-encrypted with the password in config, and an intermediate needs its issuing chain
+// either from the source or from a certificate authority in workspace.
+func (m *x509Manager) ImportCA(config Config, c Cert, src ImportSource) error {
+	if err := checkName(c.Name); err != nil {
+		return err
+	}
+
+	certType, err := DetectCAType(src.CertPath)
+	if err != nil {
+		return err
+	}
+
+	if certType != c.Type {
+		return errors.New(src.CertPath + " is not of type " + c.TypeName())
+	}
+
+	cert, err := readCertificate(src.CertPath)
+	if err != nil {
+		return err
+	}
+
+	key, err := readPrivateKey(src.KeyPassword, src.KeyPath)
+	if err != nil {
+		return err
+	}
+
+	if !keyMatches(key, cert) {
+		return errors.New("private key does not match certificate " + src.CertPath)
+	}
+
+	// A root is its own issuer
+	cCA := c
+	var chain []*x509.Certificate
+
+	if c.Type == CertTypeInterm {
+		cCA, err = issuerOf(cert, Cert{})
+		if err != nil {
+			return err
+		}
+
+		if src.ChainPath != "" {
+			chain, err = readCertificateChain(src.ChainPath)
+		} else if cCA.Type != 0 {
+			chain, err = readCertificateChain(cCA.ChainPath())
+		} else {
+			err = errors.New("certificate chain is required for an intermediate not issued in workspace")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		chain, err = verifyImportedChain(cert, chain)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write certificate key file
+	err = writePrivateKey(key, config.Password, c.KeyPath())
+	if err != nil {
+		return err
+	}
+
+	// Write certificate file
+	err = writePemFile(pemTypeCert, cert.Raw, c.CertPath())
+	if err != nil {
+		return err
+	}
+
+	if c.Type == CertTypeInterm {
+		err = os.WriteFile(c.ChainPath(), encodeCertificates(chain), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// An intermediate issued outside workspace is only recorded in issuance index
+	if cCA.Type == 0 {
+		return recordIndexed(c, Cert{Name: cert.Issuer.CommonName}, cert)
+	}
+
+	return recordCert(c, cCA, cert.Raw)
+}
+
+// ImportCSR validates a certificate signing request generated outside workspace and writes it into workspace
+// The private key of an external request never leaves its host, so the issued certificate has no key in workspace.
+func ImportCSR(c Cert, data []byte) error {
+	if c.Type != CertTypeInterm && c.Type != CertTypeServer && c.Type != CertTypeClient {
+		return errors.New("invalid certificate type")
+	}
+
+	if err := checkName(c.Name); err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || (block.Type != pemTypeCSR && block.Type != pemTypeNewCSR) {
+		return errors.New("decoding certificate request failed")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	err = csr.CheckSignature()
+	if err != nil {
+		return err
+	}
+
+	return writePemFile(pemTypeCSR, csr.Raw, c.CSRPath())
+}
@@ -0,0 +1,267 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeExternalCert creates a certificate outside workspace and writes it with its key into a directory
+// The certificate is self-signed if parent is nil.
+func writeExternalCert(t *testing.T, dir, name string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer, password string) (*x509.Certificate, crypto.Signer) {
+	_, key, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}
+
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	certData, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	assert.NoError(t, err)
+	assert.NoError(t, writePemFile(pemTypeCert, certData, filepath.Join(dir, name+".crt")))
+	assert.NoError(t, writePrivateKey(key, password, filepath.Join(dir, name+".key")))
+
+	cert, err := x509.ParseCertificate(certData)
+	assert.NoError(t, err)
+
+	return cert, key
+}
+
+func TestDetectCAType(t *testing.T) {
+	dir := t.TempDir()
+	certRoot, keyRoot := writeExternalCert(t, dir, "corp-root", true, nil, nil, "")
+	writeExternalCert(t, dir, "corp-ops", true, certRoot, keyRoot, "")
+	writeExternalCert(t, dir, "corp-web", false, certRoot, keyRoot, "")
+
+	tests := []struct {
+		name          string
+		certPath      string
+		expectedType  int
+		expectedError string
+	}{
+		{"Root", filepath.Join(dir, "corp-root.crt"), CertTypeRoot, ""},
+		{"Intermediate", filepath.Join(dir, "corp-ops.crt"), CertTypeInterm, ""},
+		{"Leaf", filepath.Join(dir, "corp-web.crt"), 0, filepath.Join(dir, "corp-web.crt") + " is not a certificate authority"},
+		{"NoFile", filepath.Join(dir, "unknown.crt"), 0, "open " + filepath.Join(dir, "unknown.crt") + ": no such file or directory"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			certType, err := DetectCAType(test.certPath)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedType, certType)
+			}
+		})
+	}
+}
+
+func TestImportCA(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	// Corporate hierarchy is imported into workspace and partner hierarchy stays outside
+	dir := t.TempDir()
+	certCorpRoot, keyCorpRoot := writeExternalCert(t, dir, "corp-root", true, nil, nil, "corpSecret")
+	certCorpOps, _ := writeExternalCert(t, dir, "corp-ops", true, certCorpRoot, keyCorpRoot, "")
+	certPartnerRoot, keyPartnerRoot := writeExternalCert(t, dir, "partner-root", true, nil, nil, "")
+	certPartnerOps, _ := writeExternalCert(t, dir, "partner-ops", true, certPartnerRoot, keyPartnerRoot, "")
+
+	src := func(name, chain, password string) ImportSource {
+		s := ImportSource{
+			CertPath:    filepath.Join(dir, name+".crt"),
+			KeyPath:     filepath.Join(dir, name+".key"),
+			KeyPassword: password,
+		}
+		if chain != "" {
+			s.ChainPath = filepath.Join(dir, chain+".crt")
+		}
+		return s
+	}
+
+	configRoot := Config{Password: "rootSecret"}
+	configInterm := Config{Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cPartner := Cert{Name: "partner", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			c             Cert
+			src           ImportSource
+			expectedError string
+		}{
+			{"NoName", Cert{Type: CertTypeRoot}, src("corp-root", "", "corpSecret"), "name is not set"},
+			{"TypeMismatch", cOps, src("corp-root", "", "corpSecret"), filepath.Join(dir, "corp-root.crt") + " is not of type intermediate"},
+			{"NoKeyPassword", cRoot, src("corp-root", "", ""), "password required but not set"},
+			{"KeyMismatch", cOps, ImportSource{CertPath: filepath.Join(dir, "corp-ops.crt"), KeyPath: filepath.Join(dir, "partner-ops.key")}, "private key does not match certificate " + filepath.Join(dir, "corp-ops.crt")},
+			{"NoChain", cPartner, src("partner-ops", "", ""), "certificate chain is required for an intermediate not issued in workspace"},
+			{"InvalidChain", cPartner, src("partner-ops", "corp-root", ""), "x509: certificate signed by unknown authority"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				err := manager.ImportCA(configInterm, test.c, test.src)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+				assert.NoFileExists(t, test.c.CertPath())
+			})
+		}
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		err := manager.ImportCA(configRoot, cRoot, src("corp-root", "", "corpSecret"))
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cRoot.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, certCorpRoot.Raw, cert.Raw)
+
+		// Private key is re-encrypted with the password of workspace
+		key, err := readPrivateKey("rootSecret", cRoot.KeyPath())
+		assert.NoError(t, err)
+		assert.Equal(t, keyCorpRoot.Public(), key.Public())
+
+		store, err := readStatusStore(cRoot.StatusPath())
+		assert.NoError(t, err)
+		assert.Equal(t, StatusGood, store.Lookup(cert.SerialNumber).Status)
+
+		err = manager.ImportCA(configRoot, cRoot, src("corp-root", "", "corpSecret"))
+		assert.EqualError(t, err, "root already exists")
+	})
+
+	t.Run("IntermediateIssuedInWorkspace", func(t *testing.T) {
+		err := manager.ImportCA(configInterm, cOps, src("corp-ops", "", ""))
+		assert.NoError(t, err)
+
+		chain, err := readCertificateChain(cOps.ChainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 2)
+		assert.Equal(t, certCorpOps.Raw, chain[0].Raw)
+		assert.Equal(t, certCorpRoot.Raw, chain[1].Raw)
+
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+		entry, ok := index.Lookup("root", certCorpOps.SerialNumber)
+		assert.True(t, ok)
+		assert.Equal(t, "intermediate/ops.ca.chain", entry.ChainPath)
+
+		// Imported intermediate signs certificates like a native one
+		assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+		assert.NoError(t, manager.SignCSR(configInterm, cOps, configServer, cServer, Usage{}, trust))
+		assert.NoError(t, manager.VerifyCert(cOps, cServer, ""))
+
+		cCA, err := FindIssuer(cServer)
+		assert.NoError(t, err)
+		assert.Equal(t, cOps, cCA)
+	})
+
+	t.Run("IntermediateWithChain", func(t *testing.T) {
+		err := manager.ImportCA(configInterm, cPartner, src("partner-ops", "partner-root", ""))
+		assert.NoError(t, err)
+
+		chain, err := readCertificateChain(cPartner.ChainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 2)
+		assert.Equal(t, certPartnerOps.Raw, chain[0].Raw)
+		assert.Equal(t, certPartnerRoot.Raw, chain[1].Raw)
+
+		// Issuer outside workspace is recorded by its common name
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+		_, ok := index.Lookup("partner-root", certPartnerOps.SerialNumber)
+		assert.True(t, ok)
+	})
+}
+
+func TestImportCSR(t *testing.T) {
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	_, key, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+	csrData, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "webapp"},
+		DNSNames: []string{"example.com"},
+	}, key)
+	assert.NoError(t, err)
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeCSR, Bytes: csrData})
+	newCSRPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeNewCSR, Bytes: csrData})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeCert, Bytes: csrData})
+
+	// Tamper with the signature of request
+	tampered := append([]byte{}, csrData...)
+	tampered[len(tampered)-1] ^= 0xff
+	tamperedPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeCSR, Bytes: tampered})
+
+	tests := []struct {
+		name          string
+		c             Cert
+		data          []byte
+		expectedError string
+	}{
+		{"InvalidType", Cert{Name: "root", Type: CertTypeRoot}, csrPEM, "invalid certificate type"},
+		{"NoName", Cert{Type: CertTypeServer}, csrPEM, "name is not set"},
+		{"NotPEM", Cert{Name: "webapp", Type: CertTypeServer}, []byte("invalid"), "decoding certificate request failed"},
+		{"NotCSR", Cert{Name: "webapp", Type: CertTypeServer}, certPEM, "decoding certificate request failed"},
+		{"InvalidSignature", Cert{Name: "webapp", Type: CertTypeServer}, tamperedPEM, "x509: ECDSA verification failure"},
+		{"Server", Cert{Name: "webapp", Type: CertTypeServer}, csrPEM, ""},
+		{"Client", Cert{Name: "service", Type: CertTypeClient}, newCSRPEM, ""},
+		{"Exists", Cert{Name: "webapp", Type: CertTypeClient}, csrPEM, "webapp already exists"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ImportCSR(test.c, test.data)
+
+			if test.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+			} else {
+				assert.NoError(t, err)
+
+				csr, err := readCertificateRequest(test.c.CSRPath())
+				assert.NoError(t, err)
+				assert.Equal(t, csrData, csr.Raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package pki
+
+import (
+	"crypto/x509"
+	"math/big"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// StatusValid is the status of a certificate in issuance index which is neither revoked nor expired
+	StatusValid = "valid"
+	// StatusExpired is the status of a certificate in issuance index which is expired
+	StatusExpired = "expired"
+)
+
+type (
+	// IndexFilter represents the type for querying issuance index
+	// Zero-valued fields match all certificates.
+	IndexFilter struct {
+		Name           string
+		Type           int
+		Issuer         string
+		Status         string
+		ExpiringWithin time.Duration
+	}
+)
+
+// LoadIndex reads and parses issuance index from a YAML file
+// A missing file means no certificate is issued yet.
+func LoadIndex(file string) (*Index, error) {
+	index := new(Index)
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// SaveIndex writes issuance index to a YAML file
+func SaveIndex(index *Index, file string) error {
+	if index == nil {
+		return nil
+	}
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first and rename it, so index file is never left partially written
+	tmp := file + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// updateIndex loads, updates, and saves issuance index of workspace while holding a lock on it
+func updateIndex(update func(*Index)) error {
+	unlock, err := lockFile(FileIndexLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := LoadIndex(FileIndex)
+	if err != nil {
+		return err
+	}
+
+	update(index)
+
+	return SaveIndex(index, FileIndex)
+}
+
+func newIndexEntry(c, cCA Cert, cert *x509.Certificate) IndexEntry {
+	entry := IndexEntry{
+		Name:           c.Name,
+		Type:           c.TypeName(),
+		Serial:         cert.SerialNumber.String(),
+		Subject:        cert.Subject.String(),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Issuer:         cCA.Name,
+		NotBefore:      cert.NotBefore.UTC(),
+		NotAfter:       cert.NotAfter.UTC(),
+		Status:         StatusValid,
+		CertPath:       c.CertPath(),
+		CSRPath:        c.CSRPath(),
+	}
+
+	// Certificates signed from external requests have no key in workspace
+	if _, err := os.Stat(c.KeyPath()); err == nil {
+		entry.KeyPath = c.KeyPath()
+	}
+
+	for _, ip := range cert.IPAddresses {
+		entry.IPAddresses = append(entry.IPAddresses, ip.String())
+	}
+
+	for _, uri := range cert.URIs {
+		entry.URIs = append(entry.URIs, uri.String())
+	}
+
+	// Root chain is the certificate itself
+	switch c.Type {
+	case CertTypeInterm:
+		entry.ChainPath = c.ChainPath()
+	case CertTypeServer, CertTypeClient:
+		entry.ChainPath = c.FullchainPath()
+	}
+
+	return entry
+}
+
+// recordIndexed adds a new certificate issued by a certificate authority to issuance index
+func recordIndexed(c, cCA Cert, cert *x509.Certificate) error {
+	return updateIndex(func(index *Index) {
+		index.Certificates = append(index.Certificates, newIndexEntry(c, cCA, cert))
+	})
+}
+
+// revokeIndexed marks a certificate issued by a certificate authority as revoked in issuance index
+func revokeIndexed(c, cCA Cert, cert *x509.Certificate, reason string, revokedAt time.Time) error {
+	return updateIndex(func(index *Index) {
+		i := index.find(cCA.Name, cert.SerialNumber)
+		if i < 0 {
+			// Certificates issued by older versions are not indexed
+			index.Certificates = append(index.Certificates, newIndexEntry(c, cCA, cert))
+			i = len(index.Certificates) - 1
+		}
+
+		index.Certificates[i].Status = StatusRevoked
+		index.Certificates[i].Reason = reason
+		index.Certificates[i].RevokedAt = revokedAt
+	})
+}
+
+// archiveIndexed points a superseded certificate issued by a certificate authority to its archived files in issuance index
+func archiveIndexed(c, cCA Cert, cert *x509.Certificate, keyArchived bool) error {
+	return updateIndex(func(index *Index) {
+		i := index.find(cCA.Name, cert.SerialNumber)
+		if i < 0 {
+			// Certificates issued by older versions are not indexed
+			index.Certificates = append(index.Certificates, newIndexEntry(c, cCA, cert))
+			i = len(index.Certificates) - 1
+		}
+
+		// Request and chain files are replaced by the renewal
+		index.Certificates[i].CertPath = c.ArchiveCertPath(cert.SerialNumber)
+		index.Certificates[i].CSRPath = ""
+		index.Certificates[i].ChainPath = ""
+		if keyArchived {
+			index.Certificates[i].KeyPath = c.ArchiveKeyPath(cert.SerialNumber)
+		}
+	})
+}
+
+func (i *Index) find(issuer string, serial *big.Int) int {
+	for j, e := range i.Certificates {
+		if e.Issuer == issuer && e.Serial == serial.String() {
+			return j
+		}
+	}
+
+	return -1
+}
+
+// Lookup returns the entry for a certificate by its issuer name and serial number
+func (i *Index) Lookup(issuer string, serial *big.Int) (IndexEntry, bool) {
+	if j := i.find(issuer, serial); j >= 0 {
+		return i.Certificates[j], true
+	}
+
+	return IndexEntry{}, false
+}
+
+// Query returns the entries matching a filter at a point in time in the order they are issued
+func (i *Index) Query(filter IndexFilter, now time.Time) []IndexEntry {
+	entries := make([]IndexEntry, 0)
+	for _, e := range i.Certificates {
+		if filter.Name != "" && e.Name != filter.Name {
+			continue
+		}
+
+		if filter.Type != 0 && e.Type != (Cert{Type: filter.Type}).TypeName() {
+			continue
+		}
+
+		if filter.Issuer != "" && e.Issuer != filter.Issuer {
+			continue
+		}
+
+		if filter.Status != "" && e.StatusAt(now) != filter.Status {
+			continue
+		}
+
+		if filter.ExpiringWithin > 0 && (e.StatusAt(now) != StatusValid || e.NotAfter.After(now.Add(filter.ExpiringWithin))) {
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// StatusAt returns the status of a certificate at a point in time
+// A certificate not revoked is expired after its validity period.
+func (e IndexEntry) StatusAt(now time.Time) string {
+	if e.Status == StatusValid && now.After(e.NotAfter) {
+		return StatusExpired
+	}
+
+	return e.Status
+}
@@ -0,0 +1,304 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveLoadIndex(t *testing.T) {
+	path, cleanup, err := util.CreateTempFile("")
+	defer cleanup()
+	assert.NoError(t, err)
+
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	index := &Index{
+		Certificates: []IndexEntry{
+			{
+				Name:      "webapp",
+				Type:      "server",
+				Serial:    "1001",
+				Subject:   "CN=webapp",
+				DNSNames:  []string{"example.com"},
+				Issuer:    "sre",
+				NotBefore: notBefore,
+				NotAfter:  notBefore.AddDate(1, 0, 0),
+				Status:    StatusValid,
+				KeyPath:   "server/webapp.key",
+				CertPath:  "server/webapp.cert",
+				CSRPath:   "csr/webapp.csr",
+			},
+		},
+	}
+
+	assert.NoError(t, SaveIndex(nil, path))
+
+	err = SaveIndex(index, path)
+	assert.NoError(t, err)
+
+	i, err := LoadIndex(path)
+	assert.NoError(t, err)
+	assert.Equal(t, index, i)
+
+	// A missing file means no certificate is issued
+	i, err = LoadIndex("missing.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, &Index{}, i)
+}
+
+func TestNewIndexEntry(t *testing.T) {
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	uri, _ := url.Parse("spiffe://example.com/webapp")
+	cert := &x509.Certificate{
+		SerialNumber:   big.NewInt(1001),
+		Subject:        pkix.Name{CommonName: "webapp", Organization: []string{"Example"}},
+		DNSNames:       []string{"example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1")},
+		EmailAddresses: []string{"admin@example.com"},
+		URIs:           []*url.URL{uri},
+		NotBefore:      notBefore,
+		NotAfter:       notBefore.AddDate(1, 0, 0),
+	}
+
+	tests := []struct {
+		name          string
+		c             Cert
+		cCA           Cert
+		expectedEntry IndexEntry
+	}{
+		{
+			"Server",
+			Cert{Name: "webapp", Type: CertTypeServer},
+			Cert{Name: "sre", Type: CertTypeInterm},
+			IndexEntry{
+				Name:           "webapp",
+				Type:           "server",
+				Serial:         "1001",
+				Subject:        "CN=webapp,O=Example",
+				DNSNames:       []string{"example.com"},
+				IPAddresses:    []string{"127.0.0.1"},
+				EmailAddresses: []string{"admin@example.com"},
+				URIs:           []string{"spiffe://example.com/webapp"},
+				Issuer:         "sre",
+				NotBefore:      notBefore,
+				NotAfter:       notBefore.AddDate(1, 0, 0),
+				Status:         StatusValid,
+				KeyPath:        "server/webapp.key",
+				CertPath:       "server/webapp.cert",
+				CSRPath:        "csr/webapp.csr",
+				ChainPath:      "server/webapp.fullchain",
+			},
+		},
+		{
+			"Intermediate",
+			Cert{Name: "sre", Type: CertTypeInterm},
+			Cert{Name: "root", Type: CertTypeRoot},
+			IndexEntry{
+				Name:           "sre",
+				Type:           "intermediate",
+				Serial:         "1001",
+				Subject:        "CN=webapp,O=Example",
+				DNSNames:       []string{"example.com"},
+				IPAddresses:    []string{"127.0.0.1"},
+				EmailAddresses: []string{"admin@example.com"},
+				URIs:           []string{"spiffe://example.com/webapp"},
+				Issuer:         "root",
+				NotBefore:      notBefore,
+				NotAfter:       notBefore.AddDate(1, 0, 0),
+				Status:         StatusValid,
+				KeyPath:        "intermediate/sre.ca.key",
+				CertPath:       "intermediate/sre.ca.cert",
+				CSRPath:        "csr/sre.ca.csr",
+				ChainPath:      "intermediate/sre.ca.chain",
+			},
+		},
+		{
+			"External",
+			Cert{Name: "agent", Type: CertTypeClient},
+			Cert{Name: "sre", Type: CertTypeInterm},
+			IndexEntry{
+				Name:           "agent",
+				Type:           "client",
+				Serial:         "1001",
+				Subject:        "CN=webapp,O=Example",
+				DNSNames:       []string{"example.com"},
+				IPAddresses:    []string{"127.0.0.1"},
+				EmailAddresses: []string{"admin@example.com"},
+				URIs:           []string{"spiffe://example.com/webapp"},
+				Issuer:         "sre",
+				NotBefore:      notBefore,
+				NotAfter:       notBefore.AddDate(1, 0, 0),
+				Status:         StatusValid,
+				CertPath:       "client/agent.cert",
+				CSRPath:        "csr/agent.csr",
+				ChainPath:      "client/agent.fullchain",
+			},
+		},
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	for _, path := range []string{"server/webapp.key", "intermediate/sre.ca.key"} {
+		assert.NoError(t, os.WriteFile(path, []byte("key"), 0600))
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedEntry, newIndexEntry(test.c, test.cCA, cert))
+		})
+	}
+}
+
+func TestRecordIndexedExternal(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configClient := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cAgent := Cert{Name: "agent", Type: CertTypeClient}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+
+	writeExternalCSR(t, cAgent)
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cAgent, Usage{}, trust))
+
+	index, err := LoadIndex(FileIndex)
+	assert.NoError(t, err)
+
+	entries := index.Query(IndexFilter{Name: "agent"}, time.Now())
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "client/agent.cert", entries[0].CertPath)
+	assert.Empty(t, entries[0].KeyPath)
+
+	entries = index.Query(IndexFilter{Name: "sre"}, time.Now())
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "intermediate/sre.ca.key", entries[0].KeyPath)
+}
+
+func TestRecordRevokeIndexed(t *testing.T) {
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	cCA := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cClient := Cert{Name: "service", Type: CertTypeClient}
+	certServer := &x509.Certificate{SerialNumber: big.NewInt(1001), NotAfter: time.Now().AddDate(1, 0, 0)}
+	certClient := &x509.Certificate{SerialNumber: big.NewInt(10001), NotAfter: time.Now().AddDate(0, 1, 0)}
+	revokedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err = recordIndexed(cServer, cCA, certServer)
+	assert.NoError(t, err)
+
+	err = revokeIndexed(cServer, cCA, certServer, "keyCompromise", revokedAt)
+	assert.NoError(t, err)
+
+	// Certificates not indexed can still be revoked
+	err = revokeIndexed(cClient, cCA, certClient, "superseded", revokedAt)
+	assert.NoError(t, err)
+
+	index, err := LoadIndex(FileIndex)
+	assert.NoError(t, err)
+	assert.Len(t, index.Certificates, 2)
+
+	e, ok := index.Lookup("sre", big.NewInt(1001))
+	assert.True(t, ok)
+	assert.Equal(t, "webapp", e.Name)
+	assert.Equal(t, StatusRevoked, e.Status)
+	assert.Equal(t, "keyCompromise", e.Reason)
+	assert.Equal(t, revokedAt, e.RevokedAt)
+
+	e, ok = index.Lookup("sre", big.NewInt(10001))
+	assert.True(t, ok)
+	assert.Equal(t, "service", e.Name)
+	assert.Equal(t, StatusRevoked, e.Status)
+
+	_, ok = index.Lookup("root", big.NewInt(1001))
+	assert.False(t, ok)
+}
+
+func TestIndexQuery(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	index := &Index{
+		Certificates: []IndexEntry{
+			{Name: "root", Type: "root", Issuer: "root", NotAfter: now.AddDate(20, 0, 0), Status: StatusValid},
+			{Name: "sre", Type: "intermediate", Issuer: "root", NotAfter: now.AddDate(10, 0, 0), Status: StatusValid},
+			{Name: "webapp", Type: "server", Issuer: "sre", NotAfter: now.AddDate(0, 0, 20), Status: StatusValid},
+			{Name: "legacy", Type: "server", Issuer: "sre", NotAfter: now.AddDate(0, 0, -1), Status: StatusValid},
+			{Name: "service", Type: "client", Issuer: "sre", NotAfter: now.AddDate(0, 0, 10), Status: StatusRevoked},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		filter        IndexFilter
+		expectedNames []string
+	}{
+		{"All", IndexFilter{}, []string{"root", "sre", "webapp", "legacy", "service"}},
+		{"ByName", IndexFilter{Name: "webapp"}, []string{"webapp"}},
+		{"ByType", IndexFilter{Type: CertTypeServer}, []string{"webapp", "legacy"}},
+		{"ByIssuer", IndexFilter{Issuer: "root"}, []string{"root", "sre"}},
+		{"Valid", IndexFilter{Status: StatusValid}, []string{"root", "sre", "webapp"}},
+		{"Expired", IndexFilter{Status: StatusExpired}, []string{"legacy"}},
+		{"Revoked", IndexFilter{Status: StatusRevoked}, []string{"service"}},
+		{"ExpiringWithin", IndexFilter{ExpiringWithin: 30 * 24 * time.Hour}, []string{"webapp"}},
+		{"Combined", IndexFilter{Type: CertTypeServer, Issuer: "sre", Status: StatusValid}, []string{"webapp"}},
+		{"NoMatch", IndexFilter{Issuer: "ops"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []string{}
+			for _, e := range index.Query(test.filter, now) {
+				names = append(names, e.Name)
+			}
+
+			assert.Equal(t, test.expectedNames, names)
+		})
+	}
+}
+
+func TestIndexEntryStatusAt(t *testing.T) {
+	notAfter := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		entry          IndexEntry
+		now            time.Time
+		expectedStatus string
+	}{
+		{"Valid", IndexEntry{Status: StatusValid, NotAfter: notAfter}, notAfter.AddDate(0, 0, -1), StatusValid},
+		{"Expired", IndexEntry{Status: StatusValid, NotAfter: notAfter}, notAfter.AddDate(0, 0, 1), StatusExpired},
+		{"Revoked", IndexEntry{Status: StatusRevoked, NotAfter: notAfter}, notAfter.AddDate(0, 0, -1), StatusRevoked},
+		{"RevokedAndExpired", IndexEntry{Status: StatusRevoked, NotAfter: notAfter}, notAfter.AddDate(0, 0, 1), StatusRevoked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedStatus, test.entry.StatusAt(test.now))
+		})
+	}
+}
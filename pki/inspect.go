@@ -0,0 +1,291 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// DescTypeCert is the type of description for a certificate
+	DescTypeCert = "certificate"
+	// DescTypeCSR is the type of description for a certificate signing request
+	DescTypeCSR = "certificate request"
+	// DescTypeKey is the type of description for a private key
+	DescTypeKey = "private key"
+	// DescTypeEncryptedKey is the type of description for an encrypted private key
+	DescTypeEncryptedKey = "encrypted private key"
+	// DescTypePublicKey is the type of description for a public key
+	DescTypePublicKey = "public key"
+	// DescTypeCRL is the type of description for a certificate revocation list
+	DescTypeCRL = "certificate revocation list"
+)
+
+var (
+	keyUsageNames = []struct {
+		usage x509.KeyUsage
+		name  string
+	}{
+		{x509.KeyUsageDigitalSignature, "DigitalSignature"},
+		{x509.KeyUsageContentCommitment, "ContentCommitment"},
+		{x509.KeyUsageKeyEncipherment, "KeyEncipherment"},
+		{x509.KeyUsageDataEncipherment, "DataEncipherment"},
+		{x509.KeyUsageKeyAgreement, "KeyAgreement"},
+		{x509.KeyUsageCertSign, "CertSign"},
+		{x509.KeyUsageCRLSign, "CRLSign"},
+		{x509.KeyUsageEncipherOnly, "EncipherOnly"},
+		{x509.KeyUsageDecipherOnly, "DecipherOnly"},
+	}
+
+	extKeyUsageNames = map[x509.ExtKeyUsage]string{
+		x509.ExtKeyUsageAny:             "Any",
+		x509.ExtKeyUsageServerAuth:      "ServerAuth",
+		x509.ExtKeyUsageClientAuth:      "ClientAuth",
+		x509.ExtKeyUsageCodeSigning:     "CodeSigning",
+		x509.ExtKeyUsageEmailProtection: "EmailProtection",
+		x509.ExtKeyUsageTimeStamping:    "TimeStamping",
+		x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+		x509.ExtKeyUsageIPSECEndSystem:  "IPSECEndSystem",
+		x509.ExtKeyUsageIPSECTunnel:     "IPSECTunnel",
+		x509.ExtKeyUsageIPSECUser:       "IPSECUser",
+
+		x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "MicrosoftServerGatedCrypto",
+		x509.ExtKeyUsageNetscapeServerGatedCrypto:      "NetscapeServerGatedCrypto",
+		x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "MicrosoftCommercialCodeSigning",
+		x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "MicrosoftKernelCodeSigning",
+	}
+)
+
+// fingerprint returns the colon-separated hex encoding of a hash
+func fingerprint(hash []byte) string {
+	parts := make([]string, len(hash))
+	for i, b := range hash {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func describePublicKey(pub crypto.PublicKey) string {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d bits", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+func describeKeyUsage(usage x509.KeyUsage) []string {
+	names := make([]string, 0)
+	for _, ku := range keyUsageNames {
+		if usage&ku.usage != 0 {
+			names = append(names, ku.name)
+		}
+	}
+
+	return names
+}
+
+func describeExtKeyUsage(usages []x509.ExtKeyUsage, unknown []asn1.ObjectIdentifier) []string {
+	names := make([]string, 0, len(usages)+len(unknown))
+	for _, usage := range usages {
+		if name, ok := extKeyUsageNames[usage]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("ExtKeyUsage(%d)", usage))
+		}
+	}
+
+	for _, oid := range unknown {
+		names = append(names, oid.String())
+	}
+
+	return names
+}
+
+func describeCert(cert *x509.Certificate) Description {
+	sha256Sum := sha256.Sum256(cert.Raw)
+	sha1Sum := sha1.Sum(cert.Raw)
+	notBefore, notAfter := cert.NotBefore.UTC(), cert.NotAfter.UTC()
+
+	desc := Description{
+		Type:               DescTypeCert,
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		Serial:             cert.SerialNumber.String(),
+		NotBefore:          &notBefore,
+		NotAfter:           &notAfter,
+		DNSNames:           cert.DNSNames,
+		IPAddresses:        ipStrings(cert.IPAddresses),
+		EmailAddresses:     cert.EmailAddresses,
+		URIs:               uriStrings(cert.URIs),
+		PublicKey:          describePublicKey(cert.PublicKey),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		KeyUsage:           describeKeyUsage(cert.KeyUsage),
+		ExtKeyUsage:        describeExtKeyUsage(cert.ExtKeyUsage, cert.UnknownExtKeyUsage),
+		SubjectKeyID:       fingerprint(cert.SubjectKeyId),
+		AuthorityKeyID:     fingerprint(cert.AuthorityKeyId),
+		SHA256Fingerprint:  fingerprint(sha256Sum[:]),
+		SHA1Fingerprint:    fingerprint(sha1Sum[:]),
+	}
+
+	if cert.BasicConstraintsValid {
+		desc.BasicConstraints = &BasicConstraints{
+			IsCA: cert.IsCA,
+		}
+
+		// A negative MaxPathLen means the path length is unlimited
+		if cert.IsCA && (cert.MaxPathLen > 0 || cert.MaxPathLenZero) {
+			maxPathLen := cert.MaxPathLen
+			desc.BasicConstraints.MaxPathLen = &maxPathLen
+		}
+	}
+
+	return desc
+}
+
+func describeCSR(csr *x509.CertificateRequest) Description {
+	sha256Sum := sha256.Sum256(csr.Raw)
+	sha1Sum := sha1.Sum(csr.Raw)
+
+	return Description{
+		Type:               DescTypeCSR,
+		Subject:            csr.Subject.String(),
+		DNSNames:           csr.DNSNames,
+		IPAddresses:        ipStrings(csr.IPAddresses),
+		EmailAddresses:     csr.EmailAddresses,
+		URIs:               uriStrings(csr.URIs),
+		PublicKey:          describePublicKey(csr.PublicKey),
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+		SHA256Fingerprint:  fingerprint(sha256Sum[:]),
+		SHA1Fingerprint:    fingerprint(sha1Sum[:]),
+	}
+}
+
+// describePublicPart describes the public part of a key, so no secret is ever included
+func describePublicPart(descType string, pub crypto.PublicKey) (Description, error) {
+	subjectKeyID, err := computeSubjectKeyID(pub)
+	if err != nil {
+		return Description{}, err
+	}
+
+	pubKeyData, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return Description{}, err
+	}
+
+	sha256Sum := sha256.Sum256(pubKeyData)
+	sha1Sum := sha1.Sum(pubKeyData)
+
+	return Description{
+		Type:              descType,
+		PublicKey:         describePublicKey(pub),
+		SubjectKeyID:      fingerprint(subjectKeyID),
+		SHA256Fingerprint: fingerprint(sha256Sum[:]),
+		SHA1Fingerprint:   fingerprint(sha1Sum[:]),
+	}, nil
+}
+
+func describeBlock(block *pem.Block) (Description, error) {
+	switch {
+	case block.Type == pemTypeCert:
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return Description{}, err
+		}
+		return describeCert(cert), nil
+
+	case block.Type == pemTypeCSR || block.Type == pemTypeNewCSR:
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return Description{}, err
+		}
+		return describeCSR(csr), nil
+
+	case block.Type == pemTypeCRL:
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return Description{}, err
+		}
+		sha256Sum := sha256.Sum256(crl.Raw)
+		sha1Sum := sha1.Sum(crl.Raw)
+		thisUpdate, nextUpdate := crl.ThisUpdate.UTC(), crl.NextUpdate.UTC()
+		return Description{
+			Type:               DescTypeCRL,
+			Issuer:             crl.Issuer.String(),
+			Serial:             crl.Number.String(),
+			NotBefore:          &thisUpdate,
+			NotAfter:           &nextUpdate,
+			SignatureAlgorithm: crl.SignatureAlgorithm.String(),
+			AuthorityKeyID:     fingerprint(crl.AuthorityKeyId),
+			SHA256Fingerprint:  fingerprint(sha256Sum[:]),
+			SHA1Fingerprint:    fingerprint(sha1Sum[:]),
+		}, nil
+
+	case block.Type == "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Description{}, err
+		}
+		return describePublicPart(DescTypePublicKey, pub)
+
+	// The public part of an encrypted private key cannot be read without password
+	case block.Type == pemTypeEncryptedKey || x509.IsEncryptedPEMBlock(block): //nolint:staticcheck // detecting legacy encrypted keys
+		return Description{Type: DescTypeEncryptedKey}, nil
+
+	case block.Type == pemTypeKey || block.Type == pemTypeRSAKey || block.Type == pemTypeECKey:
+		key, err := parsePrivateKey(block.Type, block.Bytes)
+		if err != nil {
+			return Description{}, err
+		}
+		return describePublicPart(DescTypeKey, key.Public())
+
+	default:
+		return Description{}, errors.New("unsupported PEM type " + block.Type)
+	}
+}
+
+// Inspect decodes all PEM blocks in a file including certificates, chains, certificate signing requests,
+// certificate revocation lists, and keys. Only the public parts of keys are described.
+func Inspect(path string) ([]Description, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	descs := make([]Description, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		desc, err := describeBlock(block)
+		if err != nil {
+			return nil, err
+		}
+
+		descs = append(descs, desc)
+	}
+
+	if len(descs) == 0 {
+		return nil, errors.New("no PEM block found in " + path)
+	}
+
+	return descs, nil
+}
@@ -0,0 +1,201 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, "", fingerprint(nil))
+	assert.Equal(t, "0A", fingerprint([]byte{0x0a}))
+	assert.Equal(t, "01:AB:FF", fingerprint([]byte{0x01, 0xab, 0xff}))
+}
+
+func TestDescribeKeyUsage(t *testing.T) {
+	assert.Equal(t, []string{}, describeKeyUsage(0))
+	assert.Equal(t, []string{"DigitalSignature", "CertSign", "CRLSign"}, describeKeyUsage(x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign|x509.KeyUsageCRLSign))
+	assert.Equal(t, []string{"ServerAuth", "OCSPSigning", "1.2.3.4"}, describeExtKeyUsage(
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageOCSPSigning},
+		[]asn1.ObjectIdentifier{{1, 2, 3, 4}},
+	))
+}
+
+func TestInspect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gocert-inspect-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pubCA, keyCA, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+	pub, key, err := genKeyPair(AlgorithmRSA, testKeyLen)
+	assert.NoError(t, err)
+	skiCA, err := computeSubjectKeyID(pubCA)
+	assert.NoError(t, err)
+
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	templateCA := &x509.Certificate{
+		SerialNumber:          big.NewInt(10),
+		Subject:               pkix.Name{CommonName: "Root CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(20, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            1,
+		SubjectKeyId:          skiCA,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	certCAData, err := x509.CreateCertificate(rand.Reader, templateCA, templateCA, pubCA, keyCA)
+	assert.NoError(t, err)
+	certCA, err := x509.ParseCertificate(certCAData)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1001),
+		Subject:      pkix.Name{CommonName: "webapp"},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.AddDate(1, 0, 0),
+		DNSNames:     []string{"example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certData, err := x509.CreateCertificate(rand.Reader, template, certCA, pub, keyCA)
+	assert.NoError(t, err)
+
+	csrData, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "webapp"},
+		DNSNames: []string{"example.com"},
+	}, key)
+	assert.NoError(t, err)
+
+	crlData, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: notBefore,
+		NextUpdate: notBefore.AddDate(0, 0, 7),
+	}, certCA, keyCA)
+	assert.NoError(t, err)
+
+	pubData, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+
+	certFile := path.Join(dir, "webapp.cert")
+	chainFile := path.Join(dir, "webapp.chain")
+	csrFile := path.Join(dir, "webapp.csr")
+	crlFile := path.Join(dir, "root.crl")
+	keyFile := path.Join(dir, "webapp.key")
+	encKeyFile := path.Join(dir, "root.ca.key")
+	pubFile := path.Join(dir, "webapp.pub")
+	invalidFile := path.Join(dir, "invalid.pem")
+	unsupportedFile := path.Join(dir, "unsupported.pem")
+	emptyFile := path.Join(dir, "empty.pem")
+
+	assert.NoError(t, writePemFile(pemTypeCert, certData, certFile))
+	assert.NoError(t, os.WriteFile(chainFile, append(
+		pem.EncodeToMemory(&pem.Block{Type: pemTypeCert, Bytes: certData}),
+		pem.EncodeToMemory(&pem.Block{Type: pemTypeCert, Bytes: certCAData})...,
+	), 0644))
+	assert.NoError(t, writePemFile(pemTypeCSR, csrData, csrFile))
+	assert.NoError(t, writePemFile(pemTypeCRL, crlData, crlFile))
+	assert.NoError(t, writePrivateKey(key, "", keyFile))
+	assert.NoError(t, writePrivateKey(keyCA, "secret", encKeyFile))
+	assert.NoError(t, writePemFile("PUBLIC KEY", pubData, pubFile))
+	assert.NoError(t, writePemFile(pemTypeCert, []byte("invalid"), invalidFile))
+	assert.NoError(t, writePemFile("UNKNOWN", []byte("unknown"), unsupportedFile))
+	assert.NoError(t, os.WriteFile(emptyFile, nil, 0644))
+
+	t.Run("Certificate", func(t *testing.T) {
+		descs, err := Inspect(certFile)
+		assert.NoError(t, err)
+		assert.Len(t, descs, 1)
+
+		d := descs[0]
+		assert.Equal(t, DescTypeCert, d.Type)
+		assert.Equal(t, "CN=webapp", d.Subject)
+		assert.Equal(t, "CN=Root CA", d.Issuer)
+		assert.Equal(t, "1001", d.Serial)
+		assert.Equal(t, notBefore, *d.NotBefore)
+		assert.Equal(t, notBefore.AddDate(1, 0, 0), *d.NotAfter)
+		assert.Equal(t, []string{"example.com"}, d.DNSNames)
+		assert.Equal(t, []string{"127.0.0.1"}, d.IPAddresses)
+		assert.Equal(t, "RSA 1024 bits", d.PublicKey)
+		assert.Equal(t, "ECDSA-SHA256", d.SignatureAlgorithm)
+		assert.Equal(t, []string{"DigitalSignature"}, d.KeyUsage)
+		assert.Equal(t, []string{"ServerAuth"}, d.ExtKeyUsage)
+		assert.Nil(t, d.BasicConstraints)
+		assert.Equal(t, fingerprint(skiCA), d.AuthorityKeyID)
+		assert.Len(t, d.SHA256Fingerprint, 32*3-1)
+		assert.Len(t, d.SHA1Fingerprint, 20*3-1)
+	})
+
+	t.Run("Chain", func(t *testing.T) {
+		descs, err := Inspect(chainFile)
+		assert.NoError(t, err)
+		assert.Len(t, descs, 2)
+
+		d := descs[1]
+		assert.Equal(t, "CN=Root CA", d.Subject)
+		assert.Equal(t, "ECDSA P-256", d.PublicKey)
+		assert.Equal(t, []string{"CertSign", "CRLSign"}, d.KeyUsage)
+		assert.Equal(t, fingerprint(skiCA), d.SubjectKeyID)
+		assert.True(t, d.BasicConstraints.IsCA)
+		assert.Equal(t, 1, *d.BasicConstraints.MaxPathLen)
+	})
+
+	t.Run("CSR", func(t *testing.T) {
+		descs, err := Inspect(csrFile)
+		assert.NoError(t, err)
+		assert.Len(t, descs, 1)
+		assert.Equal(t, DescTypeCSR, descs[0].Type)
+		assert.Equal(t, "CN=webapp", descs[0].Subject)
+		assert.Equal(t, []string{"example.com"}, descs[0].DNSNames)
+		assert.Equal(t, "RSA 1024 bits", descs[0].PublicKey)
+	})
+
+	t.Run("CRL", func(t *testing.T) {
+		descs, err := Inspect(crlFile)
+		assert.NoError(t, err)
+		assert.Len(t, descs, 1)
+		assert.Equal(t, DescTypeCRL, descs[0].Type)
+		assert.Equal(t, "CN=Root CA", descs[0].Issuer)
+		assert.Equal(t, "2", descs[0].Serial)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		descs, err := Inspect(keyFile)
+		assert.NoError(t, err)
+		assert.Len(t, descs, 1)
+		assert.Equal(t, DescTypeKey, descs[0].Type)
+		assert.Equal(t, "RSA 1024 bits", descs[0].PublicKey)
+
+		// The public key and private key have the same fingerprints
+		pubDescs, err := Inspect(pubFile)
+		assert.NoError(t, err)
+		assert.Len(t, pubDescs, 1)
+		assert.Equal(t, DescTypePublicKey, pubDescs[0].Type)
+		assert.Equal(t, descs[0].SHA256Fingerprint, pubDescs[0].SHA256Fingerprint)
+		assert.Equal(t, descs[0].SubjectKeyID, pubDescs[0].SubjectKeyID)
+
+		descs, err = Inspect(encKeyFile)
+		assert.NoError(t, err)
+		assert.Equal(t, []Description{{Type: DescTypeEncryptedKey}}, descs)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		for _, file := range []string{path.Join(dir, "missing.pem"), invalidFile, unsupportedFile, emptyFile} {
+			descs, err := Inspect(file)
+			assert.Error(t, err)
+			assert.Nil(t, descs)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package pki
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// StatusPending is the status of a certificate signing request which is not signed yet
+	StatusPending = "pending"
+)
+
+var (
+	listTypes = []int{CertTypeRoot, CertTypeInterm, CertTypeServer, CertTypeClient}
+)
+
+func ipStrings(ips []net.IP) []string {
+	strs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		strs = append(strs, ip.String())
+	}
+
+	return strs
+}
+
+func uriStrings(uris []*url.URL) []string {
+	strs := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		strs = append(strs, uri.String())
+	}
+
+	return strs
+}
+
+func subjectAltNames(dnsNames []string, ips []string, emails []string, uris []string) []string {
+	sans := make([]string, 0, len(dnsNames)+len(ips)+len(emails)+len(uris))
+	sans = append(sans, dnsNames...)
+	sans = append(sans, ips...)
+	sans = append(sans, emails...)
+	sans = append(sans, uris...)
+
+	return sans
+}
+
+func summarizeCert(c Cert, index *Index, now time.Time) (CertSummary, error) {
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return CertSummary{}, err
+	}
+
+	summary := CertSummary{
+		Name:     c.Name,
+		Type:     c.TypeName(),
+		Issuer:   cert.Issuer.CommonName,
+		Serial:   cert.SerialNumber.String(),
+		NotAfter: cert.NotAfter.UTC(),
+		Status:   StatusValid,
+	}
+
+	// Certificates issued by older versions are not indexed
+	entries := index.Query(IndexFilter{Name: c.Name, Type: c.Type}, now)
+	for _, e := range entries {
+		if e.Serial == summary.Serial {
+			summary.Issuer = e.Issuer
+			summary.Status = e.StatusAt(now)
+			summary.SANs = subjectAltNames(e.DNSNames, e.IPAddresses, e.EmailAddresses, e.URIs)
+			return summary, nil
+		}
+	}
+
+	if now.After(cert.NotAfter) {
+		summary.Status = StatusExpired
+	}
+
+	summary.SANs = subjectAltNames(cert.DNSNames, ipStrings(cert.IPAddresses), cert.EmailAddresses, uriStrings(cert.URIs))
+
+	return summary, nil
+}
+
+func summarizeCSR(c Cert) (CertSummary, error) {
+	csr, err := readCertificateRequest(c.CSRPath())
+	if err != nil {
+		return CertSummary{}, err
+	}
+
+	return CertSummary{
+		Name:     c.Name,
+		Type:     c.TypeName(),
+		SANs:     subjectAltNames(csr.DNSNames, ipStrings(csr.IPAddresses), csr.EmailAddresses, uriStrings(csr.URIs)),
+		Status:   StatusPending,
+		External: isExternal(c),
+	}, nil
+}
+
+// ListWorkspace returns summaries of all certificates and pending certificate signing requests in the current workspace
+func ListWorkspace(now time.Time) ([]CertSummary, error) {
+	index, err := LoadIndex(FileIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]CertSummary, 0)
+
+	for _, certType := range listTypes {
+		certs, err := ListCerts(certType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range certs {
+			summary, err := summarizeCert(c, index, now)
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+
+	for _, certType := range listTypes[1:] {
+		csrs, err := ListCSRs(certType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range csrs {
+			summary, err := summarizeCSR(c)
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+
+	return summaries, nil
+}
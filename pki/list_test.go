@@ -0,0 +1,117 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListWorkspace(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+	configClient := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 40}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cClient := Cert{Name: "service", Type: CertTypeClient}
+	cPending := Cert{Name: "pending", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp", DNSName: []string{"example.com"}, IPAddress: []net.IP{net.ParseIP("127.0.0.1")}}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configClient, Claim{CommonName: "service", EmailAddress: []string{"service@example.com"}}, cClient))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cClient, Usage{}, trust))
+	assert.NoError(t, manager.RevokeCert(cInterm, cClient, "superseded"))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "pending", DNSName: []string{"pending.example.com"}}, cPending))
+
+	t.Run("Now", func(t *testing.T) {
+		summaries, err := ListWorkspace(time.Now())
+		assert.NoError(t, err)
+		assert.Len(t, summaries, 5)
+
+		assert.Equal(t, "root", summaries[0].Name)
+		assert.Equal(t, "root", summaries[0].Type)
+		assert.Equal(t, "root", summaries[0].Issuer)
+		assert.Equal(t, "11", summaries[0].Serial)
+		assert.Equal(t, StatusValid, summaries[0].Status)
+
+		assert.Equal(t, "sre", summaries[1].Name)
+		assert.Equal(t, "intermediate", summaries[1].Type)
+		assert.Equal(t, "root", summaries[1].Issuer)
+		assert.Equal(t, "101", summaries[1].Serial)
+
+		assert.Equal(t, "webapp", summaries[2].Name)
+		assert.Equal(t, "server", summaries[2].Type)
+		assert.Equal(t, "sre", summaries[2].Issuer)
+		assert.Equal(t, "1001", summaries[2].Serial)
+		assert.Equal(t, []string{"example.com", "127.0.0.1"}, summaries[2].SANs)
+		assert.Equal(t, StatusValid, summaries[2].Status)
+		assert.False(t, summaries[2].NotAfter.IsZero())
+
+		assert.Equal(t, "service", summaries[3].Name)
+		assert.Equal(t, "client", summaries[3].Type)
+		assert.Equal(t, []string{"service@example.com"}, summaries[3].SANs)
+		assert.Equal(t, StatusRevoked, summaries[3].Status)
+
+		assert.Equal(t, CertSummary{
+			Name:   "pending",
+			Type:   "server",
+			SANs:   []string{"pending.example.com"},
+			Status: StatusPending,
+		}, summaries[4])
+	})
+
+	t.Run("Later", func(t *testing.T) {
+		summaries, err := ListWorkspace(time.Now().AddDate(2, 0, 0))
+		assert.NoError(t, err)
+		assert.Len(t, summaries, 5)
+		assert.Equal(t, StatusValid, summaries[1].Status)
+		assert.Equal(t, StatusExpired, summaries[2].Status)
+		assert.Equal(t, StatusRevoked, summaries[3].Status)
+	})
+
+	t.Run("NotIndexed", func(t *testing.T) {
+		assert.NoError(t, SaveIndex(&Index{}, FileIndex))
+
+		summaries, err := ListWorkspace(time.Now())
+		assert.NoError(t, err)
+		assert.Len(t, summaries, 5)
+		assert.Equal(t, "SRE CA", summaries[2].Issuer)
+		assert.Equal(t, []string{"example.com", "127.0.0.1"}, summaries[2].SANs)
+		assert.Equal(t, StatusValid, summaries[3].Status)
+	})
+
+	t.Run("External", func(t *testing.T) {
+		writeExternalCSR(t, Cert{Name: "agent", Type: CertTypeClient}, "ClientAuth")
+
+		summaries, err := ListWorkspace(time.Now())
+		assert.NoError(t, err)
+		assert.Len(t, summaries, 6)
+		assert.False(t, summaries[4].External)
+		assert.Equal(t, CertSummary{
+			Name:     "agent",
+			Type:     "client",
+			SANs:     []string{},
+			Status:   StatusPending,
+			External: true,
+		}, summaries[5])
+	})
+}
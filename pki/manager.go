@@ -5,11 +5,13 @@
 package pki
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"errors"
 	"math/big"
+	"net/http"
 	"path/filepath"
 	"time"
 )
@@ -19,8 +21,19 @@ type (
 	Manager interface {
 		GenCert(Config, Claim, Cert) error
 		GenCSR(Config, Claim, Cert) error
-		SignCSR(Config, Cert, Config, Cert, TrustFunc) error
+		SignCSR(Config, Cert, Config, Cert, Usage, TrustFunc) error
+		CheckCSR(Cert, Config, Cert, TrustFunc) ([]Violation, error)
 		VerifyCert(Cert, Cert, string) error
+		ReencryptKey(Config, Cert) error
+		RevokeCert(Cert, Cert, string) error
+		GenCRL(Config, Cert, int) error
+		OCSPHandler(Config, Cert, Cert) (http.Handler, error)
+		RenewCert(Config, Cert, Config, Cert, bool, TrustFunc) error
+		ReissueCert(Config, Cert, Config, Cert, Claim, bool, TrustFunc) error
+		ExportPKCS12(Config, Cert, string, bool) ([]byte, error)
+		ExportJKS(Config, Cert, string, string) ([]byte, error)
+		ExportSecret(Config, Cert, SecretMeta) ([]byte, error)
+		ImportCA(Config, Cert, ImportSource) error
 	}
 
 	// x509Manager provides methods for managing x509 certificates
@@ -39,56 +52,74 @@ func checkName(name string) error {
 
 	pattern := "./*/" + name + ".*"
 	files, _ := filepath.Glob(pattern) // Glob ignores file system errors
-	if len(files) > 0 {
-		return errors.New(name + " already exists")
+	for _, file := range files {
+		// Revocation lists, status stores, and archives outlive the certificates
+		if dir := filepath.Dir(file); dir != DirCRL && dir != DirArchive {
+			return errors.New(name + " already exists")
+		}
 	}
 
 	return nil
 }
 
-// GenCert generates a new certificate
-func (m *x509Manager) GenCert(config Config, claim Claim, c Cert) error {
-	if err := checkName(c.Name); err != nil {
-		return err
+// newCertificateRequest declares a certificate request template for a claim
+func newCertificateRequest(claim Claim) (*x509.CertificateRequest, error) {
+	uris, err := parseURIs(claim.URI)
+	if err != nil {
+		return nil, err
 	}
 
-	config.Serial++
-	length := config.Length
+	return &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         claim.CommonName,
+			Country:            claim.Country,
+			Province:           claim.Province,
+			Locality:           claim.Locality,
+			Organization:       claim.Organization,
+			OrganizationalUnit: claim.OrganizationalUnit,
+			StreetAddress:      claim.StreetAddress,
+			PostalCode:         claim.PostalCode,
+		},
+
+		DNSNames:       claim.DNSName,
+		IPAddresses:    claim.IPAddress,
+		EmailAddresses: claim.EmailAddress,
+		URIs:           uris,
+
+		// Extensions:      []pkix.Extension{},
+		// ExtraExtensions: []pkix.Extension{},
+	}, nil
+}
+
+// selfSign creates a new self-signed certificate authority for a request template and a key
+func selfSign(config Config, c Cert, req *x509.CertificateRequest, privateKey crypto.Signer) ([]byte, error) {
 	startTime := time.Now()
 	endTime := startTime.AddDate(0, 0, config.Days)
 
-	// Generate a new public-private key pair
-	publicKey, privateKey, err := genKeyPair(length)
+	subjectKeyID, err := computeSubjectKeyID(privateKey.Public())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	subjectKeyID, err := computeSubjectKeyID(publicKey)
+	// A self-signed certificate is its own issuer
+	serial, err := nextSerial(c, c.Type)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Declare certificate template
 	cert := &x509.Certificate{
-		SerialNumber: big.NewInt(config.Serial),
+		SerialNumber: serial,
 
 		NotBefore: startTime,
 		NotAfter:  endTime,
 
-		Subject: pkix.Name{
-			CommonName:         claim.CommonName,
-			Country:            claim.Country,
-			Province:           claim.Province,
-			Locality:           claim.Locality,
-			Organization:       claim.Organization,
-			OrganizationalUnit: claim.OrganizationalUnit,
-			StreetAddress:      claim.StreetAddress,
-			PostalCode:         claim.PostalCode,
-		},
+		Subject: req.Subject,
 
-		DNSNames:       claim.DNSName,
-		IPAddresses:    claim.IPAddress,
-		EmailAddresses: claim.EmailAddress,
+		DNSNames:       req.DNSNames,
+		IPAddresses:    req.IPAddresses,
+		EmailAddresses: req.EmailAddresses,
+		URIs:           req.URIs,
 
 		BasicConstraintsValid: true,
 		IsCA:                  true,
@@ -98,13 +129,47 @@ func (m *x509Manager) GenCert(config Config, claim Claim, c Cert) error {
 
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		ExtKeyUsage: []x509.ExtKeyUsage{},
-
-		// Extensions:      []pkix.Extension{},
-		// ExtraExtensions: []pkix.Extension{},
 	}
 
+	applyMaxPathLen(cert, config.maxPathLen(CertTypeRoot))
+
 	// Create the certificate
-	certData, err := x509.CreateCertificate(rand.Reader, cert, cert, publicKey, privateKey)
+	return x509.CreateCertificate(rand.Reader, cert, cert, privateKey.Public(), privateKey)
+}
+
+// recordCert records a new certificate in status store of its certificate authority and issuance index
+func recordCert(c, cCA Cert, certData []byte) error {
+	cert, err := x509.ParseCertificate(certData)
+	if err != nil {
+		return err
+	}
+
+	err = recordIssued(cCA, c.Name, cert.SerialNumber)
+	if err != nil {
+		return err
+	}
+
+	return recordIndexed(c, cCA, cert)
+}
+
+// GenCert generates a new certificate
+func (m *x509Manager) GenCert(config Config, claim Claim, c Cert) error {
+	if err := checkName(c.Name); err != nil {
+		return err
+	}
+
+	req, err := newCertificateRequest(claim)
+	if err != nil {
+		return err
+	}
+
+	// Generate a new public-private key pair
+	_, privateKey, err := genKeyPair(config.Algorithm, config.Length)
+	if err != nil {
+		return err
+	}
+
+	certData, err := selfSign(config, c, req, privateKey)
 	if err != nil {
 		return err
 	}
@@ -121,7 +186,8 @@ func (m *x509Manager) GenCert(config Config, claim Claim, c Cert) error {
 		return err
 	}
 
-	return nil
+	// A self-signed certificate is recorded in its own status store
+	return recordCert(c, c, certData)
 }
 
 // GenCSR generates a certificate signing request
@@ -130,33 +196,16 @@ func (m *x509Manager) GenCSR(config Config, claim Claim, c Cert) error {
 		return err
 	}
 
-	length := config.Length
-
-	// Generate a new public-private key pair
-	_, privateKey, err := genKeyPair(length)
+	// Declare certificate request
+	intermCSR, err := newCertificateRequest(claim)
 	if err != nil {
 		return err
 	}
 
-	// Declare certificate request
-	intermCSR := &x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName:         claim.CommonName,
-			Country:            claim.Country,
-			Province:           claim.Province,
-			Locality:           claim.Locality,
-			Organization:       claim.Organization,
-			OrganizationalUnit: claim.OrganizationalUnit,
-			StreetAddress:      claim.StreetAddress,
-			PostalCode:         claim.PostalCode,
-		},
-
-		DNSNames:       claim.DNSName,
-		IPAddresses:    claim.IPAddress,
-		EmailAddresses: claim.EmailAddress,
-
-		// Extensions:      []pkix.Extension{},
-		// ExtraExtensions: []pkix.Extension{},
+	// Generate a new public-private key pair
+	_, privateKey, err := genKeyPair(config.Algorithm, config.Length)
+	if err != nil {
+		return err
 	}
 
 	// Create the certificate request
@@ -181,7 +230,7 @@ func (m *x509Manager) GenCSR(config Config, claim Claim, c Cert) error {
 }
 
 // SignCSR signs a certificate signing request using a certificate authority
-func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR Cert, trust TrustFunc) error {
+func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR Cert, usage Usage, trust TrustFunc) error {
 	keyCA, err := readPrivateKey(configCA.Password, cCA.KeyPath())
 	if err != nil {
 		return err
@@ -198,8 +247,27 @@ func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR
 	}
 
 	// Check if the certificate authority can trust and sign the certificate request
-	if !trust(certCA, csr) {
-		return errors.New("CSR does not satisfy CA trust policy")
+	if violations := trust(certCA, csr, configCSR.Days); len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	// Certificate authorities in the chain only issue certificates for names within their constraints
+	chainCA, err := readCertificateChain(cCA.ChainPath())
+	if err != nil {
+		return err
+	}
+
+	err = checkNameConstraints(chainCA, csr)
+	if err != nil {
+		return err
+	}
+
+	// Certificate authorities in SPIFFE mode only issue client certificates for workloads in their trust domains
+	if cCSR.Type == CertTypeClient && usage.SPIFFETrustDomain != "" {
+		err = checkSPIFFEID(usage.SPIFFETrustDomain, csr.URIs)
+		if err != nil {
+			return err
+		}
 	}
 
 	subjectKeyID, err := computeSubjectKeyID(csr.PublicKey)
@@ -207,19 +275,19 @@ func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR
 		return err
 	}
 
-	configCSR.Serial++
+	serial, err := nextSerial(cCA, cCSR.Type)
+	if err != nil {
+		return err
+	}
+
 	startTime := time.Now()
 	endTime := startTime.AddDate(0, 0, configCSR.Days)
 
 	// Declare certificate template
+	// The signature algorithm is determined by the key of certificate authority and not the request,
+	// so a certificate authority can sign a request with a different key algorithm.
 	cert := &x509.Certificate{
-		Signature:          csr.Signature,
-		SignatureAlgorithm: csr.SignatureAlgorithm,
-
-		PublicKey:          csr.PublicKey,
-		PublicKeyAlgorithm: csr.PublicKeyAlgorithm,
-
-		SerialNumber: big.NewInt(configCSR.Serial),
+		SerialNumber: serial,
 
 		NotBefore: startTime,
 		NotAfter:  endTime,
@@ -230,32 +298,45 @@ func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR
 		DNSNames:       csr.DNSNames,
 		IPAddresses:    csr.IPAddresses,
 		EmailAddresses: csr.EmailAddresses,
+		URIs:           csr.URIs,
 
 		SubjectKeyId:   subjectKeyID,
 		AuthorityKeyId: certCA.SubjectKeyId,
 	}
 
-	switch cCSR.Type {
-	case CertTypeInterm:
+	if cCSR.Type == CertTypeInterm {
 		cert.BasicConstraintsValid = true
 		cert.IsCA = true
-		cert.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
-		cert.ExtKeyUsage = []x509.ExtKeyUsage{}
-	case CertTypeServer:
-		cert.BasicConstraintsValid = false
-		cert.IsCA = false
-		cert.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageContentCommitment
-		cert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
-	case CertTypeClient:
-		cert.BasicConstraintsValid = false
-		cert.IsCA = false
-		cert.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageContentCommitment
-		cert.ExtKeyUsage = []x509.ExtKeyUsage{}
-		/* READ:
-		 * https://go-review.googlesource.com/c/go/+/10806
-		 * https://github.com/golang/go/issues/7423
-		 * https://github.com/golang/go/issues/11087
-		 */
+
+		// Path length of a new intermediate is limited by every certificate authority in the chain
+		remaining := remainingPathLen(chainCA)
+		if remaining == 0 {
+			return errors.New(cCA.Name + " cannot sign intermediate certificate authorities due to its path length constraint")
+		}
+
+		maxPathLen := configCSR.maxPathLen(CertTypeInterm)
+		if remaining > 0 && (maxPathLen < 0 || maxPathLen >= remaining) {
+			maxPathLen = remaining - 1
+		}
+
+		applyMaxPathLen(cert, maxPathLen)
+	}
+
+	err = usage.apply(cert, cCSR.Type, csr.PublicKeyAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	if cCSR.Type == CertTypeInterm {
+		err = usage.NameConstraints.apply(cert)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = usage.Distribution.apply(cert)
+	if err != nil {
+		return err
 	}
 
 	// Create the certificate
@@ -270,15 +351,35 @@ func (m *x509Manager) SignCSR(configCA Config, cCA Cert, configCSR Config, cCSR
 		return err
 	}
 
-	// Write certificate chain
+	// Write certificate chain for intermediates and full chain for leaves
 	if cCSR.Type == CertTypeInterm {
 		err = writeCertificateChain(cCSR, cCA)
-		if err != nil {
-			return err
-		}
+	} else {
+		err = writeFullchain(cCSR, cCA)
 	}
 
-	return nil
+	if err != nil {
+		return err
+	}
+
+	// Record the new certificate in status store of certificate authority and issuance index
+	return recordCert(cCSR, cCA, certData)
+}
+
+// CheckCSR evaluates a certificate signing request against trust policy of a certificate authority without signing it
+// The password of certificate authority is not needed, since its key is not used.
+func (m *x509Manager) CheckCSR(cCA Cert, configCSR Config, cCSR Cert, trust TrustFunc) ([]Violation, error) {
+	certCA, err := readCertificate(cCA.CertPath())
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := readCertificateRequest(cCSR.CSRPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return trust(certCA, csr, configCSR.Days), nil
 }
 
 // VerifyCert verifies a certificate using a ceritifcate authority
@@ -297,6 +398,11 @@ func (m *x509Manager) VerifyCert(cCA, c Cert, dnsName string) error {
 		return err
 	}
 
+	err = checkPathLen(cert, chain)
+	if err != nil {
+		return err
+	}
+
 	roots := x509.NewCertPool()
 	interms := x509.NewCertPool()
 	for i, cert := range chain {
@@ -311,6 +417,7 @@ func (m *x509Manager) VerifyCert(cCA, c Cert, dnsName string) error {
 		Roots:         roots,
 		Intermediates: interms,
 		DNSName:       dnsName,
+		KeyUsages:     verifyKeyUsages(cert),
 	}
 
 	_, err = cert.Verify(opts)
@@ -320,3 +427,178 @@ func (m *x509Manager) VerifyCert(cCA, c Cert, dnsName string) error {
 
 	return nil
 }
+
+// verifyKeyUsages returns the extended key usages a certificate is verified for
+// A certificate is verified for the extended key usages it is issued for (configured per type in spec),
+// so its chain must allow them. Arbitrary extended key usages cannot be verified and any usage is accepted for them.
+func verifyKeyUsages(cert *x509.Certificate) []x509.ExtKeyUsage {
+	if len(cert.ExtKeyUsage) == 0 {
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	return cert.ExtKeyUsage
+}
+
+// ReencryptKey reads the private key of a certificate and writes it back in place using the current encryption scheme
+func (m *x509Manager) ReencryptKey(config Config, c Cert) error {
+	key, err := readPrivateKey(config.Password, c.KeyPath())
+	if err != nil {
+		return err
+	}
+
+	return writePrivateKey(key, config.Password, c.KeyPath())
+}
+
+// RevokeCert records the revocation of a certificate issued by a certificate authority
+func (m *x509Manager) RevokeCert(cCA, c Cert, reason string) error {
+	if cCA.Type != CertTypeRoot && cCA.Type != CertTypeInterm {
+		return errors.New("certificate authority is invalid")
+	}
+
+	if _, err := reasonCode(reason); err != nil {
+		return err
+	}
+
+	certCA, err := readCertificate(cCA.CertPath())
+	if err != nil {
+		return err
+	}
+
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return err
+	}
+
+	// Only the issuer of a certificate can revoke it
+	err = cert.CheckSignatureFrom(certCA)
+	if err != nil {
+		return errors.New(c.Name + " is not issued by " + cCA.Name)
+	}
+
+	if reason == "" {
+		reason = ReasonUnspecified
+	}
+
+	revokedAt := time.Now().UTC().Truncate(time.Second)
+	err = updateStatusStore(cCA, func(store *StatusStore) error {
+		return store.revoke(c.Name, cert.SerialNumber, reason, revokedAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	return revokeIndexed(c, cCA, cert, reason, revokedAt)
+}
+
+// GenCRL generates a new certificate revocation list for a certificate authority valid for a number of days
+func (m *x509Manager) GenCRL(configCA Config, cCA Cert, days int) error {
+	if cCA.Type != CertTypeRoot && cCA.Type != CertTypeInterm {
+		return errors.New("certificate authority is invalid")
+	}
+
+	if days <= 0 {
+		return errors.New("days should be a positive number")
+	}
+
+	keyCA, err := readPrivateKey(configCA.Password, cCA.KeyPath())
+	if err != nil {
+		return err
+	}
+
+	certCA, err := readCertificate(cCA.CertPath())
+	if err != nil {
+		return err
+	}
+
+	// The last CRL number is persisted, so CRL numbers are never reused
+	var crlData []byte
+	err = updateStatusStore(cCA, func(store *StatusStore) error {
+		entries, err := revocationEntries(store)
+		if err != nil {
+			return err
+		}
+
+		store.CRLNumber++
+		startTime := time.Now()
+		endTime := startTime.AddDate(0, 0, days)
+
+		// Declare certificate revocation list template
+		crl := &x509.RevocationList{
+			Number:                    big.NewInt(store.CRLNumber),
+			ThisUpdate:                startTime,
+			NextUpdate:                endTime,
+			RevokedCertificateEntries: entries,
+		}
+
+		// Create the certificate revocation list
+		crlData, err = x509.CreateRevocationList(rand.Reader, crl, certCA, keyCA)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write certificate revocation list file
+	err = writePemFile(pemTypeCRL, crlData, cCA.CRLPath())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// OCSPHandler creates an http handler responding to OCSP requests for certificates issued by a certificate authority.
+// If the signer certificate is not set, responses are signed by the certificate authority itself.
+// Otherwise, responses are signed by the delegated signer certificate which should be issued by the certificate authority for OCSP signing.
+func (m *x509Manager) OCSPHandler(configCA Config, cCA, cSigner Cert) (http.Handler, error) {
+	if cCA.Type != CertTypeRoot && cCA.Type != CertTypeInterm {
+		return nil, errors.New("certificate authority is invalid")
+	}
+
+	certCA, err := readCertificate(cCA.CertPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if cSigner.Name == "" {
+		keyCA, err := readPrivateKey(configCA.Password, cCA.KeyPath())
+		if err != nil {
+			return nil, err
+		}
+
+		return newOCSPResponder(cCA, certCA, nil, keyCA)
+	}
+
+	if cSigner.Type != CertTypeServer && cSigner.Type != CertTypeClient {
+		return nil, errors.New("signer certificate is invalid")
+	}
+
+	signerCert, err := readCertificate(cSigner.CertPath())
+	if err != nil {
+		return nil, err
+	}
+
+	// A delegated signer should be issued by the certificate authority for OCSP signing
+	err = signerCert.CheckSignatureFrom(certCA)
+	if err != nil {
+		return nil, errors.New(cSigner.Name + " is not issued by " + cCA.Name)
+	}
+
+	ocspSigning := false
+	for _, usage := range signerCert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			ocspSigning = true
+		}
+	}
+
+	if !ocspSigning {
+		return nil, errors.New(cSigner.Name + " is not authorized for OCSP signing")
+	}
+
+	signerKey, err := readPrivateKey("", cSigner.KeyPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return newOCSPResponder(cCA, certCA, signerCert, signerKey)
+}
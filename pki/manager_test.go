@@ -2,8 +2,10 @@ package pki
 
 import (
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"math/big"
 	"os"
 	"path"
@@ -46,7 +48,7 @@ func mockWorkspaceWithChains(t *testing.T) {
 
 	// Mock root CA
 	rootCertFile := path.Join(DirRoot, "root"+extCACert)
-	pub, priv, err := genKeyPair(testKeyLen)
+	pub, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	rootCA := &x509.Certificate{
 		SerialNumber: big.NewInt(10),
@@ -64,7 +66,7 @@ func mockWorkspaceWithChains(t *testing.T) {
 	// Mock first-level intermediate CA
 	sreCertFile := path.Join(DirInterm, "sre"+extCACert)
 	sreChainFile := path.Join(DirInterm, "sre"+extCAChain)
-	pub, priv, err = genKeyPair(testKeyLen)
+	pub, priv, err = genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	sreCA := &x509.Certificate{
 		SerialNumber: big.NewInt(100),
@@ -84,7 +86,7 @@ func mockWorkspaceWithChains(t *testing.T) {
 	// Mock second-level intermediate CA
 	rdCertFile := path.Join(DirInterm, "rd"+extCACert)
 	rdChainFile := path.Join(DirInterm, "rd"+extCAChain)
-	pub, priv, err = genKeyPair(testKeyLen)
+	pub, priv, err = genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	rdCA := &x509.Certificate{
 		SerialNumber: big.NewInt(200),
@@ -139,6 +141,32 @@ func TestGenCertError(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"RootInvalidAlgorithm",
+			Config{
+				Algorithm: "dsa",
+				Length:    1024,
+			},
+			Claim{},
+			Cert{
+				Name: "root",
+				Type: CertTypeRoot,
+			},
+			false,
+		},
+		{
+			"RootInvalidECDSALength",
+			Config{
+				Algorithm: AlgorithmECDSA,
+				Length:    1024,
+			},
+			Claim{},
+			Cert{
+				Name: "root",
+				Type: CertTypeRoot,
+			},
+			false,
+		},
 	}
 
 	err := NewWorkspace(NewState(), NewSpec())
@@ -338,8 +366,8 @@ func TestSignCSRError(t *testing.T) {
 				Name: "interm",
 				Type: CertTypeInterm,
 			},
-			func(*x509.Certificate, *x509.CertificateRequest) bool {
-				return false
+			func(*x509.Certificate, *x509.CertificateRequest, int) []Violation {
+				return []Violation{{Field: "CommonName", Expected: "Root CA", Actual: "none", Rule: "match"}}
 			},
 		},
 	}
@@ -365,7 +393,7 @@ func TestSignCSRError(t *testing.T) {
 				files = append(files, test.cCSR.KeyPath(), test.cCSR.CSRPath(), test.cCSR.CertPath())
 			}
 
-			err := manager.SignCSR(test.configCA, test.cCA, test.configCSR, test.cCSR, test.trust)
+			err := manager.SignCSR(test.configCA, test.cCA, test.configCSR, test.cCSR, Usage{}, test.trust)
 			assert.Error(t, err)
 
 			err = util.DeleteAll("", files...)
@@ -374,6 +402,59 @@ func TestSignCSRError(t *testing.T) {
 	}
 }
 
+func TestCheckCSR(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp", DNSName: []string{"webapp.example.com"}}, cServer))
+
+	policy := Policy{DNSNames: []string{"*.sre.example.com"}, MaxDays: 90}
+	expectedViolations := []Violation{
+		{Field: "DNSNames", Expected: "*.sre.example.com", Actual: "webapp.example.com", Rule: "dns_names"},
+		{Field: "Days", Expected: "at most 90", Actual: "375", Rule: "max_days"},
+	}
+
+	// The password of certificate authority is not needed for checking
+	violations, err := manager.CheckCSR(cInterm, configServer, cServer, PolicyTrustFunc(policy, CertTypeServer))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedViolations, violations)
+
+	violations, err = manager.CheckCSR(cInterm, configServer, cServer, PolicyTrustFunc(Policy{}, CertTypeServer))
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	_, err = manager.CheckCSR(Cert{Name: "ops", Type: CertTypeInterm}, configServer, cServer, trust)
+	assert.Error(t, err)
+
+	_, err = manager.CheckCSR(cInterm, configServer, Cert{Name: "api", Type: CertTypeServer}, trust)
+	assert.Error(t, err)
+
+	// Signing fails with the same violations
+	err = manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, PolicyTrustFunc(policy, CertTypeServer))
+	policyErr, ok := err.(*PolicyError)
+	assert.True(t, ok)
+	assert.Equal(t, expectedViolations, policyErr.Violations)
+	assert.NoFileExists(t, cServer.CertPath())
+}
+
 func TestVerifyCertError(t *testing.T) {
 	tests := []struct {
 		title   string
@@ -426,6 +507,234 @@ func TestVerifyCertError(t *testing.T) {
 	}
 }
 
+func TestVerifyCertKeyUsage(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configCA := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "secret"}
+	config := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cWeb := Cert{Name: "web", Type: CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(configCA, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configCA, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configCA, cRoot, configCA, cInterm, Usage{}, trust))
+
+	// Web intermediate is only allowed to issue certificates for server authentication
+	assert.NoError(t, manager.GenCSR(configCA, Claim{CommonName: "Web CA"}, cWeb))
+	assert.NoError(t, manager.SignCSR(configCA, cRoot, configCA, cWeb, Usage{ExtKeyUsage: []string{"ServerAuth"}}, trust))
+
+	tests := []struct {
+		name          string
+		cCA           Cert
+		c             Cert
+		usage         Usage
+		expectedError bool
+	}{
+		{"DefaultServer", cInterm, Cert{Name: "webapp", Type: CertTypeServer}, Usage{}, false},
+		{"DefaultClient", cInterm, Cert{Name: "service", Type: CertTypeClient}, Usage{}, false},
+		{"ServerForClientAuth", cInterm, Cert{Name: "proxy", Type: CertTypeServer}, Usage{ExtKeyUsage: []string{"ClientAuth"}}, false},
+		{"ClientForEmailProtection", cInterm, Cert{Name: "mail", Type: CertTypeClient}, Usage{ExtKeyUsage: []string{"EmailProtection"}}, false},
+		{"ClientForArbitraryOID", cInterm, Cert{Name: "custom", Type: CertTypeClient}, Usage{ExtKeyUsage: []string{"1.3.6.1.4.1.99999.1"}}, false},
+		{"ServerUnderRestrictedCA", cWeb, Cert{Name: "portal", Type: CertTypeServer}, Usage{}, false},
+		{"ClientUnderRestrictedCA", cWeb, Cert{Name: "agent", Type: CertTypeClient}, Usage{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NoError(t, manager.GenCSR(config, Claim{CommonName: test.c.Name}, test.c))
+			assert.NoError(t, manager.SignCSR(configCA, test.cCA, config, test.c, test.usage, trust))
+
+			err := manager.VerifyCert(test.cCA, test.c, "")
+			if test.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReencryptKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	c := Cert{Name: "root", Type: CertTypeRoot}
+	config := Config{Password: "secret"}
+
+	_, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
+	assert.NoError(t, err)
+
+	// Write the private key using the legacy PEM encryption
+	keyPem, err := x509.EncryptPEMBlock(rand.Reader, pemTypeRSAKey, x509.MarshalPKCS1PrivateKey(priv.(*rsa.PrivateKey)), []byte(config.Password), x509.PEMCipherAES256) //nolint:staticcheck // legacy encrypted keys
+	assert.NoError(t, err)
+	err = os.WriteFile(c.KeyPath(), pem.EncodeToMemory(keyPem), 0600)
+	assert.NoError(t, err)
+
+	manager := NewX509Manager()
+
+	err = manager.ReencryptKey(Config{Password: "different"}, c)
+	assert.Error(t, err)
+
+	err = manager.ReencryptKey(config, c)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(c.KeyPath())
+	assert.NoError(t, err)
+	block, _ := pem.Decode(data)
+	assert.Equal(t, pemTypeEncryptedKey, block.Type)
+
+	key, err := readPrivateKey(config.Password, c.KeyPath())
+	assert.NoError(t, err)
+	assert.Equal(t, priv, key)
+}
+
+func TestRevokeCertGenCRL(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Serial: 10, Length: 1024, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Serial: 100, Length: 1024, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Serial: 1000, Length: 1024, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	err = manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot)
+	assert.NoError(t, err)
+	err = manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm)
+	assert.NoError(t, err)
+	err = manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust)
+	assert.NoError(t, err)
+	err = manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer)
+	assert.NoError(t, err)
+	err = manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust)
+	assert.NoError(t, err)
+
+	t.Run("SerialNumbers", func(t *testing.T) {
+		cOther := Cert{Name: "webapp2", Type: CertTypeServer}
+		err := manager.GenCSR(configServer, Claim{CommonName: "webapp2"}, cOther)
+		assert.NoError(t, err)
+		err = manager.SignCSR(configInterm, cInterm, configServer, cOther, Usage{}, trust)
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		other, err := readCertificate(cOther.CertPath())
+		assert.NoError(t, err)
+
+		// Serial numbers are allocated from state file regardless of config
+		assert.Equal(t, big.NewInt(1001), cert.SerialNumber)
+		assert.Equal(t, big.NewInt(1002), other.SerialNumber)
+
+		state, err := LoadState(FileState)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), state.Root.Serial)
+		assert.Equal(t, int64(101), state.Interm.Serial)
+		assert.Equal(t, int64(1002), state.Server.Serial)
+
+		store, err := readStatusStore(cInterm.StatusPath())
+		assert.NoError(t, err)
+		assert.Equal(t, StatusGood, store.Lookup(other.SerialNumber).Status)
+
+		// Every issued certificate is recorded in issuance index
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+		assert.Len(t, index.Certificates, 4)
+		e, ok := index.Lookup("sre", other.SerialNumber)
+		assert.True(t, ok)
+		assert.Equal(t, "webapp2", e.Name)
+		assert.Equal(t, "server", e.Type)
+		assert.Equal(t, "CN=webapp2", e.Subject)
+		assert.Equal(t, StatusValid, e.Status)
+		assert.Equal(t, cOther.CertPath(), e.CertPath)
+		e, ok = index.Lookup("root", big.NewInt(11))
+		assert.True(t, ok)
+		assert.Equal(t, "root", e.Name)
+	})
+
+	t.Run("RevokeCertError", func(t *testing.T) {
+		assert.Error(t, manager.RevokeCert(cServer, cServer, ""))
+		assert.Error(t, manager.RevokeCert(cInterm, Cert{Name: "missing", Type: CertTypeServer}, ""))
+		assert.Error(t, manager.RevokeCert(cInterm, cServer, "invalid"))
+		assert.Error(t, manager.RevokeCert(cRoot, cServer, ""))
+	})
+
+	t.Run("GenCRLError", func(t *testing.T) {
+		assert.Error(t, manager.GenCRL(configServer, cServer, 7))
+		assert.Error(t, manager.GenCRL(configInterm, cInterm, 0))
+		assert.Error(t, manager.GenCRL(Config{Password: "different"}, cInterm, 7))
+	})
+
+	t.Run("EmptyCRL", func(t *testing.T) {
+		err := manager.GenCRL(configRoot, cRoot, 30)
+		assert.NoError(t, err)
+
+		crl, err := readCRL(cRoot.CRLPath())
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(1), crl.Number)
+		assert.Empty(t, crl.RevokedCertificateEntries)
+	})
+
+	t.Run("RevokeAndGenCRL", func(t *testing.T) {
+		err := manager.RevokeCert(cInterm, cServer, "keyCompromise")
+		assert.NoError(t, err)
+
+		// A certificate cannot be revoked twice
+		err = manager.RevokeCert(cInterm, cServer, "keyCompromise")
+		assert.Error(t, err)
+
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+		revoked := index.Query(IndexFilter{Status: StatusRevoked}, time.Now())
+		assert.Len(t, revoked, 1)
+		assert.Equal(t, "webapp", revoked[0].Name)
+		assert.Equal(t, "keyCompromise", revoked[0].Reason)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		certInterm, err := readCertificate(cInterm.CertPath())
+		assert.NoError(t, err)
+
+		for i := int64(1); i <= 2; i++ {
+			err = manager.GenCRL(configInterm, cInterm, 7)
+			assert.NoError(t, err)
+
+			crl, err := readCRL(cInterm.CRLPath())
+			assert.NoError(t, err)
+			assert.NoError(t, crl.CheckSignatureFrom(certInterm))
+			assert.Equal(t, big.NewInt(i), crl.Number)
+			assert.True(t, crl.NextUpdate.After(crl.ThisUpdate))
+			assert.Len(t, crl.RevokedCertificateEntries, 1)
+			assert.Equal(t, cert.SerialNumber, crl.RevokedCertificateEntries[0].SerialNumber)
+			assert.Equal(t, 1, crl.RevokedCertificateEntries[0].ReasonCode)
+		}
+	})
+}
+
 func TestX509Manager(t *testing.T) {
 	tests := []struct {
 		title     string
@@ -635,6 +944,144 @@ func TestX509Manager(t *testing.T) {
 			"milad.io",
 			"auth.milad.io",
 		},
+		{
+			"RSARootECDSAIntermediateServerEd25519Client",
+			&State{
+				Root: Config{
+					Serial:    10,
+					Algorithm: AlgorithmRSA,
+					Length:    1024,
+					Days:      7300,
+					Password:  "rootSecret",
+				},
+				Interm: Config{
+					Serial:    100,
+					Algorithm: AlgorithmECDSA,
+					Length:    384,
+					Days:      3650,
+					Password:  "intermSecret",
+				},
+				Server: Config{
+					Serial:    1000,
+					Algorithm: AlgorithmECDSA,
+					Length:    256,
+					Days:      375,
+				},
+				Client: Config{
+					Serial:    10000,
+					Algorithm: AlgorithmEd25519,
+					Days:      40,
+				},
+			},
+			&Spec{
+				Root: Claim{
+					CommonName:   "Milad Root CA",
+					Organization: []string{"Milad"},
+				},
+				Interm: Claim{
+					CommonName:   "Milad SRE CA",
+					Organization: []string{"Milad"},
+				},
+				Server: Claim{
+					CommonName:   "milad.io",
+					Organization: []string{"Milad"},
+					DNSName:      []string{"milad.io"},
+				},
+				Client: Claim{
+					CommonName:   "auth.service",
+					Organization: []string{"Milad"},
+					DNSName:      []string{"auth.milad.io"},
+				},
+				RootPolicy: Policy{
+					Match:    []string{"Organization"},
+					Supplied: []string{"CommonName"},
+				},
+				IntermPolicy: Policy{
+					Match:    []string{"Organization"},
+					Supplied: []string{"CommonName", "DNSName"},
+				},
+			},
+			Cert{
+				Name: "root",
+				Type: CertTypeRoot,
+			},
+			Cert{
+				Name: "ops",
+				Type: CertTypeInterm,
+			},
+			Cert{
+				Name: "milad.io",
+				Type: CertTypeServer,
+			},
+			Cert{
+				Name: "auth.service",
+				Type: CertTypeClient,
+			},
+			"milad.io",
+			"auth.milad.io",
+		},
+		{
+			"Ed25519RootRSAIntermediateServer",
+			&State{
+				Root: Config{
+					Serial:    10,
+					Algorithm: AlgorithmEd25519,
+					Days:      7300,
+					Password:  "rootSecret",
+				},
+				Interm: Config{
+					Serial:    100,
+					Algorithm: AlgorithmRSA,
+					Length:    1024,
+					Days:      3650,
+					Password:  "intermSecret",
+				},
+				Server: Config{
+					Serial:    1000,
+					Algorithm: AlgorithmRSA,
+					Length:    1024,
+					Days:      375,
+				},
+			},
+			&Spec{
+				Root: Claim{
+					CommonName:   "Milad Root CA",
+					Organization: []string{"Milad"},
+				},
+				Interm: Claim{
+					CommonName:   "Milad SRE CA",
+					Organization: []string{"Milad"},
+				},
+				Server: Claim{
+					CommonName:   "milad.io",
+					Organization: []string{"Milad"},
+					DNSName:      []string{"milad.io"},
+				},
+				RootPolicy: Policy{
+					Match:    []string{"Organization"},
+					Supplied: []string{"CommonName"},
+				},
+				IntermPolicy: Policy{
+					Match:    []string{"Organization"},
+					Supplied: []string{"CommonName", "DNSName"},
+				},
+			},
+			Cert{
+				Name: "root",
+				Type: CertTypeRoot,
+			},
+			Cert{
+				Name: "ops",
+				Type: CertTypeInterm,
+			},
+			Cert{
+				Name: "milad.io",
+				Type: CertTypeServer,
+			},
+			Cert{},
+			"milad.io",
+			"",
+		},
 	}
 
 	for _, test := range tests {
@@ -662,7 +1109,7 @@ func TestX509Manager(t *testing.T) {
 				err = manager.GenCSR(test.state.Interm, test.spec.Interm, test.cInterm)
 				assert.NoError(t, err)
 
-				err = manager.SignCSR(test.state.Root, test.cRoot, test.state.Interm, test.cInterm, PolicyTrustFunc(test.spec.RootPolicy))
+				err = manager.SignCSR(test.state.Root, test.cRoot, test.state.Interm, test.cInterm, test.spec.IntermUsage, PolicyTrustFunc(test.spec.RootPolicy, test.cInterm.Type))
 				assert.NoError(t, err)
 
 				parseKey(t, test.state.Interm.Password, test.cInterm.KeyPath())
@@ -679,7 +1126,7 @@ func TestX509Manager(t *testing.T) {
 				err = manager.GenCSR(test.state.Server, test.spec.Server, test.cServer)
 				assert.NoError(t, err)
 
-				err = manager.SignCSR(test.state.Interm, test.cInterm, test.state.Server, test.cServer, PolicyTrustFunc(test.spec.IntermPolicy))
+				err = manager.SignCSR(test.state.Interm, test.cInterm, test.state.Server, test.cServer, test.spec.ServerUsage, PolicyTrustFunc(test.spec.IntermPolicy, test.cServer.Type))
 				assert.NoError(t, err)
 
 				parseKey(t, "", test.cServer.KeyPath())
@@ -695,13 +1142,18 @@ func TestX509Manager(t *testing.T) {
 				err = manager.GenCSR(test.state.Client, test.spec.Client, test.cClient)
 				assert.NoError(t, err)
 
-				err = manager.SignCSR(test.state.Interm, test.cInterm, test.state.Client, test.cClient, PolicyTrustFunc(test.spec.IntermPolicy))
+				err = manager.SignCSR(test.state.Interm, test.cInterm, test.state.Client, test.cClient, test.spec.ClientUsage, PolicyTrustFunc(test.spec.IntermPolicy, test.cClient.Type))
 				assert.NoError(t, err)
 
 				parseKey(t, "", test.cClient.KeyPath())
 				parseCSR(t, test.cClient.CSRPath())
 				parseCert(t, test.cClient.CertPath())
 
+				// Client certificates are for client authentication by default
+				cert, err := readCertificate(test.cClient.CertPath())
+				assert.NoError(t, err)
+				assert.Contains(t, cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+
 				err = manager.VerifyCert(test.cInterm, test.cClient, test.dnsClient)
 				assert.NoError(t, err)
 			}
@@ -0,0 +1,327 @@
+package pki
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// ActionCreate is the action for a certificate in manifest which does not exist in workspace
+	ActionCreate = "create"
+	// ActionRenew is the action for a certificate in manifest which is expiring or revoked without being asked for
+	ActionRenew = "renew"
+	// ActionRevoke is the action for a certificate in manifest which is asked to be revoked
+	ActionRevoke = "revoke"
+	// ActionReissue is the action for a certificate in manifest whose subject or subject alternative names have changed
+	ActionReissue = "reissue"
+
+	defaultRenewBefore = 30
+)
+
+type (
+	// Manifest represents the type for a declarative description of certificates in a workspace
+	// Certificate authorities are listed before the certificates they issue.
+	// Certificates are renewed when they expire within RenewBefore days (default: 30).
+	Manifest struct {
+		RenewBefore int            `yaml:"renew_before,omitempty" toml:"renew_before,omitempty"`
+		Certs       []ManifestCert `yaml:"certs" toml:"cert"`
+	}
+
+	// ManifestCert represents the subtype for a certificate in manifest
+	// Claim fields not set are read from spec for the type of certificate, and common name defaults to name.
+	// Certificate authorities refer to their passwords by a file or an environment variable, so no secret is kept in manifest.
+	// A certificate is revoked when Revoke is set to a revocation reason (e.g. keyCompromise or unspecified).
+	ManifestCert struct {
+		Name               string   `yaml:"name" toml:"name"`
+		Type               string   `yaml:"type" toml:"type"`
+		CA                 string   `yaml:"ca,omitempty" toml:"ca,omitempty"`
+		CommonName         string   `yaml:"common_name,omitempty" toml:"common_name,omitempty"`
+		Organization       []string `yaml:"organization,omitempty" toml:"organization,omitempty"`
+		OrganizationalUnit []string `yaml:"organizational_unit,omitempty" toml:"organizational_unit,omitempty"`
+		DNSNames           []string `yaml:"dns_names,omitempty" toml:"dns_names,omitempty"`
+		IPAddresses        []string `yaml:"ip_addresses,omitempty" toml:"ip_addresses,omitempty"`
+		EmailAddresses     []string `yaml:"email_addresses,omitempty" toml:"email_addresses,omitempty"`
+		URIs               []string `yaml:"uris,omitempty" toml:"uris,omitempty"`
+		PasswordFile       string   `yaml:"password_file,omitempty" toml:"password_file,omitempty"`
+		PasswordEnv        string   `yaml:"password_env,omitempty" toml:"password_env,omitempty"`
+		Revoke             string   `yaml:"revoke,omitempty" toml:"revoke,omitempty"`
+	}
+
+	// Action represents the type for a change required to converge workspace to manifest
+	// Reason describes why the change is required, and Rekey is set when a revoked certificate is renewed or reissued.
+	Action struct {
+		Type   string
+		Cert   Cert
+		CA     Cert
+		Reason string
+		Rekey  bool
+	}
+)
+
+// LoadManifest reads and parses a manifest from a TOML file (.toml extension) or a YAML file
+func LoadManifest(file string) (*Manifest, error) {
+	manifest := new(Manifest)
+
+	if filepath.Ext(file) == ".toml" {
+		if _, err := toml.DecodeFile(file, manifest); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = yaml.Unmarshal(data, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Validate checks if certificates in manifest are well-formed and every issuer is listed before the certificates it issues
+func (m *Manifest) Validate() error {
+	if m.RenewBefore < 0 {
+		return errors.New("renew_before cannot be negative")
+	}
+
+	seen := map[string]int{}
+	for _, mc := range m.Certs {
+		if mc.Name == "" {
+			return errors.New("certificate name is not set")
+		}
+
+		if _, ok := seen[mc.Name]; ok {
+			return errors.New(mc.Name + " is listed more than once")
+		}
+
+		certType := ParseCertType(mc.Type)
+		if certType == 0 {
+			return fmt.Errorf("%s has invalid type %s", mc.Name, mc.Type)
+		}
+
+		if certType == CertTypeRoot {
+			if mc.CA != "" {
+				return errors.New(mc.Name + " is a root certificate authority and cannot have an issuer")
+			}
+		} else if caType, ok := seen[mc.CA]; !ok {
+			return fmt.Errorf("issuer of %s is not listed before it", mc.Name)
+		} else if caType != CertTypeRoot && caType != CertTypeInterm {
+			return fmt.Errorf("issuer of %s is not a certificate authority", mc.Name)
+		} else if caType == CertTypeRoot && certType != CertTypeInterm {
+			return fmt.Errorf("%s can only be issued by an intermediate certificate authority", mc.Name)
+		}
+
+		for _, ip := range mc.IPAddresses {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("%s has invalid IP address %s", mc.Name, ip)
+			}
+		}
+
+		if _, err := parseURIs(mc.URIs); err != nil {
+			return fmt.Errorf("%s has %s", mc.Name, err)
+		}
+
+		if mc.Revoke != "" {
+			if _, err := reasonCode(mc.Revoke); err != nil {
+				return fmt.Errorf("%s has %s", mc.Name, err)
+			}
+		}
+
+		seen[mc.Name] = certType
+	}
+
+	return nil
+}
+
+// Cert returns the certificate of a manifest entry
+func (mc ManifestCert) Cert() Cert {
+	return Cert{Name: mc.Name, Type: ParseCertType(mc.Type)}
+}
+
+// Claim returns the claim of a manifest entry with fields not set read from a claim in spec
+// Common name defaults to the name of manifest entry, so no certificate is issued without a subject.
+func (mc ManifestCert) Claim(spec Claim) Claim {
+	claim := spec.Clone()
+	claim.CommonName = mc.CommonName
+	if claim.CommonName == "" {
+		claim.CommonName = mc.Name
+	}
+
+	if len(mc.Organization) > 0 {
+		claim.Organization = mc.Organization
+	}
+	if len(mc.OrganizationalUnit) > 0 {
+		claim.OrganizationalUnit = mc.OrganizationalUnit
+	}
+	if len(mc.DNSNames) > 0 {
+		claim.DNSName = mc.DNSNames
+	}
+	if len(mc.IPAddresses) > 0 {
+		claim.IPAddress = nil
+		for _, ip := range mc.IPAddresses {
+			claim.IPAddress = append(claim.IPAddress, net.ParseIP(ip))
+		}
+	}
+	if len(mc.EmailAddresses) > 0 {
+		claim.EmailAddress = mc.EmailAddresses
+	}
+	if len(mc.URIs) > 0 {
+		claim.URI = mc.URIs
+	}
+
+	return claim
+}
+
+// sameValues determines if two lists have the same values regardless of their order
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Drift returns the keys of claim fields set in manifest which are different in an issued certificate
+// Claim fields not set in manifest are read from spec, so they are not compared.
+func (mc ManifestCert) Drift(cert *x509.Certificate) []string {
+	var ips []string
+	for _, ip := range mc.IPAddresses {
+		ips = append(ips, net.ParseIP(ip).String())
+	}
+
+	fields := []struct {
+		key      string
+		declared []string
+		issued   []string
+	}{
+		{"organization", mc.Organization, cert.Subject.Organization},
+		{"organizational_unit", mc.OrganizationalUnit, cert.Subject.OrganizationalUnit},
+		{"dns_names", mc.DNSNames, cert.DNSNames},
+		{"ip_addresses", ips, ipStrings(cert.IPAddresses)},
+		{"email_addresses", mc.EmailAddresses, cert.EmailAddresses},
+		{"uris", mc.URIs, uriStrings(cert.URIs)},
+	}
+
+	var keys []string
+	if mc.CommonName != "" && mc.CommonName != cert.Subject.CommonName {
+		keys = append(keys, "common_name")
+	}
+
+	for _, f := range fields {
+		if len(f.declared) > 0 && !sameValues(f.declared, f.issued) {
+			keys = append(keys, f.key)
+		}
+	}
+
+	return keys
+}
+
+// Find returns a certificate in manifest by its name
+func (m *Manifest) Find(name string) (ManifestCert, bool) {
+	for _, mc := range m.Certs {
+		if mc.Name == name {
+			return mc, true
+		}
+	}
+
+	return ManifestCert{}, false
+}
+
+// Plan compares manifest with current workspace at a point in time and returns the actions for converging workspace to manifest
+// Certificates in workspace not listed in manifest are left untouched, and a plan with no action means workspace is up to date.
+// A certificate authority reissued with a new subject is followed by renewing the certificates in manifest it has issued.
+func (m *Manifest) Plan(now time.Time) ([]Action, error) {
+	renewBefore := m.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = defaultRenewBefore
+	}
+
+	actions := []Action{}
+	certTypes := map[string]int{}
+	resubjected := map[string]bool{}
+
+	for _, mc := range m.Certs {
+		c := mc.Cert()
+		certTypes[c.Name] = c.Type
+
+		// Root certificate authority is its own issuer
+		cCA := c
+		if c.Type != CertTypeRoot {
+			cCA = Cert{Name: mc.CA, Type: certTypes[mc.CA]}
+		}
+
+		if _, err := os.Stat(c.CertPath()); os.IsNotExist(err) {
+			if mc.Revoke == "" {
+				actions = append(actions, Action{Type: ActionCreate, Cert: c, CA: cCA, Reason: "does not exist"})
+			}
+			continue
+		}
+
+		cert, err := readCertificate(c.CertPath())
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := readStatusStore(cCA.StatusPath())
+		if err != nil {
+			return nil, err
+		}
+
+		revoked := store.Lookup(cert.SerialNumber).Status == StatusRevoked
+		drift := mc.Drift(cert)
+		planned := len(actions)
+
+		switch {
+		case mc.Revoke != "" && !revoked:
+			actions = append(actions, Action{Type: ActionRevoke, Cert: c, CA: cCA, Reason: mc.Revoke})
+		case mc.Revoke == "" && len(drift) > 0:
+			reason := "has changed " + strings.Join(drift, ", ")
+			actions = append(actions, Action{Type: ActionReissue, Cert: c, CA: cCA, Reason: reason, Rekey: revoked})
+		case mc.Revoke == "" && revoked:
+			actions = append(actions, Action{Type: ActionRenew, Cert: c, CA: cCA, Reason: "is revoked", Rekey: true})
+		case mc.Revoke == "" && !now.AddDate(0, 0, renewBefore).Before(cert.NotAfter):
+			reason := fmt.Sprintf("expires on %s", cert.NotAfter.UTC().Format("2006-01-02"))
+			actions = append(actions, Action{Type: ActionRenew, Cert: c, CA: cCA, Reason: reason})
+		case mc.Revoke == "" && resubjected[mc.CA]:
+			actions = append(actions, Action{Type: ActionRenew, Cert: c, CA: cCA, Reason: "issuer " + mc.CA + " is reissued with a new subject"})
+		}
+
+		// Certificates issued by a certificate authority name it as their issuer
+		if len(actions) > planned && actions[planned].Type == ActionReissue && (c.Type == CertTypeRoot || c.Type == CertTypeInterm) {
+			for _, key := range drift {
+				if key == "common_name" || key == "organization" || key == "organizational_unit" {
+					resubjected[c.Name] = true
+				}
+			}
+		}
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,440 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	manifestYAML = `
+renew_before: 60
+certs:
+  - name: root
+    type: root
+    common_name: Root CA
+    password_file: /run/secrets/root
+  - name: sre
+    type: intermediate
+    ca: root
+    common_name: SRE CA
+    password_env: SRE_PASSWORD
+  - name: webapp
+    type: server
+    ca: sre
+    common_name: webapp.example.com
+    dns_names: [webapp.example.com, webapp]
+    ip_addresses: [127.0.0.1]
+`
+
+	manifestTOML = `
+renew_before = 60
+
+[[cert]]
+name = "root"
+type = "root"
+common_name = "Root CA"
+password_file = "/run/secrets/root"
+
+[[cert]]
+name = "sre"
+type = "intermediate"
+ca = "root"
+common_name = "SRE CA"
+password_env = "SRE_PASSWORD"
+
+[[cert]]
+name = "webapp"
+type = "server"
+ca = "sre"
+common_name = "webapp.example.com"
+dns_names = ["webapp.example.com", "webapp"]
+ip_addresses = ["127.0.0.1"]
+`
+)
+
+func TestLoadManifest(t *testing.T) {
+	expectedManifest := &Manifest{
+		RenewBefore: 60,
+		Certs: []ManifestCert{
+			{Name: "root", Type: "root", CommonName: "Root CA", PasswordFile: "/run/secrets/root"},
+			{Name: "sre", Type: "intermediate", CA: "root", CommonName: "SRE CA", PasswordEnv: "SRE_PASSWORD"},
+			{Name: "webapp", Type: "server", CA: "sre", CommonName: "webapp.example.com", DNSNames: []string{"webapp.example.com", "webapp"}, IPAddresses: []string{"127.0.0.1"}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		file             string
+		content          string
+		expectedManifest *Manifest
+		expectError      bool
+	}{
+		{"YAML", "manifest.yaml", manifestYAML, expectedManifest, false},
+		{"TOML", "manifest.toml", manifestTOML, expectedManifest, false},
+		{"InvalidYAML", "manifest.yaml", "certs: [", nil, true},
+		{"InvalidTOML", "manifest.toml", "[[cert]", nil, true},
+		{"InvalidManifest", "manifest.yaml", "certs:\n  - name: webapp\n    type: server\n", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), test.file)
+			assert.NoError(t, os.WriteFile(file, []byte(test.content), 0644))
+
+			manifest, err := LoadManifest(file)
+			if test.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, manifest)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedManifest, manifest)
+			}
+		})
+	}
+
+	t.Run("NoFile", func(t *testing.T) {
+		_, err := LoadManifest(filepath.Join(t.TempDir(), "manifest.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestManifestValidate(t *testing.T) {
+	root := ManifestCert{Name: "root", Type: "root"}
+	sre := ManifestCert{Name: "sre", Type: "intermediate", CA: "root"}
+
+	tests := []struct {
+		name          string
+		manifest      Manifest
+		expectedError string
+	}{
+		{
+			"Valid",
+			Manifest{Certs: []ManifestCert{root, sre, {Name: "webapp", Type: "server", CA: "sre", Revoke: "superseded"}}},
+			"",
+		},
+		{
+			"NegativeRenewBefore",
+			Manifest{RenewBefore: -1},
+			"renew_before cannot be negative",
+		},
+		{
+			"NoName",
+			Manifest{Certs: []ManifestCert{{Type: "root"}}},
+			"certificate name is not set",
+		},
+		{
+			"Duplicate",
+			Manifest{Certs: []ManifestCert{root, sre, sre}},
+			"sre is listed more than once",
+		},
+		{
+			"InvalidType",
+			Manifest{Certs: []ManifestCert{{Name: "root", Type: "authority"}}},
+			"root has invalid type authority",
+		},
+		{
+			"RootWithIssuer",
+			Manifest{Certs: []ManifestCert{{Name: "root", Type: "root", CA: "root"}}},
+			"root is a root certificate authority and cannot have an issuer",
+		},
+		{
+			"IssuerNotListed",
+			Manifest{Certs: []ManifestCert{root, {Name: "webapp", Type: "server", CA: "sre"}, sre}},
+			"issuer of webapp is not listed before it",
+		},
+		{
+			"IssuerNotCA",
+			Manifest{Certs: []ManifestCert{root, sre, {Name: "webapp", Type: "server", CA: "sre"}, {Name: "service", Type: "client", CA: "webapp"}}},
+			"issuer of service is not a certificate authority",
+		},
+		{
+			"RootIssuesServer",
+			Manifest{Certs: []ManifestCert{root, {Name: "webapp", Type: "server", CA: "root"}}},
+			"webapp can only be issued by an intermediate certificate authority",
+		},
+		{
+			"InvalidIPAddress",
+			Manifest{Certs: []ManifestCert{root, sre, {Name: "webapp", Type: "server", CA: "sre", IPAddresses: []string{"localhost"}}}},
+			"webapp has invalid IP address localhost",
+		},
+		{
+			"InvalidURI",
+			Manifest{Certs: []ManifestCert{root, sre, {Name: "service", Type: "client", CA: "sre", URIs: []string{"://service"}}}},
+			"service has invalid URI: ://service",
+		},
+		{
+			"InvalidRevocationReason",
+			Manifest{Certs: []ManifestCert{root, sre, {Name: "webapp", Type: "server", CA: "sre", Revoke: "retired"}}},
+			"webapp has invalid revocation reason retired",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.manifest.Validate()
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestManifestCertClaim(t *testing.T) {
+	spec := Claim{
+		Country:            []string{"CA"},
+		Organization:       []string{"Milad"},
+		OrganizationalUnit: []string{"R&D"},
+		DNSName:            []string{"example.com"},
+	}
+
+	mc := ManifestCert{
+		Name:               "webapp",
+		Type:               "server",
+		CommonName:         "webapp.example.com",
+		OrganizationalUnit: []string{"SRE"},
+		DNSNames:           []string{"webapp.example.com"},
+		IPAddresses:        []string{"127.0.0.1"},
+		EmailAddresses:     []string{"sre@example.com"},
+		URIs:               []string{"https://webapp.example.com"},
+	}
+
+	assert.Equal(t, Cert{Name: "webapp", Type: CertTypeServer}, mc.Cert())
+	assert.Equal(t, Claim{
+		CommonName:         "webapp.example.com",
+		Country:            []string{"CA"},
+		Organization:       []string{"Milad"},
+		OrganizationalUnit: []string{"SRE"},
+		DNSName:            []string{"webapp.example.com"},
+		IPAddress:          []net.IP{net.ParseIP("127.0.0.1")},
+		EmailAddress:       []string{"sre@example.com"},
+		URI:                []string{"https://webapp.example.com"},
+	}, mc.Claim(spec))
+
+	// Spec claim is not changed
+	assert.Equal(t, []string{"R&D"}, spec.OrganizationalUnit)
+
+	// Common name defaults to name
+	mc = ManifestCert{Name: "legacy", Type: "client"}
+	assert.Equal(t, "legacy", mc.Claim(spec).CommonName)
+}
+
+func TestManifestCertDrift(t *testing.T) {
+	uri, _ := url.Parse("spiffe://example.com/webapp")
+	cert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "webapp.example.com", OrganizationalUnit: []string{"SRE"}},
+		DNSNames:    []string{"webapp.example.com", "webapp"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:        []*url.URL{uri},
+	}
+
+	tests := []struct {
+		name         string
+		mc           ManifestCert
+		expectedKeys []string
+	}{
+		{
+			"NotSet",
+			ManifestCert{Name: "webapp", Type: "server"},
+			nil,
+		},
+		{
+			"Same",
+			ManifestCert{
+				CommonName:         "webapp.example.com",
+				OrganizationalUnit: []string{"SRE"},
+				DNSNames:           []string{"webapp", "webapp.example.com"},
+				IPAddresses:        []string{"127.0.0.1"},
+				URIs:               []string{"spiffe://example.com/webapp"},
+			},
+			nil,
+		},
+		{
+			"Changed",
+			ManifestCert{
+				CommonName:         "api.example.com",
+				OrganizationalUnit: []string{"Ops"},
+				DNSNames:           []string{"api.example.com"},
+				IPAddresses:        []string{"10.0.0.1"},
+				EmailAddresses:     []string{"sre@example.com"},
+				URIs:               []string{"spiffe://example.com/api"},
+			},
+			[]string{"common_name", "organizational_unit", "dns_names", "ip_addresses", "email_addresses", "uris"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedKeys, test.mc.Drift(cert))
+		})
+	}
+}
+
+func TestManifestFind(t *testing.T) {
+	manifest := &Manifest{
+		Certs: []ManifestCert{
+			{Name: "root", Type: "root"},
+			{Name: "sre", Type: "intermediate", CA: "root"},
+		},
+	}
+
+	mc, ok := manifest.Find("sre")
+	assert.True(t, ok)
+	assert.Equal(t, "root", mc.CA)
+
+	_, ok = manifest.Find("webapp")
+	assert.False(t, ok)
+}
+
+func TestManifestPlan(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cLegacy := Cert{Name: "legacy", Type: CertTypeServer}
+	cNew := Cert{Name: "api", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "legacy"}, cLegacy))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cLegacy, Usage{}, trust))
+
+	root := ManifestCert{Name: "root", Type: "root"}
+	sre := ManifestCert{Name: "sre", Type: "intermediate", CA: "root"}
+	webapp := ManifestCert{Name: "webapp", Type: "server", CA: "sre"}
+	now := time.Now()
+
+	t.Run("UpToDate", func(t *testing.T) {
+		manifest := &Manifest{Certs: []ManifestCert{root, sre, webapp}}
+		actions, err := manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Empty(t, actions)
+	})
+
+	t.Run("CreateAndRevoke", func(t *testing.T) {
+		manifest := &Manifest{
+			Certs: []ManifestCert{
+				root, sre, webapp,
+				{Name: "legacy", Type: "server", CA: "sre", Revoke: "superseded"},
+				{Name: "api", Type: "server", CA: "sre"},
+				{Name: "retired", Type: "server", CA: "sre", Revoke: "superseded"},
+			},
+		}
+
+		actions, err := manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionRevoke, Cert: cLegacy, CA: cInterm, Reason: "superseded"},
+			{Type: ActionCreate, Cert: cNew, CA: cInterm, Reason: "does not exist"},
+		}, actions)
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		expiry := now.AddDate(0, 0, 375).UTC().Format("2006-01-02")
+
+		// Server certificate expires within 30 days
+		manifest := &Manifest{Certs: []ManifestCert{root, sre, webapp}}
+		actions, err := manifest.Plan(now.AddDate(0, 0, 350))
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionRenew, Cert: cServer, CA: cInterm, Reason: "expires on " + expiry},
+		}, actions)
+
+		// Server certificate does not expire within 10 days
+		manifest.RenewBefore = 10
+		actions, err = manifest.Plan(now.AddDate(0, 0, 350))
+		assert.NoError(t, err)
+		assert.Empty(t, actions)
+	})
+
+	t.Run("Reissue", func(t *testing.T) {
+		manifest := &Manifest{
+			Certs: []ManifestCert{
+				root, sre,
+				{Name: "webapp", Type: "server", CA: "sre", CommonName: "webapp", DNSNames: []string{"webapp.example.com"}},
+			},
+		}
+
+		actions, err := manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionReissue, Cert: cServer, CA: cInterm, Reason: "has changed dns_names"},
+		}, actions)
+	})
+
+	t.Run("ReissueCASubject", func(t *testing.T) {
+		manifest := &Manifest{
+			Certs: []ManifestCert{
+				root,
+				{Name: "sre", Type: "intermediate", CA: "root", CommonName: "Platform CA"},
+				webapp,
+				{Name: "legacy", Type: "server", CA: "sre", DNSNames: []string{"legacy.example.com"}},
+			},
+		}
+
+		actions, err := manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionReissue, Cert: cInterm, CA: cRoot, Reason: "has changed common_name"},
+			{Type: ActionRenew, Cert: cServer, CA: cInterm, Reason: "issuer sre is reissued with a new subject"},
+			{Type: ActionReissue, Cert: cLegacy, CA: cInterm, Reason: "has changed dns_names"},
+		}, actions)
+
+		// Certificates are not renewed for a change in subject alternative names of their issuer
+		manifest.Certs[1] = ManifestCert{Name: "sre", Type: "intermediate", CA: "root", DNSNames: []string{"sre.example.com"}}
+		actions, err = manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionReissue, Cert: cInterm, CA: cRoot, Reason: "has changed dns_names"},
+			{Type: ActionReissue, Cert: cLegacy, CA: cInterm, Reason: "has changed dns_names"},
+		}, actions)
+	})
+
+	t.Run("RenewRevoked", func(t *testing.T) {
+		assert.NoError(t, manager.RevokeCert(cInterm, cLegacy, "superseded"))
+
+		manifest := &Manifest{
+			Certs: []ManifestCert{
+				root, sre,
+				{Name: "legacy", Type: "server", CA: "sre", Revoke: "superseded"},
+			},
+		}
+
+		actions, err := manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Empty(t, actions)
+
+		manifest.Certs[2].Revoke = ""
+		actions, err = manifest.Plan(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []Action{
+			{Type: ActionRenew, Cert: cLegacy, CA: cInterm, Reason: "is revoked", Rekey: true},
+		}, actions)
+	})
+}
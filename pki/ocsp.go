@@ -0,0 +1,152 @@
+/*
+ * https://tools.ietf.org/html/rfc6960
+ */
+
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	ocspRequestContentType  = "application/ocsp-request"
+	ocspResponseContentType = "application/ocsp-response"
+
+	ocspMaxRequestSize = 10000
+	ocspValidity       = 24 * time.Hour
+)
+
+// ocspResponder is an http handler responding to OCSP requests for certificates issued by a certificate authority
+type ocspResponder struct {
+	cCA        Cert
+	certCA     *x509.Certificate
+	signerCert *x509.Certificate
+	signerKey  crypto.Signer
+	nameHash   map[crypto.Hash][]byte
+	keyHash    map[crypto.Hash][]byte
+}
+
+func newOCSPResponder(cCA Cert, certCA *x509.Certificate, signerCert *x509.Certificate, signerKey crypto.Signer) (*ocspResponder, error) {
+	pubKeyBytes, err := subjectPublicKey(certCA.RawSubjectPublicKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ocspResponder{
+		cCA:        cCA,
+		certCA:     certCA,
+		signerCert: signerCert,
+		signerKey:  signerKey,
+		nameHash:   map[crypto.Hash][]byte{},
+		keyHash:    map[crypto.Hash][]byte{},
+	}
+
+	// Precompute issuer hashes for the hash algorithms supported by ocsp package
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		hash := h.New()
+		hash.Write(certCA.RawSubject)
+		r.nameHash[h] = hash.Sum(nil)
+
+		hash = h.New()
+		hash.Write(pubKeyBytes)
+		r.keyHash[h] = hash.Sum(nil)
+	}
+
+	return r, nil
+}
+
+func (r *ocspResponder) readRequest(req *http.Request) ([]byte, error) {
+	switch req.Method {
+	case http.MethodGet:
+		// GET {url}/{url-encoding of base-64 encoding of the DER encoding of the OCSPRequest}
+		path, err := url.PathUnescape(strings.TrimPrefix(req.URL.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(path)
+
+	case http.MethodPost:
+		if req.Header.Get("Content-Type") != ocspRequestContentType {
+			return nil, errors.New("invalid content type")
+		}
+		return io.ReadAll(io.LimitReader(req.Body, ocspMaxRequestSize))
+
+	default:
+		return nil, errors.New("invalid method")
+	}
+}
+
+func (r *ocspResponder) respond(req *ocsp.Request) []byte {
+	// Only requests for certificates issued by this certificate authority can be answered
+	if !bytes.Equal(req.IssuerNameHash, r.nameHash[req.HashAlgorithm]) || !bytes.Equal(req.IssuerKeyHash, r.keyHash[req.HashAlgorithm]) {
+		return ocsp.UnauthorizedErrorResponse
+	}
+
+	// The status store is read for every request, so revocations take effect immediately
+	store, err := readStatusStore(r.cCA.StatusPath())
+	if err != nil {
+		return ocsp.InternalErrorErrorResponse
+	}
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	template := ocsp.Response{
+		SerialNumber: req.SerialNumber,
+		IssuerHash:   req.HashAlgorithm,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(ocspValidity),
+		Certificate:  r.signerCert,
+	}
+
+	cs := store.Lookup(req.SerialNumber)
+	switch cs.Status {
+	case StatusGood:
+		template.Status = ocsp.Good
+	case StatusRevoked:
+		template.Status = ocsp.Revoked
+		template.RevokedAt = cs.RevokedAt
+		template.RevocationReason, _ = reasonCode(cs.Reason)
+	default:
+		template.Status = ocsp.Unknown
+	}
+
+	responderCert := r.certCA
+	if r.signerCert != nil {
+		responderCert = r.signerCert
+	}
+
+	resp, err := ocsp.CreateResponse(r.certCA, responderCert, template, r.signerKey)
+	if err != nil {
+		return ocsp.InternalErrorErrorResponse
+	}
+
+	return resp
+}
+
+// ServeHTTP responds to an OCSP request sent either using GET or POST method
+func (r *ocspResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var resp []byte
+
+	data, err := r.readRequest(req)
+	if err != nil {
+		resp = ocsp.MalformedRequestErrorResponse
+	} else if ocspReq, err := ocsp.ParseRequest(data); err != nil {
+		resp = ocsp.MalformedRequestErrorResponse
+	} else {
+		resp = r.respond(ocspReq)
+	}
+
+	w.Header().Set("Content-Type", ocspResponseContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp) // nolint: errcheck
+}
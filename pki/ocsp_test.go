@@ -0,0 +1,176 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+func postOCSPRequest(t *testing.T, serverURL string, req []byte) []byte {
+	resp, err := http.Post(serverURL, ocspRequestContentType, bytes.NewReader(req))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ocspResponseContentType, resp.Header.Get("Content-Type"))
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	return data
+}
+
+func getOCSPRequest(t *testing.T, serverURL string, req []byte) []byte {
+	resp, err := http.Get(serverURL + "/" + url.PathEscape(base64.StdEncoding.EncodeToString(req)))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	return data
+}
+
+func TestOCSPHandler(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Serial: 10, Length: 1024, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Serial: 100, Length: 1024, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Serial: 1000, Length: 1024, Days: 375}
+	configClient := Config{Serial: 10000, Length: 1024, Days: 40}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cClient := Cert{Name: "service", Type: CertTypeClient}
+	cSigner := Cert{Name: "ocsp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configClient, Claim{CommonName: "service"}, cClient))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cClient, Usage{}, trust))
+	assert.NoError(t, manager.RevokeCert(cInterm, cClient, "keyCompromise"))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "OCSP Responder"}, cSigner))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cSigner, Usage{ExtKeyUsage: []string{"OCSPSigning"}}, trust))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	certInterm, err := readCertificate(cInterm.CertPath())
+	assert.NoError(t, err)
+	certServer, err := readCertificate(cServer.CertPath())
+	assert.NoError(t, err)
+	certClient, err := readCertificate(cClient.CertPath())
+	assert.NoError(t, err)
+
+	t.Run("Error", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			configCA Config
+			cCA      Cert
+			cSigner  Cert
+		}{
+			{"InvalidCA", configServer, cServer, Cert{}},
+			{"MissingCA", configInterm, Cert{Name: "missing", Type: CertTypeInterm}, Cert{}},
+			{"InvalidPassword", Config{Password: "different"}, cInterm, Cert{}},
+			{"InvalidSigner", configInterm, cInterm, cRoot},
+			{"MissingSigner", configInterm, cInterm, Cert{Name: "missing", Type: CertTypeServer}},
+			{"SignerNotIssued", configRoot, cRoot, cSigner},
+			{"SignerNotAuthorized", configInterm, cInterm, cServer},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				handler, err := manager.OCSPHandler(test.configCA, test.cCA, test.cSigner)
+				assert.Error(t, err)
+				assert.Nil(t, handler)
+			})
+		}
+	})
+
+	tests := []struct {
+		name           string
+		configCA       Config
+		cCA            Cert
+		cSigner        Cert
+		expectedSigner string
+	}{
+		{"SignedByCA", Config{Password: "intermSecret"}, cInterm, Cert{}, ""},
+		{"SignedByDelegatedSigner", Config{}, cInterm, cSigner, "OCSP Responder"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, err := manager.OCSPHandler(test.configCA, test.cCA, test.cSigner)
+			assert.NoError(t, err)
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			// Good
+			req, err := ocsp.CreateRequest(certServer, certInterm, nil)
+			assert.NoError(t, err)
+			resp, err := ocsp.ParseResponseForCert(postOCSPRequest(t, server.URL, req), certServer, certInterm)
+			assert.NoError(t, err)
+			assert.Equal(t, ocsp.Good, resp.Status)
+			assert.Equal(t, certServer.SerialNumber, resp.SerialNumber)
+			assert.True(t, resp.NextUpdate.After(resp.ThisUpdate))
+			if test.expectedSigner == "" {
+				assert.Nil(t, resp.Certificate)
+			} else {
+				assert.Equal(t, test.expectedSigner, resp.Certificate.Subject.CommonName)
+			}
+
+			// Revoked using GET method and SHA-256
+			req, err = ocsp.CreateRequest(certClient, certInterm, &ocsp.RequestOptions{Hash: crypto.SHA256})
+			assert.NoError(t, err)
+			resp, err = ocsp.ParseResponseForCert(getOCSPRequest(t, server.URL, req), certClient, certInterm)
+			assert.NoError(t, err)
+			assert.Equal(t, ocsp.Revoked, resp.Status)
+			assert.Equal(t, ocsp.KeyCompromise, resp.RevocationReason)
+			assert.False(t, resp.RevokedAt.IsZero())
+
+			// Unknown
+			unknown := *certServer
+			unknown.SerialNumber = big.NewInt(4242)
+			req, err = ocsp.CreateRequest(&unknown, certInterm, nil)
+			assert.NoError(t, err)
+			resp, err = ocsp.ParseResponse(postOCSPRequest(t, server.URL, req), certInterm)
+			assert.NoError(t, err)
+			assert.Equal(t, ocsp.Unknown, resp.Status)
+
+			// Not issued by the certificate authority
+			req, err = ocsp.CreateRequest(certInterm, certRoot, nil)
+			assert.NoError(t, err)
+			_, err = ocsp.ParseResponse(postOCSPRequest(t, server.URL, req), certInterm)
+			assert.Equal(t, ocsp.ResponseError{Status: ocsp.Unauthorized}, err)
+
+			// Malformed
+			_, err = ocsp.ParseResponse(postOCSPRequest(t, server.URL, []byte("malformed")), certInterm)
+			assert.Equal(t, ocsp.ResponseError{Status: ocsp.Malformed}, err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package pki
+
+import (
+	"crypto/x509"
+	"fmt"
+)
+
+// maxPathLen returns the maximum number of intermediates beneath a certificate authority of a type
+// A negative value means no limit.
+func (c Config) maxPathLen(certType int) int {
+	if c.MaxPathLen != nil {
+		return *c.MaxPathLen
+	}
+
+	switch certType {
+	case CertTypeRoot:
+		return defaultRootCAMaxPathLen
+	case CertTypeInterm:
+		return defaultIntermCAMaxPathLen
+	default:
+		return -1
+	}
+}
+
+// applyMaxPathLen sets the path length constraint of a certificate authority template
+func applyMaxPathLen(cert *x509.Certificate, maxPathLen int) {
+	if maxPathLen < 0 {
+		cert.MaxPathLen = -1
+		cert.MaxPathLenZero = false
+	} else {
+		cert.MaxPathLen = maxPathLen
+		cert.MaxPathLenZero = maxPathLen == 0
+	}
+}
+
+// hasMaxPathLen determines whether or not a certificate authority has a path length constraint
+func hasMaxPathLen(cert *x509.Certificate) bool {
+	return cert.BasicConstraintsValid && (cert.MaxPathLen > 0 || (cert.MaxPathLen == 0 && cert.MaxPathLenZero))
+}
+
+// maxPathLenFromCert returns the path length constraint of an existing certificate authority
+func maxPathLenFromCert(cert *x509.Certificate) *int {
+	maxPathLen := -1
+	if hasMaxPathLen(cert) {
+		maxPathLen = cert.MaxPathLen
+	}
+
+	return &maxPathLen
+}
+
+// remainingPathLen returns the number of intermediates that can still be issued beneath the first certificate authority in a chain
+// Every certificate authority in the chain limits the depth beneath it. A negative value means no limit.
+func remainingPathLen(chain []*x509.Certificate) int {
+	remaining, limited := 0, false
+	for i, certCA := range chain {
+		if hasMaxPathLen(certCA) {
+			if r := certCA.MaxPathLen - i; !limited || r < remaining {
+				remaining, limited = r, true
+			}
+		}
+	}
+
+	if !limited {
+		return -1
+	} else if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// checkPathLen checks a certificate and its chain of certificate authorities against path length constraints
+// Intermediates between a certificate authority and the certificate count towards its path length.
+func checkPathLen(cert *x509.Certificate, chain []*x509.Certificate) error {
+	for i, certCA := range chain {
+		if hasMaxPathLen(certCA) && i > certCA.MaxPathLen {
+			return fmt.Errorf("path length constraint of %s allows %d intermediates beneath it but chain of %s has %d",
+				certCA.Subject.CommonName, certCA.MaxPathLen, cert.Subject.CommonName, i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package pki
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// caWithPathLen creates a certificate authority template with a path length constraint
+func caWithPathLen(commonName string, maxPathLen int) *x509.Certificate {
+	cert := &x509.Certificate{BasicConstraintsValid: true, IsCA: true}
+	cert.Subject.CommonName = commonName
+	applyMaxPathLen(cert, maxPathLen)
+	return cert
+}
+
+func TestConfigMaxPathLen(t *testing.T) {
+	two := 2
+	unlimited := -1
+
+	tests := []struct {
+		name               string
+		config             Config
+		certType           int
+		expectedMaxPathLen int
+	}{
+		{"RootDefault", Config{}, CertTypeRoot, 1},
+		{"IntermediateDefault", Config{}, CertTypeInterm, 0},
+		{"ServerDefault", Config{}, CertTypeServer, -1},
+		{"Root", Config{MaxPathLen: &two}, CertTypeRoot, 2},
+		{"IntermediateUnlimited", Config{MaxPathLen: &unlimited}, CertTypeInterm, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedMaxPathLen, test.config.maxPathLen(test.certType))
+		})
+	}
+}
+
+func TestApplyMaxPathLen(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPathLen int
+		expected   bool
+	}{
+		{"Unlimited", -1, false},
+		{"Zero", 0, true},
+		{"One", 1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert := caWithPathLen("CA", test.maxPathLen)
+			assert.Equal(t, test.expected, hasMaxPathLen(cert))
+			assert.Equal(t, test.maxPathLen, *maxPathLenFromCert(cert))
+		})
+	}
+}
+
+func TestRemainingPathLen(t *testing.T) {
+	tests := []struct {
+		name              string
+		chain             []*x509.Certificate
+		expectedRemaining int
+	}{
+		{"Unlimited", []*x509.Certificate{caWithPathLen("Root", -1)}, -1},
+		{"Root", []*x509.Certificate{caWithPathLen("Root", 1)}, 1},
+		{"IntermediateZero", []*x509.Certificate{caWithPathLen("Ops", 0), caWithPathLen("Root", 2)}, 0},
+		{"LimitedByRoot", []*x509.Certificate{caWithPathLen("Ops", -1), caWithPathLen("Root", 1)}, 0},
+		{"ViolatedByRoot", []*x509.Certificate{caWithPathLen("Team", -1), caWithPathLen("Ops", -1), caWithPathLen("Root", 0)}, 0},
+		{"LimitedByIntermediate", []*x509.Certificate{caWithPathLen("Ops", 1), caWithPathLen("Root", 3)}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedRemaining, remainingPathLen(test.chain))
+		})
+	}
+}
+
+func TestCheckPathLen(t *testing.T) {
+	leaf := &x509.Certificate{}
+	leaf.Subject.CommonName = "webapp"
+
+	tests := []struct {
+		name          string
+		chain         []*x509.Certificate
+		expectedError string
+	}{
+		{"Root", []*x509.Certificate{caWithPathLen("Root", 0)}, ""},
+		{"Intermediate", []*x509.Certificate{caWithPathLen("Ops", 0), caWithPathLen("Root", 1)}, ""},
+		{"Unlimited", []*x509.Certificate{caWithPathLen("Team", -1), caWithPathLen("Ops", -1), caWithPathLen("Root", -1)}, ""},
+		{"TooDeep", []*x509.Certificate{caWithPathLen("Team", -1), caWithPathLen("Ops", -1), caWithPathLen("Root", 1)}, "path length constraint of Root allows 1 intermediates beneath it but chain of webapp has 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkPathLen(leaf, test.chain)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSignCSRPathLen(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	unlimited := -1
+	config := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 30, Password: "password"}
+	configUnlimited := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 30, MaxPathLen: &unlimited, Password: "password"}
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cTeam := Cert{Name: "team", Type: CertTypeInterm}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(config, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "Ops CA"}, cOps))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "Team CA"}, cTeam))
+
+	// Intermediate asks for no limit, but it is limited by root
+	assert.NoError(t, manager.SignCSR(config, cRoot, configUnlimited, cOps, Usage{}, trust))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *maxPathLenFromCert(certRoot))
+
+	certOps, err := readCertificate(cOps.CertPath())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, *maxPathLenFromCert(certOps))
+
+	err = manager.SignCSR(config, cOps, config, cTeam, Usage{}, trust)
+	assert.EqualError(t, err, "ops cannot sign intermediate certificate authorities due to its path length constraint")
+	assert.NoFileExists(t, cTeam.CertPath())
+}
@@ -0,0 +1,292 @@
+/*
+ * https://tools.ietf.org/html/rfc5208
+ * https://tools.ietf.org/html/rfc8018
+ * https://tools.ietf.org/html/rfc7914
+ */
+
+package pki
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	pbkdf2SaltLen    = 16
+	pbkdf2Iterations = 600000
+)
+
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES256GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+)
+
+type (
+	encryptedPrivateKeyInfo struct {
+		EncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedData       []byte
+	}
+
+	pbes2Params struct {
+		KeyDerivationFunc pkix.AlgorithmIdentifier
+		EncryptionScheme  pkix.AlgorithmIdentifier
+	}
+
+	pbkdf2Params struct {
+		Salt           []byte
+		IterationCount int
+		KeyLength      int                      `asn1:"optional"`
+		PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+	}
+
+	scryptParams struct {
+		Salt                     []byte
+		CostParameter            int
+		BlockSize                int
+		ParallelizationParameter int
+		KeyLength                int `asn1:"optional"`
+	}
+
+	gcmParams struct {
+		Nonce  []byte
+		ICVLen int `asn1:"optional,default:12"`
+	}
+)
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("invalid padding")
+	}
+
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, errors.New("invalid padding")
+	}
+
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("invalid padding")
+		}
+	}
+
+	return data[:len(data)-n], nil
+}
+
+// encryptPKCS8 encrypts a DER-encoded PKCS#8 private key using PBES2 with PBKDF2-HMAC-SHA256 and AES-256-CBC.
+// The result is a DER-encoded EncryptedPrivateKeyInfo.
+func encryptPKCS8(keyData []byte, password string) ([]byte, error) {
+	salt := make([]byte, pbkdf2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := pkcs7Pad(append([]byte{}, keyData...), aes.BlockSize)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF: pkix.AlgorithmIdentifier{
+			Algorithm:  oidHMACWithSHA256,
+			Parameters: asn1.NullRawValue,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBKDF2,
+			Parameters: asn1.RawValue{FullBytes: kdfParams},
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  oidAES256CBC,
+			Parameters: asn1.RawValue{FullBytes: encParams},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: ciphertext,
+	})
+}
+
+func deriveKey(kdf pkix.AlgorithmIdentifier, password string, keyLen int) ([]byte, error) {
+	switch {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var params pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+
+		var h func() hash.Hash
+		switch {
+		case len(params.PRF.Algorithm) == 0 || params.PRF.Algorithm.Equal(oidHMACWithSHA1):
+			h = sha1.New
+		case params.PRF.Algorithm.Equal(oidHMACWithSHA256):
+			h = sha256.New
+		default:
+			return nil, errors.New("unsupported pbkdf2 pseudorandom function")
+		}
+
+		return pbkdf2.Key(h, password, params.Salt, params.IterationCount, keyLen)
+
+	case kdf.Algorithm.Equal(oidScrypt):
+		var params scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+
+		return scrypt.Key([]byte(password), params.Salt, params.CostParameter, params.BlockSize, params.ParallelizationParameter, keyLen)
+
+	default:
+		return nil, errors.New("unsupported key derivation function")
+	}
+}
+
+// decryptPKCS8 decrypts a DER-encoded EncryptedPrivateKeyInfo encrypted using PBES2.
+// The key derivation function can be either PBKDF2 or scrypt and the cipher can be either AES-CBC or AES-256-GCM.
+func decryptPKCS8(data []byte, password string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.New("unsupported private key encryption scheme")
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+
+	enc := params.EncryptionScheme
+
+	switch {
+	case enc.Algorithm.Equal(oidAES128CBC), enc.Algorithm.Equal(oidAES192CBC), enc.Algorithm.Equal(oidAES256CBC):
+		keyLen := 32
+		if enc.Algorithm.Equal(oidAES128CBC) {
+			keyLen = 16
+		} else if enc.Algorithm.Equal(oidAES192CBC) {
+			keyLen = 24
+		}
+
+		var iv []byte
+		if _, err := asn1.Unmarshal(enc.Parameters.FullBytes, &iv); err != nil {
+			return nil, err
+		}
+
+		if len(iv) != aes.BlockSize || len(info.EncryptedData)%aes.BlockSize != 0 {
+			return nil, errors.New("malformed encrypted private key")
+		}
+
+		key, err := deriveKey(params.KeyDerivationFunc, password, keyLen)
+		if err != nil {
+			return nil, err
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext := make([]byte, len(info.EncryptedData))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+		keyData, err := pkcs7Unpad(plaintext, aes.BlockSize)
+		if err != nil {
+			return nil, errors.New("decrypting private key failed")
+		}
+
+		return keyData, nil
+
+	case enc.Algorithm.Equal(oidAES256GCM):
+		var gcm gcmParams
+		if _, err := asn1.Unmarshal(enc.Parameters.FullBytes, &gcm); err != nil {
+			return nil, err
+		}
+
+		key, err := deriveKey(params.KeyDerivationFunc, password, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(gcm.Nonce) != 12 {
+			return nil, errors.New("malformed encrypted private key")
+		}
+
+		aead, err := cipher.NewGCMWithTagSize(block, gcm.ICVLen)
+		if err != nil {
+			return nil, err
+		}
+
+		keyData, err := aead.Open(nil, gcm.Nonce, info.EncryptedData, nil)
+		if err != nil {
+			return nil, errors.New("decrypting private key failed")
+		}
+
+		return keyData, nil
+
+	default:
+		return nil, errors.New("unsupported private key cipher")
+	}
+}
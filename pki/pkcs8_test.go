@@ -0,0 +1,144 @@
+package pki
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptPKCS8ScryptGCM encrypts a PKCS#8 private key using PBES2 with scrypt and AES-256-GCM.
+func encryptPKCS8ScryptGCM(t *testing.T, keyData []byte, password string) []byte {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	assert.NoError(t, err)
+
+	nonce := make([]byte, 12)
+	_, err = rand.Read(nonce)
+	assert.NoError(t, err)
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<10, 8, 1, 32)
+	assert.NoError(t, err)
+
+	block, err := aes.NewCipher(key)
+	assert.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	ciphertext := aead.Seal(nil, nonce, keyData, nil)
+
+	kdfParams, err := asn1.Marshal(scryptParams{
+		Salt:                     salt,
+		CostParameter:            1 << 10,
+		BlockSize:                8,
+		ParallelizationParameter: 1,
+	})
+	assert.NoError(t, err)
+
+	encParams, err := asn1.Marshal(gcmParams{
+		Nonce:  nonce,
+		ICVLen: 16,
+	})
+	assert.NoError(t, err)
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm:  oidScrypt,
+			Parameters: asn1.RawValue{FullBytes: kdfParams},
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  oidAES256GCM,
+			Parameters: asn1.RawValue{FullBytes: encParams},
+		},
+	})
+	assert.NoError(t, err)
+
+	data, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: ciphertext,
+	})
+	assert.NoError(t, err)
+
+	return data
+}
+
+func TestPKCS7Padding(t *testing.T) {
+	tests := []struct {
+		data        []byte
+		expectError bool
+	}{
+		{[]byte{}, false},
+		{[]byte("key"), false},
+		{[]byte("0123456789abcdef"), false},
+		{[]byte("0123456789abcdef0"), false},
+	}
+
+	for _, test := range tests {
+		padded := pkcs7Pad(append([]byte{}, test.data...), aes.BlockSize)
+		assert.Zero(t, len(padded)%aes.BlockSize)
+
+		data, err := pkcs7Unpad(padded, aes.BlockSize)
+		assert.NoError(t, err)
+		assert.Equal(t, test.data, data)
+	}
+
+	_, err := pkcs7Unpad([]byte{}, aes.BlockSize)
+	assert.Error(t, err)
+
+	_, err = pkcs7Unpad(make([]byte, aes.BlockSize), aes.BlockSize)
+	assert.Error(t, err)
+}
+
+func TestEncryptDecryptPKCS8(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	_, priv, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+	keyData, err := x509.MarshalPKCS8PrivateKey(priv)
+	assert.NoError(t, err)
+
+	pbkdf2Data, err := encryptPKCS8(keyData, "secret")
+	assert.NoError(t, err)
+
+	scryptData := encryptPKCS8ScryptGCM(t, keyData, "secret")
+
+	tests := []struct {
+		title       string
+		data        []byte
+		password    string
+		expectError bool
+	}{
+		{"Invalid", []byte("invalid"), "secret", true},
+		{"PBKDF2WrongPassword", pbkdf2Data, "different", true},
+		{"PBKDF2", pbkdf2Data, "secret", false},
+		{"ScryptWrongPassword", scryptData, "different", true},
+		{"Scrypt", scryptData, "secret", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			data, err := decryptPKCS8(test.data, test.password)
+
+			if test.expectError {
+				// A wrong password may rarely produce a valid padding, but never a valid key
+				if err == nil {
+					_, err = x509.ParsePKCS8PrivateKey(data)
+				}
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, keyData, data)
+			}
+		})
+	}
+}
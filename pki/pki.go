@@ -3,82 +3,210 @@ package pki
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/x509"
-	"encoding/gob"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 const (
-	pemTypeKey  = "RSA PRIVATE KEY"
-	pemTypeCert = "CERTIFICATE"
-	pemTypeCSR  = "CERTIFICATE REQUEST"
+	pemTypeKey          = "PRIVATE KEY"
+	pemTypeEncryptedKey = "ENCRYPTED PRIVATE KEY"
+	pemTypeRSAKey       = "RSA PRIVATE KEY"
+	pemTypeECKey        = "EC PRIVATE KEY"
+	pemTypeCert         = "CERTIFICATE"
+	pemTypeCSR          = "CERTIFICATE REQUEST"
+	pemTypeNewCSR       = "NEW CERTIFICATE REQUEST"
 )
 
-func genKeyPair(length int) (*rsa.PublicKey, *rsa.PrivateKey, error) {
-	private, err := rsa.GenerateKey(rand.Reader, length)
+func ellipticCurve(length int) (elliptic.Curve, error) {
+	switch length {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ecdsa key length %d", length)
+	}
+}
+
+// genKeyPair generates a new public-private key pair.
+// For RSA, length is the size of modulus in bits and for ECDSA, length is the size of curve (256, 384, or 521).
+// The length is ignored for Ed25519 keys.
+func genKeyPair(algorithm string, length int) (crypto.PublicKey, crypto.Signer, error) {
+	switch algorithm {
+	// An empty algorithm is RSA for backward compatibility
+	case "", AlgorithmRSA:
+		private, err := rsa.GenerateKey(rand.Reader, length)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &private.PublicKey, private, nil
+
+	case AlgorithmECDSA:
+		curve, err := ellipticCurve(length)
+		if err != nil {
+			return nil, nil, err
+		}
+		private, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &private.PublicKey, private, nil
+
+	case AlgorithmEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return public, private, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+}
+
+// subjectPublicKey returns the subjectPublicKey bit string of a DER-encoded SubjectPublicKeyInfo
+func subjectPublicKey(pubKeyData []byte) ([]byte, error) {
+	var pubKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	_, err := asn1.Unmarshal(pubKeyData, &pubKeyInfo)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	public := &private.PublicKey
 
-	return public, private, nil
+	return pubKeyInfo.PublicKey.Bytes, nil
 }
 
-func computeSubjectKeyID(pubKey interface{}) ([]byte, error) {
-	hash := sha1.New()
-	pubKeyEncoder := gob.NewEncoder(hash)
-	err := pubKeyEncoder.Encode(pubKey)
+// computeSubjectKeyID computes the key identifier as the SHA-1 hash of subject public key (RFC 5280 4.2.1.2).
+func computeSubjectKeyID(pubKey crypto.PublicKey) ([]byte, error) {
+	pubKeyData, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKeyBytes, err := subjectPublicKey(pubKeyData)
 	if err != nil {
 		return nil, err
 	}
 
-	return hash.Sum(nil), nil
+	hash := sha1.Sum(pubKeyBytes)
+
+	return hash[:], nil
+}
+
+func marshalPrivateKey(private crypto.Signer) (string, []byte, error) {
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return pemTypeRSAKey, x509.MarshalPKCS1PrivateKey(key), nil
+	case *ecdsa.PrivateKey:
+		keyData, err := x509.MarshalECPrivateKey(key)
+		return pemTypeECKey, keyData, err
+	default:
+		keyData, err := x509.MarshalPKCS8PrivateKey(key)
+		return pemTypeKey, keyData, err
+	}
+}
+
+func parsePrivateKey(pemType string, keyData []byte) (crypto.Signer, error) {
+	switch pemType {
+	case pemTypeRSAKey:
+		return x509.ParsePKCS1PrivateKey(keyData)
+	case pemTypeECKey:
+		return x509.ParseECPrivateKey(keyData)
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(keyData)
+		if err != nil {
+			return nil, err
+		}
+
+		private, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+
+		return private, nil
+	}
 }
 
-func writePrivateKey(private *rsa.PrivateKey, password, path string) (err error) {
+func writePrivateKey(private crypto.Signer, password, path string) error {
 	var keyPem *pem.Block
-	keyData := x509.MarshalPKCS1PrivateKey(private)
 
 	// Encrypt private key if a password set
 	if password == "" {
+		pemType, keyData, err := marshalPrivateKey(private)
+		if err != nil {
+			return err
+		}
+
 		keyPem = &pem.Block{
-			Type:  pemTypeKey,
+			Type:  pemType,
 			Bytes: keyData,
 		}
 	} else {
-		// FIXME:
-		// nolint directive: SA1019
-		keyPem, err = x509.EncryptPEMBlock(rand.Reader, pemTypeKey, keyData, []byte(password), x509.PEMCipherAES256)
+		keyData, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return err
+		}
+
+		encData, err := encryptPKCS8(keyData, password)
 		if err != nil {
 			return err
 		}
+
+		keyPem = &pem.Block{
+			Type:  pemTypeEncryptedKey,
+			Bytes: encData,
+		}
 	}
 
-	keyFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	// Write to a temporary file in the same directory first and rename it,
+	// so an existing key is never lost if writing the new one fails midway
+	keyFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
 	if err != nil {
 		return err
 	}
+	tmp := keyFile.Name()
 
 	err = pem.Encode(keyFile, keyPem)
-	if err != nil {
-		return err
+	if err == nil {
+		err = keyFile.Sync()
+	}
+	if cerr := keyFile.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
 	}
-
-	err = keyFile.Close()
 	if err != nil {
+		_ = os.Remove(tmp)
 		return err
 	}
 
 	return nil
 }
 
-func readPrivateKey(password, path string) (*rsa.PrivateKey, error) {
+func readPrivateKey(password, path string) (crypto.Signer, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -89,25 +217,33 @@ func readPrivateKey(password, path string) (*rsa.PrivateKey, error) {
 		return nil, errors.New("decoding private key failed")
 	}
 
+	pemType := keyPem.Type
 	pemBytes := keyPem.Bytes
 
-	// FIXME:
-	// nolint directive: SA1019
-	if x509.IsEncryptedPEMBlock(keyPem) {
-		// Decrypt private key if a password set
-		if password != "" {
-			// FIXME:
-			// nolint directive: SA1019
-			pemBytes, err = x509.DecryptPEMBlock(keyPem, []byte(password))
-			if err != nil {
-				return nil, err
-			}
-		} else {
+	if pemType == pemTypeEncryptedKey {
+		// Decrypt PKCS#8 private key
+		if password == "" {
+			return nil, errors.New("password required but not set")
+		}
+
+		pemType = pemTypeKey
+		pemBytes, err = decryptPKCS8(keyPem.Bytes, password)
+		if err != nil {
+			return nil, err
+		}
+	} else if x509.IsEncryptedPEMBlock(keyPem) { //nolint:staticcheck // reading legacy encrypted keys
+		// Decrypt legacy PEM-encrypted private key
+		if password == "" {
 			return nil, errors.New("password required but not set")
 		}
+
+		pemBytes, err = x509.DecryptPEMBlock(keyPem, []byte(password)) //nolint:staticcheck // reading legacy encrypted keys
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	private, err := x509.ParsePKCS1PrivateKey(pemBytes)
+	private, err := parsePrivateKey(pemType, pemBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -249,6 +385,48 @@ func writeCertificateChain(c, cCA Cert) error {
 	return nil
 }
 
+// writeFullchain writes the full chain file of a leaf certificate issued by a certificate authority
+// The root certificate authority is left out since it is already trusted by peers.
+func writeFullchain(c, cCA Cert) error {
+	// Only server and client certificates need a full chain
+	if c.Type != CertTypeServer && c.Type != CertTypeClient {
+		return errors.New("only server and client certificates have full chain")
+	}
+
+	// CA can only be root or another intermediate
+	if cCA.Type != CertTypeRoot && cCA.Type != CertTypeInterm {
+		return errors.New("CA can only be root ca or another intermediate CA")
+	}
+
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return err
+	}
+
+	chain, err := readCertificateChain(cCA.ChainPath())
+	if err != nil {
+		return err
+	}
+
+	if len(chain) == 0 {
+		return errors.New("certificate chain of " + cCA.Name + " is empty")
+	}
+
+	certs := append([]*x509.Certificate{cert}, chain[:len(chain)-1]...)
+
+	return os.WriteFile(c.FullchainPath(), encodeCertificates(certs), 0644)
+}
+
+// encodeCertificates encodes a list of certificates as concatenated PEM blocks
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	data := []byte{}
+	for _, cert := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: pemTypeCert, Bytes: cert.Raw})...)
+	}
+
+	return data
+}
+
 func readCertificateChain(path string) ([]*x509.Certificate, error) {
 	certs := make([]*x509.Certificate, 0)
 
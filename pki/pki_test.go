@@ -1,12 +1,16 @@
 package pki
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"math/big"
+	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/moorara/gocert/util"
@@ -20,7 +24,7 @@ func mockWorkspaceWithCA(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock root CA
-	pub, priv, err := genKeyPair(testKeyLen)
+	pub, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	rootCA := &x509.Certificate{
 		SerialNumber: big.NewInt(10),
@@ -34,7 +38,7 @@ func mockWorkspaceWithCA(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock an intermediate CA
-	pub, priv, err = genKeyPair(testKeyLen)
+	pub, priv, err = genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	opsCA := &x509.Certificate{
 		SerialNumber: big.NewInt(100),
@@ -48,7 +52,7 @@ func mockWorkspaceWithCA(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock first-level intermediate CA
-	pub, priv, err = genKeyPair(testKeyLen)
+	pub, priv, err = genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	sreCA := &x509.Certificate{
 		SerialNumber: big.NewInt(200),
@@ -62,7 +66,7 @@ func mockWorkspaceWithCA(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock second-level intermediate CA
-	pub, priv, err = genKeyPair(testKeyLen)
+	pub, priv, err = genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 	rdCA := &x509.Certificate{
 		SerialNumber: big.NewInt(300),
@@ -82,17 +86,27 @@ func TestGenKeyPair(t *testing.T) {
 	}
 
 	tests := []struct {
+		algorithm   string
 		length      int
 		expectError bool
 	}{
-		{0, true},
-		{1024, false},
-		{2048, false},
-		{4096, false},
+		{"", 0, true},
+		{"", 1024, false},
+		{AlgorithmRSA, 0, true},
+		{AlgorithmRSA, 1024, false},
+		{AlgorithmRSA, 2048, false},
+		{AlgorithmRSA, 4096, false},
+		{AlgorithmECDSA, 0, true},
+		{AlgorithmECDSA, 224, true},
+		{AlgorithmECDSA, 256, false},
+		{AlgorithmECDSA, 384, false},
+		{AlgorithmECDSA, 521, false},
+		{AlgorithmEd25519, 0, false},
+		{"dsa", 1024, true},
 	}
 
 	for _, test := range tests {
-		pub, priv, err := genKeyPair(test.length)
+		pub, priv, err := genKeyPair(test.algorithm, test.length)
 
 		if test.expectError {
 			assert.Error(t, err)
@@ -111,7 +125,13 @@ func TestComputeSubjectKeyID(t *testing.T) {
 		t.Skip()
 	}
 
-	pub, _, err := genKeyPair(testKeyLen)
+	rsaPub, _, err := genKeyPair(AlgorithmRSA, testKeyLen)
+	assert.NoError(t, err)
+
+	ecdsaPub, _, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+
+	ed25519Pub, _, err := genKeyPair(AlgorithmEd25519, 0)
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -119,7 +139,9 @@ func TestComputeSubjectKeyID(t *testing.T) {
 		expectError bool
 	}{
 		{nil, true},
-		{pub, false},
+		{rsaPub, false},
+		{ecdsaPub, false},
+		{ed25519Pub, false},
 	}
 
 	for _, test := range tests {
@@ -140,11 +162,17 @@ func TestWriteReadPrivateKey(t *testing.T) {
 		t.Skip()
 	}
 
-	_, priv, err := genKeyPair(testKeyLen)
+	_, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
+	assert.NoError(t, err)
+
+	_, ecdsaPriv, err := genKeyPair(AlgorithmECDSA, 384)
+	assert.NoError(t, err)
+
+	_, ed25519Priv, err := genKeyPair(AlgorithmEd25519, 0)
 	assert.NoError(t, err)
 
 	tests := []*struct {
-		privKey    *rsa.PrivateKey
+		privKey    crypto.Signer
 		writePW    string
 		readPW     string
 		setPath    bool
@@ -182,6 +210,30 @@ func TestWriteReadPrivateKey(t *testing.T) {
 			true, "",
 			false, true,
 		},
+		{
+			ecdsaPriv,
+			"", "",
+			true, "",
+			false, false,
+		},
+		{
+			ecdsaPriv,
+			"secret", "secret",
+			true, "",
+			false, false,
+		},
+		{
+			ed25519Priv,
+			"", "",
+			true, "",
+			false, false,
+		},
+		{
+			ed25519Priv,
+			"secret", "secret",
+			true, "",
+			false, false,
+		},
 	}
 
 	// Prepare temporary files
@@ -221,12 +273,80 @@ func TestWriteReadPrivateKey(t *testing.T) {
 	})
 }
 
+func TestWritePrivateKeyReplace(t *testing.T) {
+	_, priv, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+
+	_, newPriv, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ca.key")
+
+	assert.NoError(t, writePrivateKey(priv, "secret", path))
+	assert.NoError(t, writePrivateKey(newPriv, "changed", path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	privKey, err := readPrivateKey("changed", path)
+	assert.NoError(t, err)
+	assert.Equal(t, newPriv, privKey)
+
+	// A failed write leaves no temporary file behind
+	target := filepath.Join(dir, "dir.key")
+	assert.NoError(t, os.MkdirAll(filepath.Join(target, "nested"), 0755))
+	assert.Error(t, writePrivateKey(priv, "secret", target))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestReadLegacyPrivateKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	_, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
+	assert.NoError(t, err)
+
+	keyPem, err := x509.EncryptPEMBlock(rand.Reader, pemTypeRSAKey, x509.MarshalPKCS1PrivateKey(priv.(*rsa.PrivateKey)), []byte("secret"), x509.PEMCipherAES256) //nolint:staticcheck // legacy encrypted keys
+	assert.NoError(t, err)
+
+	path, cleanup, err := util.CreateTempFile(string(pem.EncodeToMemory(keyPem)))
+	defer cleanup()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		password    string
+		expectError bool
+	}{
+		{"", true},
+		{"different", true},
+		{"secret", false},
+	}
+
+	for _, test := range tests {
+		privKey, err := readPrivateKey(test.password, path)
+
+		if test.expectError {
+			assert.Error(t, err)
+			assert.Nil(t, privKey)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, priv, privKey)
+		}
+	}
+}
+
 func TestWritePemFileReadCertificate(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
 
-	pub, priv, err := genKeyPair(testKeyLen)
+	pub, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 
 	cert := &x509.Certificate{
@@ -305,7 +425,7 @@ func TestWritePemFileReadCertificateRequest(t *testing.T) {
 		t.Skip()
 	}
 
-	_, priv, err := genKeyPair(testKeyLen)
+	_, priv, err := genKeyPair(AlgorithmRSA, testKeyLen)
 	assert.NoError(t, err)
 
 	csr := &x509.CertificateRequest{
@@ -484,3 +604,102 @@ func TestWriteReadCertificateChain(t *testing.T) {
 		}
 	})
 }
+
+func TestEncodeCertificates(t *testing.T) {
+	certs := []*x509.Certificate{
+		{Raw: []byte("first")},
+		{Raw: []byte("second")},
+	}
+
+	data := encodeCertificates(certs)
+
+	block, rest := pem.Decode(data)
+	assert.Equal(t, pemTypeCert, block.Type)
+	assert.Equal(t, []byte("first"), block.Bytes)
+
+	block, rest = pem.Decode(rest)
+	assert.Equal(t, pemTypeCert, block.Type)
+	assert.Equal(t, []byte("second"), block.Bytes)
+	assert.Empty(t, rest)
+}
+
+func TestWriteFullchain(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	// Intermediates sign other intermediates in this hierarchy
+	rootPathLen, intermPathLen := 2, 1
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, MaxPathLen: &rootPathLen, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, MaxPathLen: &intermPathLen, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cSRE := Cert{Name: "sre", Type: CertTypeInterm}
+	cWebapp := Cert{Name: "webapp", Type: CertTypeServer}
+	cService := Cert{Name: "service", Type: CertTypeClient}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "Ops CA"}, cOps))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cOps, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cSRE))
+	assert.NoError(t, manager.SignCSR(configInterm, cOps, configInterm, cSRE, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cWebapp))
+	assert.NoError(t, manager.SignCSR(configInterm, cSRE, configServer, cWebapp, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "service"}, cService))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configServer, cService, Usage{}, trust))
+
+	certOps, err := readCertificate(cOps.CertPath())
+	assert.NoError(t, err)
+	certSRE, err := readCertificate(cSRE.CertPath())
+	assert.NoError(t, err)
+	certWebapp, err := readCertificate(cWebapp.CertPath())
+	assert.NoError(t, err)
+	certService, err := readCertificate(cService.CertPath())
+	assert.NoError(t, err)
+
+	cEmpty := Cert{Name: "empty", Type: CertTypeInterm}
+	assert.NoError(t, os.WriteFile(cEmpty.ChainPath(), nil, 0644))
+
+	tests := []struct {
+		title         string
+		c             Cert
+		cCA           Cert
+		expectedError string
+		expectedChain []*x509.Certificate
+	}{
+		{"InvalidCertType", cOps, cRoot, "only server and client certificates have full chain", nil},
+		{"InvalidCAType", cWebapp, cService, "CA can only be root ca or another intermediate CA", nil},
+		{"CertNotExist", Cert{Name: "unknown", Type: CertTypeServer}, cSRE, "open server/unknown.cert: no such file or directory", nil},
+		{"EmptyCAChain", cWebapp, cEmpty, "certificate chain of empty is empty", nil},
+		{"IntermServer", cWebapp, cSRE, "", []*x509.Certificate{certWebapp, certSRE, certOps}},
+		{"RootClient", cService, cRoot, "", []*x509.Certificate{certService}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := writeFullchain(test.c, test.cCA)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+
+				chain, err := readCertificateChain(test.c.FullchainPath())
+				assert.NoError(t, err)
+				assert.Len(t, chain, len(test.expectedChain))
+				for i := range chain {
+					assert.Equal(t, test.expectedChain[i].Raw, chain[i].Raw)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// requestFromCert declares a certificate request template with subject and subject alternative names of a certificate
+func requestFromCert(cert *x509.Certificate) *x509.CertificateRequest {
+	return &x509.CertificateRequest{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		IPAddresses:    cert.IPAddresses,
+		EmailAddresses: cert.EmailAddresses,
+		URIs:           cert.URIs,
+	}
+}
+
+// issuerOf returns the certificate authority in current workspace that has signed a certificate
+// An empty Cert is returned if no certificate authority other than the excluded one has signed the certificate.
+func issuerOf(cert *x509.Certificate, exclude Cert) (Cert, error) {
+	for _, certType := range []int{CertTypeRoot, CertTypeInterm} {
+		cCAs, err := ListCerts(certType)
+		if err != nil {
+			return Cert{}, err
+		}
+
+		for _, cCA := range cCAs {
+			if cCA == exclude {
+				continue
+			}
+
+			certCA, err := readCertificate(cCA.CertPath())
+			if err != nil {
+				continue
+			}
+
+			if cert.CheckSignatureFrom(certCA) == nil {
+				return cCA, nil
+			}
+		}
+	}
+
+	return Cert{}, nil
+}
+
+// FindIssuer returns the certificate authority in current workspace that has issued a certificate
+// A root certificate authority is its own issuer.
+func FindIssuer(c Cert) (Cert, error) {
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return Cert{}, err
+	}
+
+	if c.Type == CertTypeRoot {
+		return c, nil
+	}
+
+	cCA, err := issuerOf(cert, c)
+	if err != nil {
+		return Cert{}, err
+	}
+
+	if cCA.Type == 0 {
+		return Cert{}, errors.New("no certificate authority found for " + c.Name)
+	}
+
+	return cCA, nil
+}
+
+func copyFile(src, dest string, perm os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dest, data, perm)
+}
+
+// archiveFiles copies current cert file and optionally key file of a certificate to archive directory
+func archiveFiles(c Cert, cert *x509.Certificate, withKey bool) error {
+	if err := os.MkdirAll(DirArchive, 0755); err != nil {
+		return err
+	}
+
+	err := copyFile(c.CertPath(), c.ArchiveCertPath(cert.SerialNumber), 0644)
+	if err != nil {
+		return err
+	}
+
+	if withKey {
+		err = copyFile(c.KeyPath(), c.ArchiveKeyPath(cert.SerialNumber), 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateChains rewrites certificate chains of intermediate certificate authorities and full chains of leaf certificates issued by a certificate authority
+// A chain embeds the certificate of its issuer, so it becomes stale when the issuer is renewed.
+func updateChains(cCA Cert) error {
+	certCA, err := readCertificate(cCA.CertPath())
+	if err != nil {
+		return err
+	}
+
+	for _, certType := range []int{CertTypeServer, CertTypeClient} {
+		cLeaves, err := ListCerts(certType)
+		if err != nil {
+			return err
+		}
+
+		for _, cLeaf := range cLeaves {
+			cert, err := readCertificate(cLeaf.CertPath())
+			if err != nil {
+				return err
+			}
+
+			// Certificates signed by a previous key of certificate authority are left intact
+			if cert.CheckSignatureFrom(certCA) != nil {
+				continue
+			}
+
+			err = writeFullchain(cLeaf, cCA)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cInterms, err := ListCerts(CertTypeInterm)
+	if err != nil {
+		return err
+	}
+
+	for _, cInterm := range cInterms {
+		if cInterm == cCA {
+			continue
+		}
+
+		cert, err := readCertificate(cInterm.CertPath())
+		if err != nil {
+			return err
+		}
+
+		// Certificates signed by a previous key of certificate authority are left intact
+		if cert.CheckSignatureFrom(certCA) != nil {
+			continue
+		}
+
+		err = writeCertificateChain(cInterm, cCA)
+		if err != nil {
+			return err
+		}
+
+		err = updateChains(cInterm)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenewCert issues a new certificate with the same subject and subject alternative names of an existing certificate
+// The previous certificate is moved to archive directory, and the same key is reused unless rekey is set.
+// The renewal request is evaluated against the current trust policy of certificate authority before anything is changed,
+// since policies may have been tightened after the certificate was issued. Root certificate authority is renewed by itself without trust.
+// Certificates signed from external requests have no key in workspace and cannot be renewed, so a new request should be signed for them.
+func (m *x509Manager) RenewCert(configCA Config, cCA Cert, config Config, c Cert, rekey bool, trust TrustFunc) error {
+	return m.reissue(configCA, cCA, config, c, nil, rekey, trust)
+}
+
+// ReissueCert issues a new certificate with a new claim replacing an existing certificate
+// It is the same as RenewCert except the subject and subject alternative names are read from the claim.
+// A claim with no common name keeps the common name of the existing certificate.
+// Reissuing a certificate authority with a new subject requires renewing the certificates it has issued.
+func (m *x509Manager) ReissueCert(configCA Config, cCA Cert, config Config, c Cert, claim Claim, rekey bool, trust TrustFunc) error {
+	req, err := newCertificateRequest(claim)
+	if err != nil {
+		return err
+	}
+
+	return m.reissue(configCA, cCA, config, c, req, rekey, trust)
+}
+
+// reissue replaces an existing certificate with a new one for a certificate request template
+// If the template is not set, the subject and subject alternative names of the existing certificate are used.
+func (m *x509Manager) reissue(configCA Config, cCA Cert, config Config, c Cert, req *x509.CertificateRequest, rekey bool, trust TrustFunc) error {
+	if c.Type == CertTypeRoot && cCA != c {
+		return errors.New("root certificate authority can only be renewed by itself")
+	}
+
+	cert, err := readCertificate(c.CertPath())
+	if err != nil {
+		return err
+	}
+
+	// The key of an external request never leaves its host
+	if _, err = os.Stat(c.KeyPath()); os.IsNotExist(err) {
+		return errors.New(c.Name + " is signed from an external request and has no key in workspace, sign a new request for it instead")
+	}
+
+	var certCA *x509.Certificate
+	if c.Type != CertTypeRoot {
+		certCA, err = readCertificate(cCA.CertPath())
+		if err != nil {
+			return err
+		}
+
+		if err = cert.CheckSignatureFrom(certCA); err != nil {
+			return errors.New(c.Name + " is not issued by " + cCA.Name)
+		}
+	}
+
+	var privateKey crypto.Signer
+	if rekey {
+		_, privateKey, err = genKeyPair(config.Algorithm, config.Length)
+	} else {
+		privateKey, err = readPrivateKey(config.Password, c.KeyPath())
+	}
+	if err != nil {
+		return err
+	}
+
+	if req == nil {
+		req = requestFromCert(cert)
+	} else if req.Subject.CommonName == "" {
+		req.Subject.CommonName = cert.Subject.CommonName
+	}
+
+	var csrData []byte
+	if c.Type != CertTypeRoot {
+		csrData, err = x509.CreateCertificateRequest(rand.Reader, req, privateKey)
+		if err != nil {
+			return err
+		}
+
+		csr, err := x509.ParseCertificateRequest(csrData)
+		if err != nil {
+			return err
+		}
+
+		if violations := trust(certCA, csr, config.Days); len(violations) > 0 {
+			return &PolicyError{Violations: violations}
+		}
+	}
+
+	err = archiveFiles(c, cert, rekey)
+	if err != nil {
+		return err
+	}
+
+	// Renewed certificate authorities keep their path length constraints
+	if c.Type == CertTypeRoot || c.Type == CertTypeInterm {
+		config.MaxPathLen = maxPathLenFromCert(cert)
+	}
+
+	if c.Type == CertTypeRoot {
+		certData, err := selfSign(config, c, req, privateKey)
+		if err != nil {
+			return err
+		}
+
+		err = writePemFile(pemTypeCert, certData, c.CertPath())
+		if err != nil {
+			return err
+		}
+
+		err = recordCert(c, c, certData)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = writePemFile(pemTypeCSR, csrData, c.CSRPath())
+		if err != nil {
+			return err
+		}
+
+		err = m.SignCSR(configCA, cCA, config, c, usageFromCert(cert), trust)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The new key replaces the previous one only after the new certificate is issued
+	if rekey {
+		err = writePrivateKey(privateKey, config.Password, c.KeyPath())
+		if err != nil {
+			return err
+		}
+	}
+
+	err = archiveIndexed(c, cCA, cert, rekey)
+	if err != nil {
+		return err
+	}
+
+	if c.Type == CertTypeRoot || c.Type == CertTypeInterm {
+		return updateChains(c)
+	}
+
+	return nil
+}
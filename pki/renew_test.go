@@ -0,0 +1,258 @@
+package pki
+
+import (
+	"crypto/x509"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestFromCert(t *testing.T) {
+	cert := &x509.Certificate{
+		DNSNames:       []string{"example.com"},
+		EmailAddresses: []string{"admin@example.com"},
+	}
+	cert.Subject.CommonName = "webapp"
+
+	req := requestFromCert(cert)
+	assert.Equal(t, "webapp", req.Subject.CommonName)
+	assert.Equal(t, []string{"example.com"}, req.DNSNames)
+	assert.Equal(t, []string{"admin@example.com"}, req.EmailAddresses)
+}
+
+func TestRenewCert(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	// Intermediates sign other intermediates in this hierarchy
+	rootPathLen, intermPathLen := 2, 1
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, MaxPathLen: &rootPathLen, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, MaxPathLen: &intermPathLen, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 375}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cTeam := Cert{Name: "team", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "Team CA"}, cTeam))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configInterm, cTeam, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp", DNSName: []string{"example.com", "www.example.com"}}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+
+	t.Run("FindIssuer", func(t *testing.T) {
+		cCA, err := FindIssuer(cRoot)
+		assert.NoError(t, err)
+		assert.Equal(t, cRoot, cCA)
+
+		cCA, err = FindIssuer(cInterm)
+		assert.NoError(t, err)
+		assert.Equal(t, cRoot, cCA)
+
+		cCA, err = FindIssuer(cServer)
+		assert.NoError(t, err)
+		assert.Equal(t, cInterm, cCA)
+
+		_, err = FindIssuer(Cert{Name: "unknown", Type: CertTypeServer})
+		assert.Error(t, err)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		err := manager.RenewCert(configInterm, cInterm, configRoot, cRoot, false, trust)
+		assert.Error(t, err)
+
+		err = manager.RenewCert(configRoot, cRoot, configServer, cServer, false, trust)
+		assert.EqualError(t, err, "webapp is not issued by root")
+
+		err = manager.RenewCert(configRoot, cRoot, Config{Password: "wrong"}, cInterm, false, trust)
+		assert.Error(t, err)
+	})
+
+	t.Run("PolicyViolated", func(t *testing.T) {
+		// Trust policy of certificate authority is tightened after issuance
+		policy := Policy{DNSNames: []string{"*.sre.example.com"}}
+		err := manager.RenewCert(configInterm, cInterm, configServer, cServer, false, PolicyTrustFunc(policy, CertTypeServer))
+		assert.EqualError(t, err, "CSR does not satisfy CA trust policy: DNSNames: expected *.sre.example.com, got example.com (dns_names); DNSNames: expected *.sre.example.com, got www.example.com (dns_names)")
+
+		// Nothing is renewed or archived
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "1001", cert.SerialNumber.String())
+		assert.NoFileExists(t, "archive/webapp.1001.cert")
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		oldKey, err := os.ReadFile(cServer.KeyPath())
+		assert.NoError(t, err)
+
+		err = manager.RenewCert(configInterm, cInterm, configServer, cServer, false, trust)
+		assert.NoError(t, err)
+
+		newKey, err := os.ReadFile(cServer.KeyPath())
+		assert.NoError(t, err)
+		assert.Equal(t, oldKey, newKey)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "1002", cert.SerialNumber.String())
+		assert.Equal(t, "webapp", cert.Subject.CommonName)
+		assert.Equal(t, []string{"example.com", "www.example.com"}, cert.DNSNames)
+
+		archived, err := readCertificate("archive/webapp.1001.cert")
+		assert.NoError(t, err)
+		assert.Equal(t, "1001", archived.SerialNumber.String())
+		assert.NoFileExists(t, "archive/webapp.1001.key")
+
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+
+		oldEntry, ok := index.Lookup("sre", archived.SerialNumber)
+		assert.True(t, ok)
+		assert.Equal(t, "archive/webapp.1001.cert", oldEntry.CertPath)
+		assert.Equal(t, "server/webapp.key", oldEntry.KeyPath)
+
+		newEntry, ok := index.Lookup("sre", cert.SerialNumber)
+		assert.True(t, ok)
+		assert.Equal(t, "server/webapp.cert", newEntry.CertPath)
+	})
+
+	t.Run("ServerRekey", func(t *testing.T) {
+		oldKey, err := os.ReadFile(cServer.KeyPath())
+		assert.NoError(t, err)
+
+		err = manager.RenewCert(configInterm, cInterm, configServer, cServer, true, trust)
+		assert.NoError(t, err)
+
+		newKey, err := os.ReadFile(cServer.KeyPath())
+		assert.NoError(t, err)
+		assert.NotEqual(t, oldKey, newKey)
+
+		archivedKey, err := os.ReadFile("archive/webapp.1002.key")
+		assert.NoError(t, err)
+		assert.Equal(t, oldKey, archivedKey)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "1003", cert.SerialNumber.String())
+
+		key, err := readPrivateKey("", cServer.KeyPath())
+		assert.NoError(t, err)
+		assert.Equal(t, key.Public(), cert.PublicKey)
+
+		index, err := LoadIndex(FileIndex)
+		assert.NoError(t, err)
+
+		oldEntry, ok := index.Lookup("sre", big.NewInt(1002))
+		assert.True(t, ok)
+		assert.Equal(t, "archive/webapp.1002.key", oldEntry.KeyPath)
+	})
+
+	t.Run("ServerReissue", func(t *testing.T) {
+		claim := Claim{CommonName: "api", DNSName: []string{"example.com", "api.example.com"}}
+		err := manager.ReissueCert(configInterm, cInterm, configServer, cServer, claim, false, trust)
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "1004", cert.SerialNumber.String())
+		assert.Equal(t, "api", cert.Subject.CommonName)
+		assert.Equal(t, []string{"example.com", "api.example.com"}, cert.DNSNames)
+		assert.FileExists(t, "archive/webapp.1003.cert")
+	})
+
+	t.Run("ServerReissueKeepsCommonName", func(t *testing.T) {
+		claim := Claim{DNSName: []string{"example.com"}}
+		err := manager.ReissueCert(configInterm, cInterm, configServer, cServer, claim, false, trust)
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cServer.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "1005", cert.SerialNumber.String())
+		assert.Equal(t, "api", cert.Subject.CommonName)
+		assert.Equal(t, []string{"example.com"}, cert.DNSNames)
+	})
+
+	t.Run("Intermediate", func(t *testing.T) {
+		err := manager.RenewCert(configRoot, cRoot, configInterm, cInterm, false, trust)
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cInterm.CertPath())
+		assert.NoError(t, err)
+
+		// Team CA has taken serial number 102
+		assert.Equal(t, "103", cert.SerialNumber.String())
+
+		chain, err := readCertificateChain(cInterm.ChainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 2)
+		assert.Equal(t, cert.Raw, chain[0].Raw)
+
+		// Chain of subordinate intermediate CA embeds the renewed certificate
+		chain, err = readCertificateChain(cTeam.ChainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 3)
+		assert.Equal(t, cert.Raw, chain[1].Raw)
+
+		// Full chain of server certificate embeds the renewed certificate
+		chain, err = readCertificateChain(cServer.FullchainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 2)
+		assert.Equal(t, cert.Raw, chain[1].Raw)
+
+		// Certificates issued by intermediate CA are still valid
+		assert.NoError(t, manager.VerifyCert(cInterm, cServer, "example.com"))
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		err := manager.RenewCert(configRoot, cRoot, configRoot, cRoot, false, trust)
+		assert.NoError(t, err)
+
+		cert, err := readCertificate(cRoot.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, "12", cert.SerialNumber.String())
+		assert.Equal(t, "Root CA", cert.Subject.CommonName)
+		assert.FileExists(t, "archive/root.11.ca.cert")
+
+		chain, err := readCertificateChain(cTeam.ChainPath())
+		assert.NoError(t, err)
+		assert.Len(t, chain, 3)
+		assert.Equal(t, cert.Raw, chain[2].Raw)
+	})
+
+	t.Run("External", func(t *testing.T) {
+		cAgent := Cert{Name: "agent", Type: CertTypeClient}
+		writeExternalCSR(t, cAgent)
+		assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cAgent, Usage{}, trust))
+
+		cert, err := readCertificate(cAgent.CertPath())
+		assert.NoError(t, err)
+
+		for _, rekey := range []bool{false, true} {
+			err = manager.RenewCert(configInterm, cInterm, configServer, cAgent, rekey, trust)
+			assert.EqualError(t, err, "agent is signed from an external request and has no key in workspace, sign a new request for it instead")
+
+			err = manager.ReissueCert(configInterm, cInterm, configServer, cAgent, Claim{CommonName: "agent"}, rekey, trust)
+			assert.EqualError(t, err, "agent is signed from an external request and has no key in workspace, sign a new request for it instead")
+		}
+
+		// Nothing is renewed, archived, or written for the external certificate
+		renewed, err := readCertificate(cAgent.CertPath())
+		assert.NoError(t, err)
+		assert.Equal(t, cert.Raw, renewed.Raw)
+		assert.NoFileExists(t, cAgent.KeyPath())
+		assert.NoFileExists(t, cAgent.ArchiveCertPath(cert.SerialNumber))
+	})
+}
@@ -0,0 +1,92 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	secretAPIVersion = "v1"
+	secretKind       = "Secret"
+	secretTypeTLS    = "kubernetes.io/tls"
+
+	secretKeyCert = "tls.crt"
+	secretKeyKey  = "tls.key"
+	secretKeyCA   = "ca.crt"
+)
+
+type (
+	// SecretMeta represents the metadata of a Kubernetes secret
+	SecretMeta struct {
+		Name      string            `yaml:"name"`
+		Namespace string            `yaml:"namespace,omitempty"`
+		Labels    map[string]string `yaml:"labels,omitempty"`
+	}
+
+	// secret represents a Kubernetes secret manifest
+	secret struct {
+		APIVersion string            `yaml:"apiVersion"`
+		Kind       string            `yaml:"kind"`
+		Metadata   SecretMeta        `yaml:"metadata"`
+		Type       string            `yaml:"type"`
+		Data       map[string]string `yaml:"data"`
+	}
+)
+
+// ExportSecret writes the certificate, its private key, and its issuing chain as a kubernetes.io/tls secret manifest
+// tls.crt contains the certificate followed by intermediate certificate authorities and ca.crt contains the root certificate authority.
+// The secret of an intermediate certificate authority can be used by cert-manager CA issuers.
+// The private key is NOT encrypted in the secret.
+func (m *x509Manager) ExportSecret(config Config, c Cert, meta SecretMeta) ([]byte, error) {
+	if c.Type == CertTypeRoot {
+		return nil, errors.New("root certificate authority cannot be exported as secret")
+	}
+
+	if meta.Name == "" {
+		return nil, errors.New("secret name is not set")
+	}
+
+	key, cert, err := readKeyPair(config, c)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := issuingChain(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("issuing chain of " + c.Name + " is empty")
+	}
+
+	pemType, keyData, err := marshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The last certificate in issuing chain is the root certificate authority
+	certs := []*x509.Certificate{cert}
+	if len(chain) > 1 {
+		certs = append(certs, chain[:len(chain)-1]...)
+	}
+	root := chain[len(chain)-1]
+
+	s := secret{
+		APIVersion: secretAPIVersion,
+		Kind:       secretKind,
+		Metadata:   meta,
+		Type:       secretTypeTLS,
+		Data: map[string]string{
+			secretKeyCert: base64.StdEncoding.EncodeToString(encodeCertificates(certs)),
+			secretKeyKey:  base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: keyData})),
+			secretKeyCA:   base64.StdEncoding.EncodeToString(encodeCertificates([]*x509.Certificate{root})),
+		},
+	}
+
+	return yaml.Marshal(s)
+}
@@ -0,0 +1,156 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func decodeSecretData(t *testing.T, s secret, key string) []*pem.Block {
+	data, err := base64.StdEncoding.DecodeString(s.Data[key])
+	assert.NoError(t, err)
+
+	blocks := []*pem.Block{}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+func TestExportSecret(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	configRoot := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 7300, Password: "rootSecret"}
+	configInterm := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 3650, Password: "intermSecret"}
+	configServer := Config{Algorithm: AlgorithmRSA, Length: 2048, Days: 375}
+	configClient := Config{Algorithm: AlgorithmEd25519, Days: 40}
+
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cInterm := Cert{Name: "sre", Type: CertTypeInterm}
+	cServer := Cert{Name: "webapp", Type: CertTypeServer}
+	cClient := Cert{Name: "service", Type: CertTypeClient}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+
+	manager := NewX509Manager()
+
+	assert.NoError(t, manager.GenCert(configRoot, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(configInterm, Claim{CommonName: "SRE CA"}, cInterm))
+	assert.NoError(t, manager.SignCSR(configRoot, cRoot, configInterm, cInterm, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configServer, Claim{CommonName: "webapp"}, cServer))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configServer, cServer, Usage{}, trust))
+	assert.NoError(t, manager.GenCSR(configClient, Claim{CommonName: "service"}, cClient))
+	assert.NoError(t, manager.SignCSR(configInterm, cInterm, configClient, cClient, Usage{}, trust))
+
+	certRoot, err := readCertificate(cRoot.CertPath())
+	assert.NoError(t, err)
+	certInterm, err := readCertificate(cInterm.CertPath())
+	assert.NoError(t, err)
+	certServer, err := readCertificate(cServer.CertPath())
+	assert.NoError(t, err)
+	certClient, err := readCertificate(cClient.CertPath())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		config          Config
+		c               Cert
+		meta            SecretMeta
+		expectedKeyType string
+		expectedCerts   []*x509.Certificate
+	}{
+		{
+			"Intermediate",
+			configInterm,
+			cInterm,
+			SecretMeta{Name: "sre-ca", Namespace: "cert-manager"},
+			pemTypeECKey,
+			[]*x509.Certificate{certInterm},
+		},
+		{
+			"Server",
+			configServer,
+			cServer,
+			SecretMeta{Name: "webapp-tls", Namespace: "web", Labels: map[string]string{"app": "webapp"}},
+			pemTypeRSAKey,
+			[]*x509.Certificate{certServer, certInterm},
+		},
+		{
+			"Client",
+			configClient,
+			cClient,
+			SecretMeta{Name: "service-tls"},
+			pemTypeKey,
+			[]*x509.Certificate{certClient, certInterm},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := manager.ExportSecret(test.config, test.c, test.meta)
+			assert.NoError(t, err)
+
+			s := secret{}
+			assert.NoError(t, yaml.Unmarshal(data, &s))
+			assert.Equal(t, "v1", s.APIVersion)
+			assert.Equal(t, "Secret", s.Kind)
+			assert.Equal(t, "kubernetes.io/tls", s.Type)
+			assert.Equal(t, test.meta, s.Metadata)
+
+			blocks := decodeSecretData(t, s, "tls.crt")
+			assert.Len(t, blocks, len(test.expectedCerts))
+			for i := range blocks {
+				assert.Equal(t, pemTypeCert, blocks[i].Type)
+				assert.Equal(t, test.expectedCerts[i].Raw, blocks[i].Bytes)
+			}
+
+			blocks = decodeSecretData(t, s, "tls.key")
+			assert.Len(t, blocks, 1)
+			assert.Equal(t, test.expectedKeyType, blocks[0].Type)
+			key, err := parsePrivateKey(blocks[0].Type, blocks[0].Bytes)
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+
+			blocks = decodeSecretData(t, s, "ca.crt")
+			assert.Len(t, blocks, 1)
+			assert.Equal(t, certRoot.Raw, blocks[0].Bytes)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := manager.ExportSecret(configRoot, cRoot, SecretMeta{Name: "root"})
+		assert.EqualError(t, err, "root certificate authority cannot be exported as secret")
+
+		_, err = manager.ExportSecret(configServer, cServer, SecretMeta{})
+		assert.EqualError(t, err, "secret name is not set")
+
+		_, err = manager.ExportSecret(Config{Password: "wrong"}, cInterm, SecretMeta{Name: "sre"})
+		assert.Error(t, err)
+	})
+
+	t.Run("SingleCertChain", func(t *testing.T) {
+		// The chain of an imported intermediate may only have the intermediate itself
+		assert.NoError(t, os.WriteFile(cInterm.ChainPath(), encodeCertificates([]*x509.Certificate{certInterm}), 0644))
+
+		data, err := manager.ExportSecret(configServer, cServer, SecretMeta{Name: "webapp-tls"})
+		assert.NoError(t, err)
+
+		s := secret{}
+		assert.NoError(t, yaml.Unmarshal(data, &s))
+		assert.Len(t, decodeSecretData(t, s, "tls.crt"), 1)
+		blocks := decodeSecretData(t, s, "ca.crt")
+		assert.Len(t, blocks, 1)
+		assert.Equal(t, certInterm.Raw, blocks[0].Bytes)
+	})
+}
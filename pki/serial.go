@@ -0,0 +1,129 @@
+package pki
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+	"time"
+)
+
+const (
+	// CA/B Forum Baseline Requirements require at least 64 bits of entropy
+	randomSerialBits  = 128
+	randomSerialTries = 8
+
+	lockStateTries    = 50
+	lockStateInterval = 100 * time.Millisecond
+)
+
+// lockFile acquires an exclusive lock using a lock file
+func lockFile(lock string) (func(), error) {
+	for i := 0; i < lockStateTries; i++ {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(lock)
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		time.Sleep(lockStateInterval)
+	}
+
+	return nil, errors.New("workspace is locked by another process (remove " + lock + " if it is stale)")
+}
+
+// lockState acquires an exclusive lock on state file of workspace
+func lockState() (func(), error) {
+	return lockFile(FileStateLock)
+}
+
+func randomSerial() (*big.Int, error) {
+	max := new(big.Int).Lsh(big.NewInt(1), randomSerialBits)
+	for {
+		serial, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return nil, err
+		}
+
+		// Serial numbers must be positive
+		if serial.Sign() > 0 {
+			return serial, nil
+		}
+	}
+}
+
+// reserveSerial allocates a new serial number for a certificate type.
+// Sequential serial numbers are incremented and saved in state file before issuance,
+// so a serial number is never reused even if issuance fails.
+func reserveSerial(certType int) (*big.Int, bool, error) {
+	unlock, err := lockState()
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	state, err := LoadState(FileState)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if state.RandomSerial {
+		serial, err := randomSerial()
+		return serial, true, err
+	}
+
+	var config *Config
+	switch certType {
+	case CertTypeRoot:
+		config = &state.Root
+	case CertTypeInterm:
+		config = &state.Interm
+	case CertTypeServer:
+		config = &state.Server
+	case CertTypeClient:
+		config = &state.Client
+	default:
+		return nil, false, errors.New("invalid certificate type")
+	}
+
+	config.Serial++
+	err = SaveState(state, FileState)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return big.NewInt(config.Serial), false, nil
+}
+
+// nextSerial allocates a new serial number for a certificate type which is not issued by a certificate authority before
+func nextSerial(cCA Cert, certType int) (*big.Int, error) {
+	store, err := readStatusStore(cCA.StatusPath())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < randomSerialTries; i++ {
+		serial, random, err := reserveSerial(certType)
+		if err != nil {
+			return nil, err
+		}
+
+		j := store.find(serial)
+		if j < 0 {
+			return serial, nil
+		}
+
+		// A duplicate sequential serial number means the state file is out of sync with issued certificates
+		if !random {
+			return nil, errors.New("serial number " + serial.String() + " is already issued by " + cCA.Name + " to " + store.Certificates[j].Name)
+		}
+	}
+
+	return nil, errors.New("failed to allocate a unique serial number")
+}
@@ -0,0 +1,116 @@
+package pki
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockState(t *testing.T) {
+	unlock, err := lockState()
+	assert.NoError(t, err)
+	_, err = os.Stat(FileStateLock)
+	assert.NoError(t, err)
+
+	unlock()
+	_, err = os.Stat(FileStateLock)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRandomSerial(t *testing.T) {
+	max := new(big.Int).Lsh(big.NewInt(1), randomSerialBits)
+
+	for i := 0; i < 10; i++ {
+		serial, err := randomSerial()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, serial.Sign())
+		assert.Equal(t, -1, serial.Cmp(max))
+	}
+}
+
+func TestReserveSerial(t *testing.T) {
+	t.Run("NoWorkspace", func(t *testing.T) {
+		_, _, err := reserveSerial(CertTypeServer)
+		assert.Error(t, err)
+	})
+
+	t.Run("Sequential", func(t *testing.T) {
+		err := NewWorkspace(NewState(), NewSpec())
+		assert.NoError(t, err)
+		defer CleanupWorkspace() // nolint: errcheck
+
+		_, _, err = reserveSerial(-1)
+		assert.Error(t, err)
+
+		tests := []struct {
+			certType       int
+			expectedSerial int64
+		}{
+			{CertTypeRoot, 11},
+			{CertTypeInterm, 101},
+			{CertTypeServer, 1001},
+			{CertTypeServer, 1002},
+			{CertTypeClient, 10001},
+		}
+
+		for _, test := range tests {
+			serial, random, err := reserveSerial(test.certType)
+			assert.NoError(t, err)
+			assert.False(t, random)
+			assert.Equal(t, big.NewInt(test.expectedSerial), serial)
+
+			// Serial number is saved in state file
+			state, err := LoadState(FileState)
+			assert.NoError(t, err)
+			config, _ := state.ConfigFor(test.certType)
+			assert.Equal(t, test.expectedSerial, config.Serial)
+		}
+
+		_, err = os.Stat(FileStateLock)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		state := NewState()
+		state.RandomSerial = true
+		err := NewWorkspace(state, NewSpec())
+		assert.NoError(t, err)
+		defer CleanupWorkspace() // nolint: errcheck
+
+		s1, random, err := reserveSerial(CertTypeServer)
+		assert.NoError(t, err)
+		assert.True(t, random)
+		s2, _, err := reserveSerial(CertTypeServer)
+		assert.NoError(t, err)
+		assert.NotEqual(t, s1, s2)
+
+		// Sequential serial number is not changed
+		s, err := LoadState(FileState)
+		assert.NoError(t, err)
+		assert.Equal(t, defaultServerCertSerial, s.Server.Serial)
+	})
+}
+
+func TestNextSerial(t *testing.T) {
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	cCA := Cert{Name: "sre", Type: CertTypeInterm}
+
+	serial, err := nextSerial(cCA, CertTypeServer)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1001), serial)
+	assert.NoError(t, recordIssued(cCA, "webapp", serial))
+
+	// Serial numbers already issued are refused
+	assert.NoError(t, recordIssued(cCA, "service", big.NewInt(1002)))
+	_, err = nextSerial(cCA, CertTypeServer)
+	assert.Error(t, err)
+
+	serial, err = nextSerial(cCA, CertTypeServer)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1003), serial)
+}
@@ -0,0 +1,57 @@
+package pki
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+const schemeSPIFFE = "spiffe"
+
+var trustDomainRegex = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// parseURIs parses URI subject alternative names of a claim
+func parseURIs(uris []string) ([]*url.URL, error) {
+	var urls []*url.URL
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" {
+			return nil, errors.New("invalid URI: " + s)
+		}
+		urls = append(urls, u)
+	}
+
+	return urls, nil
+}
+
+// validateTrustDomain checks if a string is a valid SPIFFE trust domain name
+func validateTrustDomain(trustDomain string) error {
+	if !trustDomainRegex.MatchString(trustDomain) {
+		return errors.New("invalid SPIFFE trust domain: " + trustDomain)
+	}
+
+	return nil
+}
+
+// checkSPIFFEID checks if the URI subject alternative names of a certificate request are exactly one SPIFFE ID within a trust domain
+func checkSPIFFEID(trustDomain string, uris []*url.URL) error {
+	if len(uris) != 1 {
+		return fmt.Errorf("exactly one SPIFFE ID is required in trust domain %s but %d URIs are requested", trustDomain, len(uris))
+	}
+
+	u := uris[0]
+	if u.Scheme != schemeSPIFFE || u.Opaque != "" || u.User != nil || u.Port() != "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("invalid SPIFFE ID: " + u.String())
+	}
+
+	if u.Host != trustDomain {
+		return fmt.Errorf("SPIFFE ID %s is not in trust domain %s", u, trustDomain)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		return fmt.Errorf("SPIFFE ID %s does not identify a workload", u)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseURIs(t *testing.T) {
+	tests := []struct {
+		name          string
+		uris          []string
+		expectedURIs  []string
+		expectedError string
+	}{
+		{"Empty", nil, []string{}, ""},
+		{"SPIFFE", []string{"spiffe://example.org/ns/ops/sa/webapp"}, []string{"spiffe://example.org/ns/ops/sa/webapp"}, ""},
+		{"Multiple", []string{"spiffe://example.org/webapp", "https://webapp.example.org"}, []string{"spiffe://example.org/webapp", "https://webapp.example.org"}, ""},
+		{"NoScheme", []string{"example.org/webapp"}, nil, "invalid URI: example.org/webapp"},
+		{"Invalid", []string{"spiffe://[::1"}, nil, "invalid URI: spiffe://[::1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uris, err := parseURIs(test.uris)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedURIs, uriStrings(uris))
+			}
+		})
+	}
+}
+
+func TestValidateTrustDomain(t *testing.T) {
+	tests := []struct {
+		trustDomain   string
+		expectedError string
+	}{
+		{"example.org", ""},
+		{"prod_cluster-1.example.org", ""},
+		{"Example.org", "invalid SPIFFE trust domain: Example.org"},
+		{"example.org:8443", "invalid SPIFFE trust domain: example.org:8443"},
+		{"", "invalid SPIFFE trust domain: "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.trustDomain, func(t *testing.T) {
+			err := validateTrustDomain(test.trustDomain)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckSPIFFEID(t *testing.T) {
+	tests := []struct {
+		name          string
+		uris          []string
+		expectedError string
+	}{
+		{"Valid", []string{"spiffe://example.org/ns/ops/sa/webapp"}, ""},
+		{"None", nil, "exactly one SPIFFE ID is required in trust domain example.org but 0 URIs are requested"},
+		{"Multiple", []string{"spiffe://example.org/webapp", "spiffe://example.org/worker"}, "exactly one SPIFFE ID is required in trust domain example.org but 2 URIs are requested"},
+		{"NotSPIFFE", []string{"https://example.org/webapp"}, "invalid SPIFFE ID: https://example.org/webapp"},
+		{"Port", []string{"spiffe://example.org:8443/webapp"}, "invalid SPIFFE ID: spiffe://example.org:8443/webapp"},
+		{"Query", []string{"spiffe://example.org/webapp?v=1"}, "invalid SPIFFE ID: spiffe://example.org/webapp?v=1"},
+		{"OtherTrustDomain", []string{"spiffe://example.com/webapp"}, "SPIFFE ID spiffe://example.com/webapp is not in trust domain example.org"},
+		{"NoWorkload", []string{"spiffe://example.org"}, "SPIFFE ID spiffe://example.org does not identify a workload"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uris, err := parseURIs(test.uris)
+			assert.NoError(t, err)
+
+			err = checkSPIFFEID("example.org", uris)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSignCSRSPIFFE(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	config := Config{Algorithm: AlgorithmECDSA, Length: 256, Days: 30, Password: "password"}
+	cRoot := Cert{Name: "root", Type: CertTypeRoot}
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cWebapp := Cert{Name: "webapp", Type: CertTypeClient}
+	cWorker := Cert{Name: "worker", Type: CertTypeClient}
+	trust := func(*x509.Certificate, *x509.CertificateRequest, int) []Violation { return nil }
+	usage := Usage{SPIFFETrustDomain: "example.org"}
+
+	manager := NewX509Manager()
+	assert.NoError(t, manager.GenCert(config, Claim{CommonName: "Root CA"}, cRoot))
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "Ops CA"}, cOps))
+	assert.NoError(t, manager.SignCSR(config, cRoot, config, cOps, Usage{}, trust))
+
+	err = manager.GenCSR(config, Claim{CommonName: "webapp", URI: []string{"webapp"}}, cWebapp)
+	assert.EqualError(t, err, "invalid URI: webapp")
+
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "webapp", URI: []string{"spiffe://example.org/ns/ops/sa/webapp"}}, cWebapp))
+	assert.NoError(t, manager.SignCSR(config, cOps, config, cWebapp, usage, trust))
+
+	cert, err := readCertificate(cWebapp.CertPath())
+	assert.NoError(t, err)
+	assert.Equal(t, []*url.URL{&url.URL{Scheme: "spiffe", Host: "example.org", Path: "/ns/ops/sa/webapp"}}, cert.URIs)
+
+	assert.NoError(t, manager.GenCSR(config, Claim{CommonName: "worker", URI: []string{"spiffe://example.com/worker"}}, cWorker))
+	err = manager.SignCSR(config, cOps, config, cWorker, usage, trust)
+	assert.EqualError(t, err, "SPIFFE ID spiffe://example.com/worker is not in trust domain example.org")
+	assert.NoFileExists(t, cWorker.CertPath())
+}
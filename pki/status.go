@@ -0,0 +1,154 @@
+package pki
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// StatusGood is the status of a valid certificate
+	StatusGood = "good"
+	// StatusRevoked is the status of a revoked certificate
+	StatusRevoked = "revoked"
+	// StatusUnknown is the status of a certificate not issued by a certificate authority
+	StatusUnknown = "unknown"
+)
+
+func readStatusStore(path string) (*StatusStore, error) {
+	store := new(StatusStore)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, store)
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func writeStatusStore(store *StatusStore, path string) error {
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return err
+	}
+
+	// Workspaces created by older versions do not have this directory
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first and rename it, so status store is never left partially written
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// updateStatusStore loads, updates, and saves status store of a certificate authority while holding a lock on it
+func updateStatusStore(cCA Cert, update func(*StatusStore) error) error {
+	path := cCA.StatusPath()
+
+	// Workspaces created by older versions do not have this directory
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	store, err := readStatusStore(path)
+	if err != nil {
+		return err
+	}
+
+	err = update(store)
+	if err != nil {
+		return err
+	}
+
+	return writeStatusStore(store, path)
+}
+
+// find returns the index of a certificate status by serial number or -1 if not found
+func (s *StatusStore) find(serial *big.Int) int {
+	for i, cs := range s.Certificates {
+		if n, ok := new(big.Int).SetString(cs.Serial, 10); ok && n.Cmp(serial) == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Lookup returns the status of a certificate by serial number
+func (s *StatusStore) Lookup(serial *big.Int) CertStatus {
+	if i := s.find(serial); i >= 0 {
+		return s.Certificates[i]
+	}
+
+	return CertStatus{
+		Serial: serial.String(),
+		Status: StatusUnknown,
+	}
+}
+
+// issue records a certificate as good
+func (s *StatusStore) issue(name string, serial *big.Int) error {
+	if i := s.find(serial); i >= 0 {
+		return errors.New("serial number " + serial.String() + " is already issued to " + s.Certificates[i].Name)
+	}
+
+	s.Certificates = append(s.Certificates, CertStatus{
+		Name:   name,
+		Serial: serial.String(),
+		Status: StatusGood,
+	})
+
+	return nil
+}
+
+// revoke records a certificate as revoked
+func (s *StatusStore) revoke(name string, serial *big.Int, reason string, revokedAt time.Time) error {
+	i := s.find(serial)
+	if i < 0 {
+		// Certificates issued by older versions are not recorded
+		s.Certificates = append(s.Certificates, CertStatus{
+			Name:   name,
+			Serial: serial.String(),
+		})
+		i = len(s.Certificates) - 1
+	} else if s.Certificates[i].Status == StatusRevoked {
+		return errors.New(name + " is already revoked")
+	}
+
+	s.Certificates[i].Status = StatusRevoked
+	s.Certificates[i].Reason = reason
+	s.Certificates[i].RevokedAt = revokedAt
+
+	return nil
+}
+
+func recordIssued(cCA Cert, name string, serial *big.Int) error {
+	return updateStatusStore(cCA, func(store *StatusStore) error {
+		return store.issue(name, serial)
+	})
+}
@@ -0,0 +1,100 @@
+package pki
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moorara/gocert/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReadStatusStore(t *testing.T) {
+	path, cleanup, err := util.CreateTempFile("")
+	defer cleanup()
+	assert.NoError(t, err)
+
+	revokedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &StatusStore{
+		CRLNumber: 2,
+		Certificates: []CertStatus{
+			{Name: "webapp", Serial: "1001", Status: StatusRevoked, Reason: "keyCompromise", RevokedAt: revokedAt},
+			{Name: "service", Serial: "1002", Status: StatusGood},
+		},
+	}
+
+	err = writeStatusStore(store, path)
+	assert.NoError(t, err)
+
+	s, err := readStatusStore(path)
+	assert.NoError(t, err)
+	assert.Equal(t, store, s)
+
+	// A missing file means no certificate is recorded
+	s, err = readStatusStore("missing.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, &StatusStore{}, s)
+}
+
+func TestStatusStore(t *testing.T) {
+	revokedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &StatusStore{}
+
+	assert.NoError(t, store.issue("webapp", big.NewInt(1001)))
+	assert.NoError(t, store.issue("service", big.NewInt(1002)))
+
+	cs := store.Lookup(big.NewInt(1001))
+	assert.Equal(t, CertStatus{Name: "webapp", Serial: "1001", Status: StatusGood}, cs)
+
+	cs = store.Lookup(big.NewInt(1003))
+	assert.Equal(t, CertStatus{Serial: "1003", Status: StatusUnknown}, cs)
+
+	err := store.revoke("webapp", big.NewInt(1001), "keyCompromise", revokedAt)
+	assert.NoError(t, err)
+
+	cs = store.Lookup(big.NewInt(1001))
+	assert.Equal(t, CertStatus{Name: "webapp", Serial: "1001", Status: StatusRevoked, Reason: "keyCompromise", RevokedAt: revokedAt}, cs)
+
+	err = store.revoke("webapp", big.NewInt(1001), "superseded", revokedAt)
+	assert.Error(t, err)
+
+	// Certificates not recorded can still be revoked
+	err = store.revoke("worker", big.NewInt(1004), "", revokedAt)
+	assert.NoError(t, err)
+	assert.Len(t, store.Certificates, 3)
+
+	cs = store.Lookup(big.NewInt(1004))
+	assert.Equal(t, StatusRevoked, cs.Status)
+
+	// A serial number cannot be issued twice
+	err = store.issue("service2", big.NewInt(1002))
+	assert.Error(t, err)
+	assert.Len(t, store.Certificates, 3)
+	assert.Equal(t, "service", store.Lookup(big.NewInt(1002)).Name)
+}
+
+func TestRecordIssuedConcurrently(t *testing.T) {
+	err := NewWorkspace(NewState(), NewSpec())
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	cCA := Cert{Name: "ops", Type: CertTypeInterm}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, recordIssued(cCA, "server", big.NewInt(int64(i))))
+		}(i)
+	}
+	wg.Wait()
+
+	// No entry is lost and the serial numbers stay unique
+	store, err := readStatusStore(cCA.StatusPath())
+	assert.NoError(t, err)
+	assert.Len(t, store.Certificates, 10)
+	assert.Error(t, recordIssued(cCA, "server", big.NewInt(1)))
+	assert.NoFileExists(t, cCA.StatusPath()+".lock")
+}
@@ -1,32 +1,194 @@
 package pki
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"path"
 	"reflect"
 	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ruleRequest                     = "request"
+	ruleTypes                       = "types"
+	ruleMatch                       = "match"
+	ruleSupplied                    = "supplied"
+	ruleCommonNames                 = "common_names"
+	ruleDNSNames                    = "dns_names"
+	ruleForbidWildcard              = "forbid_wildcard"
+	ruleIPRanges                    = "ip_ranges"
+	ruleMaxDays                     = "max_days"
+	ruleAlgorithms                  = "algorithms"
+	ruleMinKeyLength                = "min_key_length"
+	ruleRequiredOrganizationalUnits = "required_organizational_units"
 )
 
 var (
-	regex = map[string]*regexp.Regexp{
-		"CommonName":         regexp.MustCompile("(?i)^Common[_-]?Name$"),
-		"Country":            regexp.MustCompile("(?i)^Country$"),
-		"Province":           regexp.MustCompile("(?i)^Province$"),
-		"Locality":           regexp.MustCompile("(?i)^Locality$"),
-		"Organization":       regexp.MustCompile("(?i)^Organization$"),
-		"OrganizationalUnit": regexp.MustCompile("(?i)^Organizational[_-]?Unit$"),
-		"DNSNames":           regexp.MustCompile("(?i)^DNS[_-]?Name(s)?$"),
-		"IPAddresses":        regexp.MustCompile("(?i)^IP[_-]?Address(es)?$"),
-		"EmailAddresses":     regexp.MustCompile("(?i)^Email[_-]?Address(es)?$"),
-		"StreetAddress":      regexp.MustCompile("(?i)^Street[_-]?Address$"),
-		"PostalCode":         regexp.MustCompile("(?i)^Postal[_-]?Code$"),
+	// regex lists the fields of a request which can be matched or supplied in a fixed order,
+	// so violations of trust policy are always reported in the same order
+	regex = []struct {
+		key string
+		re  *regexp.Regexp
+	}{
+		{"CommonName", regexp.MustCompile("(?i)^Common[_-]?Name$")},
+		{"Country", regexp.MustCompile("(?i)^Country$")},
+		{"Province", regexp.MustCompile("(?i)^Province$")},
+		{"Locality", regexp.MustCompile("(?i)^Locality$")},
+		{"Organization", regexp.MustCompile("(?i)^Organization$")},
+		{"OrganizationalUnit", regexp.MustCompile("(?i)^Organizational[_-]?Unit$")},
+		{"DNSNames", regexp.MustCompile("(?i)^DNS[_-]?Name(s)?$")},
+		{"IPAddresses", regexp.MustCompile("(?i)^IP[_-]?Address(es)?$")},
+		{"EmailAddresses", regexp.MustCompile("(?i)^Email[_-]?Address(es)?$")},
+		{"URIs", regexp.MustCompile("(?i)^URI(s)?$")},
+		{"StreetAddress", regexp.MustCompile("(?i)^Street[_-]?Address$")},
+		{"PostalCode", regexp.MustCompile("(?i)^Postal[_-]?Code$")},
 	}
 )
 
+const (
+	valueNone = "none"
+	valueAny  = "any value"
+)
+
 type (
-	// TrustFunc is the function for determing if a ca can sign a csr
-	TrustFunc func(*x509.Certificate, *x509.CertificateRequest) bool
+	// TrustFunc is the function for determing if a ca can sign a csr for a number of days
+	// It returns the violations of trust policy, and no violation means the csr can be signed.
+	TrustFunc func(*x509.Certificate, *x509.CertificateRequest, int) []Violation
+
+	// Violation represents the type for a field of a certificate signing request not satisfying a trust policy rule
+	// Rule is the key of the policy rule in spec (e.g. dns_names or max_days).
+	Violation struct {
+		Field    string
+		Expected string
+		Actual   string
+		Rule     string
+	}
+
+	// PolicyError represents the error for a certificate signing request not satisfying trust policy of a certificate authority
+	PolicyError struct {
+		Violations []Violation
+	}
 )
 
+// String returns a human-readable description of a violation
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s (%s)", v.Field, v.Expected, v.Actual, v.Rule)
+}
+
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+
+	return "CSR does not satisfy CA trust policy: " + strings.Join(msgs, "; ")
+}
+
+// formatValue returns a human-readable string for the value of a field
+func formatValue(v reflect.Value) string {
+	if v == (reflect.Value{}) {
+		return valueNone
+	}
+
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		v = reflect.ValueOf(strings.Join(items, ", "))
+	}
+
+	if str := fmt.Sprint(v.Interface()); str != "" {
+		return str
+	}
+
+	return valueNone
+}
+
+// fieldValue returns the value of a field or a subject field of a certificate or certificate signing request
+func fieldValue(obj interface{}, fieldName string) reflect.Value {
+	v := reflect.ValueOf(obj).Elem()
+	if fv := v.FieldByName(fieldName); fv != (reflect.Value{}) {
+		return fv
+	}
+
+	return v.FieldByName("Subject").FieldByName(fieldName)
+}
+
+func formatList(list []string) string {
+	if len(list) == 0 {
+		return valueNone
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// isRegexPattern determines whether or not a pattern is a regular expression between slashes
+func isRegexPattern(pattern string) bool {
+	return len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// matchPattern determines whether or not a name matches a pattern
+// A pattern between slashes is a regular expression (e.g. /^web-[0-9]+$/).
+// Any other pattern is a case-insensitive glob matched label by label, so *.example.com matches www.example.com but not example.com or a.b.example.com.
+func matchPattern(pattern, name string) bool {
+	if isRegexPattern(pattern) {
+		matched, err := regexp.MatchString(pattern[1:len(pattern)-1], name)
+		return err == nil && matched
+	}
+
+	patternLabels := strings.Split(strings.ToLower(pattern), ".")
+	nameLabels := strings.Split(strings.ToLower(name), ".")
+	if len(patternLabels) != len(nameLabels) {
+		return false
+	}
+
+	for i := range patternLabels {
+		if matched, err := path.Match(patternLabels[i], nameLabels[i]); err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyAlgorithm returns the algorithm and length of a public key
+// The length of an ECDSA key is the size of its curve.
+func keyAlgorithm(pub crypto.PublicKey) (string, int) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRSA, key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return AlgorithmECDSA, key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, 256
+	default:
+		return "", 0
+	}
+}
+
+func validAlgorithm(algorithm string) bool {
+	return algorithm == AlgorithmRSA || algorithm == AlgorithmECDSA || algorithm == AlgorithmEd25519
+}
+
 func matches(cert *x509.Certificate, req *x509.CertificateRequest, fieldName string) bool {
 	zero := reflect.Value{}
 
@@ -67,31 +229,169 @@ func supplied(req *x509.CertificateRequest, fieldName string) bool {
 	return true
 }
 
-// PolicyTrustFunc returns a TrustFunc using Policy
-func PolicyTrustFunc(policy Policy) TrustFunc {
-	return func(ca *x509.Certificate, csr *x509.CertificateRequest) bool {
+// Validate checks if rules of a policy are well-formed
+func (p Policy) Validate() error {
+	for _, name := range p.Types {
+		if certType := ParseCertType(name); certType == 0 || certType == CertTypeRoot {
+			return errors.New("invalid certificate type: " + name)
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, p.CommonNames...), p.DNSNames...) {
+		var err error
+		if isRegexPattern(pattern) {
+			_, err = regexp.Compile(pattern[1 : len(pattern)-1])
+		} else {
+			_, err = path.Match(pattern, "")
+		}
+
+		if err != nil {
+			return errors.New("invalid pattern: " + pattern)
+		}
+	}
+
+	if _, err := parseIPRanges(p.IPRanges); err != nil {
+		return err
+	}
+
+	if p.MaxDays < 0 {
+		return errors.New("max_days cannot be negative")
+	}
+
+	for _, algorithm := range p.Algorithms {
+		if !validAlgorithm(algorithm) {
+			return errors.New("invalid algorithm: " + algorithm)
+		}
+	}
+
+	// Algorithms are sorted, so the same error is always reported
+	algorithms := make([]string, 0, len(p.MinKeyLength))
+	for algorithm := range p.MinKeyLength {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+
+	for _, algorithm := range algorithms {
+		if !validAlgorithm(algorithm) {
+			return errors.New("invalid algorithm: " + algorithm)
+		}
+		if p.MinKeyLength[algorithm] < 0 {
+			return errors.New("min_key_length cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// PolicyTrustFunc returns a TrustFunc using Policy for a type of certificate
+// All rules of policy are evaluated, so every violation of policy is returned.
+func PolicyTrustFunc(policy Policy, certType int) TrustFunc {
+	return func(ca *x509.Certificate, csr *x509.CertificateRequest, days int) []Violation {
 		if ca == nil || csr == nil {
-			return false
+			return []Violation{{"Request", "certificate authority and request", valueNone, ruleRequest}}
+		}
+
+		var violations []Violation
+		add := func(field, expected, actual, rule string) {
+			violations = append(violations, Violation{field, expected, actual, rule})
+		}
+
+		if typeName := (Cert{Type: certType}).TypeName(); len(policy.Types) > 0 && !contains(policy.Types, typeName) {
+			add("Type", formatList(policy.Types), typeName, ruleTypes)
 		}
 
 		// These fields should be matched
 		for _, field := range policy.Match {
-			for key, re := range regex {
-				if re.MatchString(field) && !matches(ca, csr, key) {
-					return false
+			for _, r := range regex {
+				if r.re.MatchString(field) && !matches(ca, csr, r.key) {
+					add(r.key, formatValue(fieldValue(ca, r.key)), formatValue(fieldValue(csr, r.key)), ruleMatch)
 				}
 			}
 		}
 
 		// These fields should be present
 		for _, field := range policy.Supplied {
-			for key, re := range regex {
-				if re.MatchString(field) && !supplied(csr, key) {
-					return false
+			for _, r := range regex {
+				if r.re.MatchString(field) && !supplied(csr, r.key) {
+					add(r.key, valueAny, valueNone, ruleSupplied)
 				}
 			}
 		}
 
-		return true
+		if len(policy.CommonNames) > 0 && !matchAnyPattern(policy.CommonNames, csr.Subject.CommonName) {
+			add("CommonName", formatList(policy.CommonNames), formatValue(reflect.ValueOf(csr.Subject.CommonName)), ruleCommonNames)
+		}
+
+		if len(policy.DNSNames) > 0 {
+			for _, name := range csr.DNSNames {
+				if !matchAnyPattern(policy.DNSNames, name) {
+					add("DNSNames", formatList(policy.DNSNames), name, ruleDNSNames)
+				}
+			}
+		}
+
+		if policy.ForbidWildcard {
+			if strings.Contains(csr.Subject.CommonName, "*") {
+				add("CommonName", "no wildcard", csr.Subject.CommonName, ruleForbidWildcard)
+			}
+
+			for _, name := range csr.DNSNames {
+				if strings.Contains(name, "*") {
+					add("DNSNames", "no wildcard", name, ruleForbidWildcard)
+				}
+			}
+		}
+
+		if len(policy.IPRanges) > 0 {
+			// Policy is ensured to be valid, and no IP address is in any range otherwise
+			ipNets, _ := parseIPRanges(policy.IPRanges)
+			for _, ip := range csr.IPAddresses {
+				if !containsIP(ipNets, ip) {
+					add("IPAddresses", formatList(policy.IPRanges), ip.String(), ruleIPRanges)
+				}
+			}
+		}
+
+		if policy.MaxDays > 0 && days > policy.MaxDays {
+			add("Days", fmt.Sprintf("at most %d", policy.MaxDays), fmt.Sprint(days), ruleMaxDays)
+		}
+
+		algorithm, length := keyAlgorithm(csr.PublicKey)
+
+		if len(policy.Algorithms) > 0 && !contains(policy.Algorithms, algorithm) {
+			add("Algorithm", formatList(policy.Algorithms), formatValue(reflect.ValueOf(algorithm)), ruleAlgorithms)
+		}
+
+		if min, ok := policy.MinKeyLength[algorithm]; ok && length < min {
+			add("Length", fmt.Sprintf("at least %d for %s", min, algorithm), fmt.Sprint(length), ruleMinKeyLength)
+		}
+
+		for _, ou := range policy.RequiredOrganizationalUnits {
+			if !contains(csr.Subject.OrganizationalUnit, ou) {
+				add("OrganizationalUnit", ou, formatList(csr.Subject.OrganizationalUnit), ruleRequiredOrganizationalUnits)
+			}
+		}
+
+		return violations
+	}
+}
+
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
 	}
+
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
@@ -1,6 +1,11 @@
 package pki
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"net"
@@ -799,10 +804,242 @@ func TestPolicyTrustFunc(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.title, func(t *testing.T) {
-			trust := PolicyTrustFunc(test.policy)
-			result := trust(test.ca, test.csr)
+			trust := PolicyTrustFunc(test.policy, CertTypeServer)
+			violations := trust(test.ca, test.csr, 0)
 
-			assert.Equal(t, test.expectedResult, result)
+			assert.Equal(t, test.expectedResult, len(violations) == 0)
+		})
+	}
+}
+
+func TestViolation(t *testing.T) {
+	v := Violation{Field: "DNSNames", Expected: "*.example.com", Actual: "example.org", Rule: "dns_names"}
+	assert.Equal(t, "DNSNames: expected *.example.com, got example.org (dns_names)", v.String())
+
+	err := &PolicyError{Violations: []Violation{v, {Field: "Days", Expected: "at most 90", Actual: "375", Rule: "max_days"}}}
+	assert.EqualError(t, err, "CSR does not satisfy CA trust policy: DNSNames: expected *.example.com, got example.org (dns_names); Days: expected at most 90, got 375 (max_days)")
+}
+
+func TestFormatValue(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:     pkix.Name{Country: []string{"CA", "US"}},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+	}
+
+	assert.Equal(t, "CA, US", formatValue(fieldValue(cert, "Country")))
+	assert.Equal(t, "10.0.0.1", formatValue(fieldValue(cert, "IPAddresses")))
+	assert.Equal(t, "none", formatValue(fieldValue(cert, "CommonName")))
+	assert.Equal(t, "none", formatValue(fieldValue(cert, "DNSNames")))
+	assert.Equal(t, "none", formatValue(fieldValue(cert, "Unknown")))
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		name          string
+		expectedMatch bool
+	}{
+		{"webapp.example.com", "webapp.example.com", true},
+		{"webapp.example.com", "WebApp.Example.com", true},
+		{"*.example.com", "webapp.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", false},
+		{"web-?.example.com", "web-1.example.com", true},
+		{"*.sre.example.com", "webapp.example.com", false},
+		{"*CA", "SRE CA", true},
+		{"/^web-[0-9]+$/", "web-42", true},
+		{"/^web-[0-9]+$/", "web-x", false},
+		{"/[/", "[", false},
+		{"[", "[", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+"/"+test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedMatch, matchPattern(test.pattern, test.name))
+		})
+	}
+}
+
+func TestKeyAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	algorithm, length := keyAlgorithm(&rsaKey.PublicKey)
+	assert.Equal(t, AlgorithmRSA, algorithm)
+	assert.Equal(t, 1024, length)
+
+	algorithm, length = keyAlgorithm(&ecdsaKey.PublicKey)
+	assert.Equal(t, AlgorithmECDSA, algorithm)
+	assert.Equal(t, 384, length)
+
+	algorithm, length = keyAlgorithm(edKey)
+	assert.Equal(t, AlgorithmEd25519, algorithm)
+	assert.Equal(t, 256, length)
+
+	algorithm, length = keyAlgorithm(nil)
+	assert.Equal(t, "", algorithm)
+	assert.Equal(t, 0, length)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        Policy
+		expectedError string
+	}{
+		{"Empty", Policy{}, ""},
+		{"Valid", Policy{
+			Types:        []string{"server", "client"},
+			CommonNames:  []string{"*.example.com", "/^web-[0-9]+$/"},
+			DNSNames:     []string{"*.example.com"},
+			IPRanges:     []string{"10.0.0.0/8"},
+			MaxDays:      90,
+			Algorithms:   []string{"rsa", "ecdsa"},
+			MinKeyLength: map[string]int{"rsa": 2048, "ecdsa": 256},
+		}, ""},
+		{"InvalidType", Policy{Types: []string{"root"}}, "invalid certificate type: root"},
+		{"InvalidRegex", Policy{CommonNames: []string{"/[/"}}, "invalid pattern: /[/"},
+		{"InvalidGlob", Policy{DNSNames: []string{"[.example.com"}}, "invalid pattern: [.example.com"},
+		{"InvalidIPRange", Policy{IPRanges: []string{"10.0.0.1"}}, "invalid IP range: 10.0.0.1"},
+		{"NegativeMaxDays", Policy{MaxDays: -1}, "max_days cannot be negative"},
+		{"InvalidAlgorithm", Policy{Algorithms: []string{"dsa"}}, "invalid algorithm: dsa"},
+		{"InvalidKeyLengthAlgorithm", Policy{MinKeyLength: map[string]int{"dsa": 2048}}, "invalid algorithm: dsa"},
+		{"NegativeKeyLength", Policy{MinKeyLength: map[string]int{"rsa": -1}}, "min_key_length cannot be negative"},
+		{"InvalidKeyLengthAlgorithms", Policy{MinKeyLength: map[string]int{"rsa": -1, "dsa": 2048, "x448": 448}}, "invalid algorithm: dsa"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Validate()
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyTrustFuncRules(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	ca := &x509.Certificate{Subject: pkix.Name{Country: []string{"CA"}}}
+
+	tests := []struct {
+		title              string
+		policy             Policy
+		certType           int
+		csr                *x509.CertificateRequest
+		days               int
+		expectedViolations []Violation
+	}{
+		{
+			"NoRules",
+			Policy{},
+			CertTypeServer,
+			&x509.CertificateRequest{Subject: pkix.Name{CommonName: "*.example.org"}, PublicKey: &rsaKey.PublicKey},
+			3650,
+			nil,
+		},
+		{
+			"Satisfied",
+			Policy{
+				Types:                       []string{"server"},
+				CommonNames:                 []string{"*.sre.example.com"},
+				DNSNames:                    []string{"*.sre.example.com", "/^web-[0-9]+$/"},
+				ForbidWildcard:              true,
+				IPRanges:                    []string{"10.0.0.0/8"},
+				MaxDays:                     90,
+				Algorithms:                  []string{AlgorithmECDSA},
+				MinKeyLength:                map[string]int{AlgorithmECDSA: 256},
+				RequiredOrganizationalUnits: []string{"SRE"},
+			},
+			CertTypeServer,
+			&x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: "webapp.sre.example.com", OrganizationalUnit: []string{"SRE", "R&D"}},
+				DNSNames:    []string{"webapp.sre.example.com", "web-1"},
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+				PublicKey:   &ecdsaKey.PublicKey,
+			},
+			90,
+			nil,
+		},
+		{
+			"Violated",
+			Policy{
+				Types:                       []string{"server"},
+				Match:                       []string{"Country"},
+				Supplied:                    []string{"Organization"},
+				CommonNames:                 []string{"*.sre.example.com"},
+				DNSNames:                    []string{"*.sre.example.com"},
+				ForbidWildcard:              true,
+				IPRanges:                    []string{"10.0.0.0/8"},
+				MaxDays:                     90,
+				Algorithms:                  []string{AlgorithmECDSA},
+				MinKeyLength:                map[string]int{AlgorithmRSA: 2048},
+				RequiredOrganizationalUnits: []string{"SRE"},
+			},
+			CertTypeClient,
+			&x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: "webapp.example.com"},
+				DNSNames:    []string{"webapp.sre.example.com", "*.sre.example.com", "webapp.example.com"},
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("192.168.0.1")},
+				PublicKey:   &rsaKey.PublicKey,
+			},
+			375,
+			[]Violation{
+				{"Type", "server", "client", "types"},
+				{"Country", "CA", "none", "match"},
+				{"Organization", "any value", "none", "supplied"},
+				{"CommonName", "*.sre.example.com", "webapp.example.com", "common_names"},
+				{"DNSNames", "*.sre.example.com", "webapp.example.com", "dns_names"},
+				{"DNSNames", "no wildcard", "*.sre.example.com", "forbid_wildcard"},
+				{"IPAddresses", "10.0.0.0/8", "192.168.0.1", "ip_ranges"},
+				{"Days", "at most 90", "375", "max_days"},
+				{"Algorithm", "ecdsa", "rsa", "algorithms"},
+				{"Length", "at least 2048 for rsa", "1024", "min_key_length"},
+				{"OrganizationalUnit", "SRE", "none", "required_organizational_units"},
+			},
+		},
+		{
+			"MatchedAndSuppliedInOrder",
+			Policy{
+				Match:    []string{"Country", "Province", "Locality"},
+				Supplied: []string{"CommonName", "Organization", "OrganizationalUnit", "DNSName", "EmailAddress"},
+			},
+			CertTypeServer,
+			&x509.CertificateRequest{PublicKey: &ecdsaKey.PublicKey},
+			90,
+			[]Violation{
+				{"Country", "CA", "none", "match"},
+				{"CommonName", "any value", "none", "supplied"},
+				{"Organization", "any value", "none", "supplied"},
+				{"OrganizationalUnit", "any value", "none", "supplied"},
+				{"DNSNames", "any value", "none", "supplied"},
+				{"EmailAddresses", "any value", "none", "supplied"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			trust := PolicyTrustFunc(test.policy, test.certType)
+
+			// Violations are always reported in the same order
+			for i := 0; i < 10; i++ {
+				violations := trust(ca, test.csr, test.days)
+				assert.Equal(t, test.expectedViolations, violations)
+			}
 		})
 	}
 }
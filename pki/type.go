@@ -1,39 +1,57 @@
 package pki
 
 import (
+	"fmt"
+	"math/big"
 	"net"
 	"path"
+	"time"
 )
 
 const (
-	extKey     = ".key"
-	extCert    = ".cert"
-	extCSR     = ".csr"
-	extCAKey   = ".ca.key"
-	extCACert  = ".ca.cert"
-	extCACSR   = ".ca.csr"
-	extCAChain = ".ca.chain"
-
-	defaultRootCASerial = int64(10)
-	defaultRootCALength = 4096
-	defaultRootCADays   = 20 * 365
-
-	defaultIntermCASerial = int64(100)
-	defaultIntermCALength = 4096
-	defaultIntermCADays   = 10 * 365
-
-	defaultServerCertSerial = int64(1000)
-	defaultServerCertLength = 2048
-	defaultServerCertDays   = 10 + 365
-
-	defaultClientCertSerial = int64(10000)
-	defaultClientCertLength = 2048
-	defaultClientCertDays   = 10 + 30
+	extKey       = ".key"
+	extCert      = ".cert"
+	extCSR       = ".csr"
+	extCAKey     = ".ca.key"
+	extCACert    = ".ca.cert"
+	extCACSR     = ".ca.csr"
+	extCAChain   = ".ca.chain"
+	extFullchain = ".fullchain"
+	extCRL       = ".crl"
+	extStatus    = ".status.yaml"
+
+	defaultRootCASerial    = int64(10)
+	defaultRootCAAlgorithm = AlgorithmRSA
+	defaultRootCALength    = 4096
+	defaultRootCADays      = 20 * 365
+
+	defaultIntermCASerial    = int64(100)
+	defaultIntermCAAlgorithm = AlgorithmRSA
+	defaultIntermCALength    = 4096
+	defaultIntermCADays      = 10 * 365
+
+	defaultRootCAMaxPathLen   = 1
+	defaultIntermCAMaxPathLen = 0
+
+	defaultServerCertSerial    = int64(1000)
+	defaultServerCertAlgorithm = AlgorithmRSA
+	defaultServerCertLength    = 2048
+	defaultServerCertDays      = 10 + 365
+
+	defaultClientCertSerial    = int64(10000)
+	defaultClientCertAlgorithm = AlgorithmRSA
+	defaultClientCertLength    = 2048
+	defaultClientCertDays      = 10 + 30
 
 	titleRoot   = "Root Certificate Authority"
 	titleInterm = "Intermediate Certificate Authority"
 	titleServer = "Server Certificate Authority"
 	titleClient = "Client Certificate Authority"
+
+	typeNameRoot   = "root"
+	typeNameInterm = "intermediate"
+	typeNameServer = "server"
+	typeNameClient = "client"
 )
 
 var (
@@ -47,18 +65,23 @@ var (
 type (
 	// State represents the type for state
 	State struct {
-		Root   Config `yaml:"root"`
-		Interm Config `yaml:"intermediate"`
-		Server Config `yaml:"server"`
-		Client Config `yaml:"client"`
+		RandomSerial bool   `yaml:"random_serial,omitempty"`
+		Root         Config `yaml:"root"`
+		Interm       Config `yaml:"intermediate"`
+		Server       Config `yaml:"server"`
+		Client       Config `yaml:"client"`
 	}
 
 	// Config represents the subtype for configurations
+	// MaxPathLen is the maximum number of intermediates beneath a certificate authority (negative for no limit).
+	// If not set, it defaults to 1 for root and 0 for intermediate certificate authorities.
 	Config struct {
-		Serial   int64  `yaml:"serial"`
-		Length   int    `yaml:"length"`
-		Days     int    `yaml:"days"`
-		Password string `yaml:"-" secret:"required,6"`
+		Serial     int64  `yaml:"serial"`
+		Algorithm  string `yaml:"algorithm" default:"rsa"`
+		Length     int    `yaml:"length"`
+		Days       int    `yaml:"days"`
+		MaxPathLen *int   `yaml:"max_path_len,omitempty"`
+		Password   string `yaml:"-" secret:"required,6"`
 	}
 
 	// Spec represents the type for specs
@@ -69,7 +92,12 @@ type (
 		Client       Claim    `toml:"client"`
 		RootPolicy   Policy   `toml:"root_policy"`
 		IntermPolicy Policy   `toml:"intermediate_policy"`
+		IntermUsage  Usage    `toml:"intermediate_usage,omitempty"`
+		ServerUsage  Usage    `toml:"server_usage,omitempty"`
+		ClientUsage  Usage    `toml:"client_usage,omitempty"`
 		Metadata     Metadata `toml:"metadata"`
+
+		Authorities map[string]Authority `toml:"authority,omitempty"`
 	}
 
 	// Claim represents the subtype for an identity claim
@@ -83,19 +111,163 @@ type (
 		DNSName            []string `toml:"dns_name"`
 		IPAddress          []net.IP `toml:"ip_address"`
 		EmailAddress       []string `toml:"email_address"`
+		URI                []string `toml:"uri"`
 		StreetAddress      []string `toml:"street_address"`
 		PostalCode         []string `toml:"postal_code"`
 	}
 
 	// Policy represents the subtype for a policy
+	// Types are the types of certificates a certificate authority signs (intermediate, server, or client).
+	// CommonNames and DNSNames are allowlists of globs (e.g. *.example.com) or regular expressions between slashes (e.g. /^web-[0-9]+$/).
+	// IPRanges are in CIDR notation, and MinKeyLength is the minimum length of keys by algorithm (e.g. rsa = 2048).
+	// Rules not set are not evaluated.
 	Policy struct {
-		Match    []string `toml:"match"`
-		Supplied []string `toml:"supplied" default:"CommonName"`
+		Types                       []string       `toml:"types,omitempty"`
+		Match                       []string       `toml:"match"`
+		Supplied                    []string       `toml:"supplied" default:"CommonName"`
+		CommonNames                 []string       `toml:"common_names,omitempty"`
+		DNSNames                    []string       `toml:"dns_names,omitempty"`
+		ForbidWildcard              bool           `toml:"forbid_wildcard,omitempty"`
+		IPRanges                    []string       `toml:"ip_ranges,omitempty"`
+		MaxDays                     int            `toml:"max_days,omitzero"`
+		Algorithms                  []string       `toml:"algorithms,omitempty"`
+		MinKeyLength                map[string]int `toml:"min_key_length,omitempty"`
+		RequiredOrganizationalUnits []string       `toml:"required_organizational_units,omitempty"`
+	}
+
+	// Usage represents the subtype for key usages and extended key usages of certificates
+	// Extended key usages are either names (ServerAuth, ClientAuth, CodeSigning, EmailProtection, OCSPSigning, TimeStamping, ...)
+	// or object identifiers in dotted notation for arbitrary extended key usages.
+	// Name constraints are not set per certificate type, but per intermediate certificate authority.
+	// Distribution URLs and SPIFFE trust domains are not set per certificate type, but per issuing certificate authority.
+	Usage struct {
+		KeyUsage          []string        `toml:"key_usage"`
+		ExtKeyUsage       []string        `toml:"ext_key_usage"`
+		NameConstraints   NameConstraints `toml:"-"`
+		Distribution      Distribution    `toml:"-"`
+		SPIFFETrustDomain string          `toml:"-"`
+	}
+
+	// Authority represents the subtype for issuance settings of a certificate authority by its name
+	// MaxPathLen takes precedence over the one in state for the certificate authority.
+	// When SPIFFETrustDomain is set, client certificates signed by the certificate authority carry exactly one SPIFFE ID in the trust domain.
+	// Policy takes precedence over the policy for the type of certificate authority, so a delegated intermediate only signs what it is meant for.
+	Authority struct {
+		Distribution
+		Policy            *Policy         `toml:"policy,omitempty"`
+		MaxPathLen        *int            `toml:"max_path_len,omitempty"`
+		SPIFFETrustDomain string          `toml:"spiffe_trust_domain,omitempty"`
+		NameConstraints   NameConstraints `toml:"name_constraints"`
+	}
+
+	// Distribution represents the subtype for URLs where relying parties find revocation status and issuer of certificates
+	// CRL distribution points, OCSP servers, and issuing certificate URLs are stamped into every certificate a certificate authority issues.
+	Distribution struct {
+		CRLDistributionPoints  []string `toml:"crl_distribution_points,omitempty"`
+		OCSPServers            []string `toml:"ocsp_servers,omitempty"`
+		IssuingCertificateURLs []string `toml:"issuing_certificate_urls,omitempty"`
+	}
+
+	// NameConstraints represents the subtype for names an intermediate certificate authority can issue certificates for
+	// A domain matches itself and all of its subdomains, and a domain with a leading period only matches its subdomains.
+	// Email constraints are either domains or exact mailboxes, and IP ranges are in CIDR notation.
+	NameConstraints struct {
+		PermittedDNSDomains   []string `toml:"permitted_dns_domains,omitempty"`
+		ExcludedDNSDomains    []string `toml:"excluded_dns_domains,omitempty"`
+		PermittedIPRanges     []string `toml:"permitted_ip_ranges,omitempty"`
+		ExcludedIPRanges      []string `toml:"excluded_ip_ranges,omitempty"`
+		PermittedEmailDomains []string `toml:"permitted_email_domains,omitempty"`
+		ExcludedEmailDomains  []string `toml:"excluded_email_domains,omitempty"`
+		PermittedURIDomains   []string `toml:"permitted_uri_domains,omitempty"`
+		ExcludedURIDomains    []string `toml:"excluded_uri_domains,omitempty"`
 	}
 
 	// Metadata represents the subtyoe for metadata
 	Metadata map[string][]string
 
+	// CertStatus represents the subtype for status of a certificate
+	CertStatus struct {
+		Name      string    `yaml:"name"`
+		Serial    string    `yaml:"serial"`
+		Status    string    `yaml:"status"`
+		Reason    string    `yaml:"reason,omitempty"`
+		RevokedAt time.Time `yaml:"revoked_at,omitempty"`
+	}
+
+	// StatusStore represents the type for status of certificates issued by a certificate authority
+	StatusStore struct {
+		CRLNumber    int64        `yaml:"crl_number"`
+		Certificates []CertStatus `yaml:"certificates"`
+	}
+
+	// IndexEntry represents the subtype for a certificate in issuance index
+	IndexEntry struct {
+		Name           string    `yaml:"name"`
+		Type           string    `yaml:"type"`
+		Serial         string    `yaml:"serial"`
+		Subject        string    `yaml:"subject"`
+		DNSNames       []string  `yaml:"dns_names,omitempty"`
+		IPAddresses    []string  `yaml:"ip_addresses,omitempty"`
+		EmailAddresses []string  `yaml:"email_addresses,omitempty"`
+		URIs           []string  `yaml:"uris,omitempty"`
+		Issuer         string    `yaml:"issuer"`
+		NotBefore      time.Time `yaml:"not_before"`
+		NotAfter       time.Time `yaml:"not_after"`
+		Status         string    `yaml:"status"`
+		Reason         string    `yaml:"reason,omitempty"`
+		RevokedAt      time.Time `yaml:"revoked_at,omitempty"`
+		KeyPath        string    `yaml:"key_path,omitempty"`
+		CertPath       string    `yaml:"cert_path"`
+		CSRPath        string    `yaml:"csr_path,omitempty"`
+		ChainPath      string    `yaml:"chain_path,omitempty"`
+	}
+
+	// Index represents the type for issuance index of a workspace
+	Index struct {
+		Certificates []IndexEntry `yaml:"certificates"`
+	}
+
+	// CertSummary represents the type for a summary of a certificate or a pending certificate signing request in workspace
+	CertSummary struct {
+		Name     string
+		Type     string
+		Issuer   string
+		Serial   string
+		SANs     []string
+		NotAfter time.Time
+		Status   string
+		External bool
+	}
+
+	// Description represents the type for decoded details of a PEM block
+	Description struct {
+		Type               string            `json:"type"`
+		Subject            string            `json:"subject,omitempty"`
+		Issuer             string            `json:"issuer,omitempty"`
+		Serial             string            `json:"serial,omitempty"`
+		NotBefore          *time.Time        `json:"not_before,omitempty"`
+		NotAfter           *time.Time        `json:"not_after,omitempty"`
+		DNSNames           []string          `json:"dns_names,omitempty"`
+		IPAddresses        []string          `json:"ip_addresses,omitempty"`
+		EmailAddresses     []string          `json:"email_addresses,omitempty"`
+		URIs               []string          `json:"uris,omitempty"`
+		PublicKey          string            `json:"public_key,omitempty"`
+		SignatureAlgorithm string            `json:"signature_algorithm,omitempty"`
+		KeyUsage           []string          `json:"key_usage,omitempty"`
+		ExtKeyUsage        []string          `json:"ext_key_usage,omitempty"`
+		BasicConstraints   *BasicConstraints `json:"basic_constraints,omitempty"`
+		SubjectKeyID       string            `json:"subject_key_id,omitempty"`
+		AuthorityKeyID     string            `json:"authority_key_id,omitempty"`
+		SHA256Fingerprint  string            `json:"sha256_fingerprint,omitempty"`
+		SHA1Fingerprint    string            `json:"sha1_fingerprint,omitempty"`
+	}
+
+	// BasicConstraints represents the subtype for basic constraints of a certificate
+	BasicConstraints struct {
+		IsCA       bool `json:"is_ca"`
+		MaxPathLen *int `json:"max_path_len,omitempty"`
+	}
+
 	// Cert represents the type for a certificate
 	Cert struct {
 		Type int
@@ -107,24 +279,28 @@ type (
 func NewState() *State {
 	return &State{
 		Root: Config{
-			Serial: defaultRootCASerial,
-			Length: defaultRootCALength,
-			Days:   defaultRootCADays,
+			Serial:    defaultRootCASerial,
+			Algorithm: defaultRootCAAlgorithm,
+			Length:    defaultRootCALength,
+			Days:      defaultRootCADays,
 		},
 		Interm: Config{
-			Serial: defaultIntermCASerial,
-			Length: defaultIntermCALength,
-			Days:   defaultIntermCADays,
+			Serial:    defaultIntermCASerial,
+			Algorithm: defaultIntermCAAlgorithm,
+			Length:    defaultIntermCALength,
+			Days:      defaultIntermCADays,
 		},
 		Server: Config{
-			Serial: defaultServerCertSerial,
-			Length: defaultServerCertLength,
-			Days:   defaultServerCertDays,
+			Serial:    defaultServerCertSerial,
+			Algorithm: defaultServerCertAlgorithm,
+			Length:    defaultServerCertLength,
+			Days:      defaultServerCertDays,
 		},
 		Client: Config{
-			Serial: defaultClientCertSerial,
-			Length: defaultClientCertLength,
-			Days:   defaultClientCertDays,
+			Serial:    defaultClientCertSerial,
+			Algorithm: defaultClientCertAlgorithm,
+			Length:    defaultClientCertLength,
+			Days:      defaultClientCertDays,
 		},
 	}
 }
@@ -144,6 +320,13 @@ func NewSpec() *Spec {
 			Match:    defaultIntermPolicyMatch,
 			Supplied: defaultIntermPolicySupplied,
 		},
+		IntermUsage: Usage{},
+		ServerUsage: Usage{
+			ExtKeyUsage: defaultServerExtKeyUsage,
+		},
+		ClientUsage: Usage{
+			ExtKeyUsage: defaultClientExtKeyUsage,
+		},
 		Metadata: Metadata{},
 	}
 }
@@ -192,6 +375,79 @@ func (s *Spec) PolicyFor(certType int) (Policy, bool) {
 	}
 }
 
+// PolicyForCA returns policy for a certificate authority
+// A policy set for the certificate authority by its name takes precedence over the policy for its type.
+func (s *Spec) PolicyForCA(c Cert) (Policy, bool) {
+	if c.Type != CertTypeRoot && c.Type != CertTypeInterm {
+		return Policy{}, false
+	}
+
+	if policy := s.AuthorityFor(c.Name).Policy; policy != nil {
+		return *policy, true
+	}
+
+	return s.PolicyFor(c.Type)
+}
+
+// UsageFor returns key usages and extended key usages for a certificate type
+func (s *Spec) UsageFor(certType int) (Usage, bool) {
+	switch certType {
+	case CertTypeInterm:
+		return s.IntermUsage, true
+	case CertTypeServer:
+		return s.ServerUsage, true
+	case CertTypeClient:
+		return s.ClientUsage, true
+	default:
+		return Usage{}, false
+	}
+}
+
+// AuthorityFor returns issuance settings for a certificate authority by its name
+func (s *Spec) AuthorityFor(name string) Authority {
+	return s.Authorities[name]
+}
+
+// Validate checks if policies and issuance settings of all certificate authorities are well-formed
+func (s *Spec) Validate() error {
+	if err := s.RootPolicy.Validate(); err != nil {
+		return fmt.Errorf("root_policy: %s", err)
+	}
+
+	if err := s.IntermPolicy.Validate(); err != nil {
+		return fmt.Errorf("intermediate_policy: %s", err)
+	}
+
+	for name, authority := range s.Authorities {
+		if err := authority.Validate(); err != nil {
+			return fmt.Errorf("authority %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks if issuance settings of a certificate authority are well-formed
+func (a Authority) Validate() error {
+	if err := a.Distribution.Validate(); err != nil {
+		return err
+	}
+
+	if a.SPIFFETrustDomain != "" {
+		if err := validateTrustDomain(a.SPIFFETrustDomain); err != nil {
+			return err
+		}
+	}
+
+	if a.Policy != nil {
+		if err := a.Policy.Validate(); err != nil {
+			return fmt.Errorf("policy: %s", err)
+		}
+	}
+
+	return a.NameConstraints.Validate()
+}
+
 // Clone return a deep copy of claim
 func (c Claim) Clone() Claim {
 	return Claim{
@@ -204,6 +460,7 @@ func (c Claim) Clone() Claim {
 		DNSName:            c.DNSName,
 		IPAddress:          c.IPAddress,
 		EmailAddress:       c.EmailAddress,
+		URI:                c.URI,
 		StreetAddress:      c.StreetAddress,
 		PostalCode:         c.PostalCode,
 	}
@@ -225,6 +482,38 @@ func (c Cert) Title() string {
 	}
 }
 
+// TypeName returns the name of certificate type
+func (c Cert) TypeName() string {
+	switch c.Type {
+	case CertTypeRoot:
+		return typeNameRoot
+	case CertTypeInterm:
+		return typeNameInterm
+	case CertTypeServer:
+		return typeNameServer
+	case CertTypeClient:
+		return typeNameClient
+	default:
+		return ""
+	}
+}
+
+// ParseCertType returns the certificate type for a type name or zero if the name is invalid
+func ParseCertType(name string) int {
+	switch name {
+	case typeNameRoot:
+		return CertTypeRoot
+	case typeNameInterm:
+		return CertTypeInterm
+	case typeNameServer:
+		return CertTypeServer
+	case typeNameClient:
+		return CertTypeClient
+	default:
+		return 0
+	}
+}
+
 // KeyPath returns path to key file
 func (c Cert) KeyPath() string {
 	if c.Name == "" {
@@ -298,3 +587,80 @@ func (c Cert) ChainPath() string {
 		return ""
 	}
 }
+
+// FullchainPath returns path to full chain file of a leaf certificate
+// A full chain contains the certificate followed by its intermediate certificate authorities without the root.
+func (c Cert) FullchainPath() string {
+	if c.Name == "" {
+		return ""
+	}
+
+	switch c.Type {
+	case CertTypeServer:
+		return path.Join(DirServer, c.Name+extFullchain)
+	case CertTypeClient:
+		return path.Join(DirClient, c.Name+extFullchain)
+	default:
+		return ""
+	}
+}
+
+// CRLPath returns path to certificate revocation list file
+func (c Cert) CRLPath() string {
+	if c.Name == "" {
+		return ""
+	}
+
+	switch c.Type {
+	case CertTypeRoot, CertTypeInterm:
+		return path.Join(DirCRL, c.Name+extCRL)
+	default:
+		return ""
+	}
+}
+
+// StatusPath returns path to status store file of certificates issued by a certificate authority
+func (c Cert) StatusPath() string {
+	if c.Name == "" {
+		return ""
+	}
+
+	switch c.Type {
+	case CertTypeRoot, CertTypeInterm:
+		return path.Join(DirCRL, c.Name+extStatus)
+	default:
+		return ""
+	}
+}
+
+// ArchiveKeyPath returns path to archived key file of a certificate with a serial number
+func (c Cert) ArchiveKeyPath(serial *big.Int) string {
+	if c.Name == "" || serial == nil {
+		return ""
+	}
+
+	switch c.Type {
+	case CertTypeRoot, CertTypeInterm:
+		return path.Join(DirArchive, c.Name+"."+serial.String()+extCAKey)
+	case CertTypeServer, CertTypeClient:
+		return path.Join(DirArchive, c.Name+"."+serial.String()+extKey)
+	default:
+		return ""
+	}
+}
+
+// ArchiveCertPath returns path to archived cert file of a certificate with a serial number
+func (c Cert) ArchiveCertPath(serial *big.Int) string {
+	if c.Name == "" || serial == nil {
+		return ""
+	}
+
+	switch c.Type {
+	case CertTypeRoot, CertTypeInterm:
+		return path.Join(DirArchive, c.Name+"."+serial.String()+extCACert)
+	case CertTypeServer, CertTypeClient:
+		return path.Join(DirArchive, c.Name+"."+serial.String()+extCert)
+	default:
+		return ""
+	}
+}
@@ -1,8 +1,10 @@
 package pki
 
 import (
+	"math/big"
 	"net"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,18 +14,22 @@ func TestNewState(t *testing.T) {
 	state := NewState()
 
 	assert.Equal(t, defaultRootCASerial, state.Root.Serial)
+	assert.Equal(t, defaultRootCAAlgorithm, state.Root.Algorithm)
 	assert.Equal(t, defaultRootCALength, state.Root.Length)
 	assert.Equal(t, defaultRootCADays, state.Root.Days)
 
 	assert.Equal(t, defaultIntermCASerial, state.Interm.Serial)
+	assert.Equal(t, defaultIntermCAAlgorithm, state.Interm.Algorithm)
 	assert.Equal(t, defaultIntermCALength, state.Interm.Length)
 	assert.Equal(t, defaultIntermCADays, state.Interm.Days)
 
 	assert.Equal(t, defaultServerCertSerial, state.Server.Serial)
+	assert.Equal(t, defaultServerCertAlgorithm, state.Server.Algorithm)
 	assert.Equal(t, defaultServerCertLength, state.Server.Length)
 	assert.Equal(t, defaultServerCertDays, state.Server.Days)
 
 	assert.Equal(t, defaultClientCertSerial, state.Client.Serial)
+	assert.Equal(t, defaultClientCertAlgorithm, state.Client.Algorithm)
 	assert.Equal(t, defaultClientCertLength, state.Client.Length)
 	assert.Equal(t, defaultClientCertDays, state.Client.Days)
 }
@@ -44,6 +50,12 @@ func TestNewSpec(t *testing.T) {
 			Match:    defaultIntermPolicyMatch,
 			Supplied: defaultIntermPolicySupplied,
 		},
+		ServerUsage: Usage{
+			ExtKeyUsage: []string{"ServerAuth"},
+		},
+		ClientUsage: Usage{
+			ExtKeyUsage: []string{"ClientAuth"},
+		},
 		Metadata: Metadata{},
 	}
 
@@ -67,9 +79,10 @@ func TestState(t *testing.T) {
 			NewState(),
 			CertTypeRoot,
 			Config{
-				Serial: defaultRootCASerial,
-				Length: defaultRootCALength,
-				Days:   defaultRootCADays,
+				Serial:    defaultRootCASerial,
+				Algorithm: defaultRootCAAlgorithm,
+				Length:    defaultRootCALength,
+				Days:      defaultRootCADays,
 			},
 			true,
 		},
@@ -77,9 +90,10 @@ func TestState(t *testing.T) {
 			NewState(),
 			CertTypeInterm,
 			Config{
-				Serial: defaultIntermCASerial,
-				Length: defaultIntermCALength,
-				Days:   defaultIntermCADays,
+				Serial:    defaultIntermCASerial,
+				Algorithm: defaultIntermCAAlgorithm,
+				Length:    defaultIntermCALength,
+				Days:      defaultIntermCADays,
 			},
 			true,
 		},
@@ -87,9 +101,10 @@ func TestState(t *testing.T) {
 			NewState(),
 			CertTypeServer,
 			Config{
-				Serial: defaultServerCertSerial,
-				Length: defaultServerCertLength,
-				Days:   defaultServerCertDays,
+				Serial:    defaultServerCertSerial,
+				Algorithm: defaultServerCertAlgorithm,
+				Length:    defaultServerCertLength,
+				Days:      defaultServerCertDays,
 			},
 			true,
 		},
@@ -97,9 +112,10 @@ func TestState(t *testing.T) {
 			NewState(),
 			CertTypeClient,
 			Config{
-				Serial: defaultClientCertSerial,
-				Length: defaultClientCertLength,
-				Days:   defaultClientCertDays,
+				Serial:    defaultClientCertSerial,
+				Algorithm: defaultClientCertAlgorithm,
+				Length:    defaultClientCertLength,
+				Days:      defaultClientCertDays,
 			},
 			true,
 		},
@@ -150,6 +166,13 @@ func TestSpec(t *testing.T) {
 			Match:    []string{"Country", "Organization"},
 			Supplied: []string{"CommonName", "DNSName", "EmailAddress"},
 		},
+		ServerUsage: Usage{
+			ExtKeyUsage: []string{"ServerAuth"},
+		},
+		ClientUsage: Usage{
+			KeyUsage:    []string{"DigitalSignature"},
+			ExtKeyUsage: []string{"ClientAuth"},
+		},
 	}
 
 	tests := []struct {
@@ -159,6 +182,8 @@ func TestSpec(t *testing.T) {
 		expectedClaimOK  bool
 		expectedPolicy   Policy
 		expectedPolicyOK bool
+		expectedUsage    Usage
+		expectedUsageOK  bool
 	}{
 		{
 			spec,
@@ -167,6 +192,8 @@ func TestSpec(t *testing.T) {
 			false,
 			Policy{},
 			false,
+			Usage{},
+			false,
 		},
 		{
 			spec,
@@ -183,6 +210,8 @@ func TestSpec(t *testing.T) {
 				Supplied: []string{"CommonName", "DNSName"},
 			},
 			true,
+			Usage{},
+			false,
 		},
 		{
 			spec,
@@ -200,6 +229,8 @@ func TestSpec(t *testing.T) {
 				Supplied: []string{"CommonName", "DNSName", "EmailAddress"},
 			},
 			true,
+			Usage{},
+			true,
 		},
 		{
 			spec,
@@ -214,6 +245,10 @@ func TestSpec(t *testing.T) {
 			true,
 			Policy{},
 			false,
+			Usage{
+				ExtKeyUsage: []string{"ServerAuth"},
+			},
+			true,
 		},
 		{
 			spec,
@@ -228,6 +263,11 @@ func TestSpec(t *testing.T) {
 			true,
 			Policy{},
 			false,
+			Usage{
+				KeyUsage:    []string{"DigitalSignature"},
+				ExtKeyUsage: []string{"ClientAuth"},
+			},
+			true,
 		},
 	}
 
@@ -239,7 +279,95 @@ func TestSpec(t *testing.T) {
 		policy, ok := test.spec.PolicyFor(test.certType)
 		assert.Equal(t, test.expectedPolicyOK, ok)
 		assert.Equal(t, test.expectedPolicy, policy)
+
+		usage, ok := test.spec.UsageFor(test.certType)
+		assert.Equal(t, test.expectedUsageOK, ok)
+		assert.Equal(t, test.expectedUsage, usage)
+	}
+}
+
+func TestSpecPolicyRules(t *testing.T) {
+	policy := Policy{
+		Supplied:                    []string{"CommonName"},
+		DNSNames:                    []string{"*.sre.example.com"},
+		ForbidWildcard:              true,
+		IPRanges:                    []string{"10.0.0.0/8"},
+		MaxDays:                     90,
+		Algorithms:                  []string{AlgorithmECDSA},
+		MinKeyLength:                map[string]int{AlgorithmECDSA: 256},
+		RequiredOrganizationalUnits: []string{"SRE"},
+	}
+
+	spec := NewSpec()
+	spec.IntermPolicy = policy
+
+	// Policy rules are stored in policy tables in spec file
+	file := filepath.Join(t.TempDir(), "spec.toml")
+	assert.NoError(t, SaveSpec(spec, file))
+	loaded, err := LoadSpec(file)
+	assert.NoError(t, err)
+	assert.Equal(t, policy, loaded.IntermPolicy)
+}
+
+func TestSpecPolicyForCA(t *testing.T) {
+	sre := Policy{Types: []string{"server"}, DNSNames: []string{"*.sre.example.com"}}
+	payments := Policy{Types: []string{"client"}, RequiredOrganizationalUnits: []string{"payments"}}
+
+	spec := NewSpec()
+	spec.Authorities = map[string]Authority{
+		"sre":      Authority{Policy: &sre},
+		"payments": Authority{Policy: &payments},
+		"webapp":   Authority{Policy: &sre},
+	}
+
+	tests := []struct {
+		name           string
+		c              Cert
+		expectedPolicy Policy
+		expectedOK     bool
+	}{
+		{"Root", Cert{Name: "root", Type: CertTypeRoot}, spec.RootPolicy, true},
+		{"Interm", Cert{Name: "ops", Type: CertTypeInterm}, spec.IntermPolicy, true},
+		{"SRE", Cert{Name: "sre", Type: CertTypeInterm}, sre, true},
+		{"Payments", Cert{Name: "payments", Type: CertTypeInterm}, payments, true},
+		{"Server", Cert{Name: "webapp", Type: CertTypeServer}, Policy{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, ok := spec.PolicyForCA(test.c)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedPolicy, policy)
+		})
+	}
+
+	// Policies of certificate authorities are stored in authority tables in spec file
+	file := filepath.Join(t.TempDir(), "spec.toml")
+	assert.NoError(t, SaveSpec(spec, file))
+	loaded, err := LoadSpec(file)
+	assert.NoError(t, err)
+	assert.Equal(t, &payments, loaded.AuthorityFor("payments").Policy)
+	assert.Nil(t, loaded.AuthorityFor("ops").Policy)
+}
+
+func TestSpecAuthorityFor(t *testing.T) {
+	nc := NameConstraints{PermittedDNSDomains: []string{".sre.example.com"}}
+	spec := &Spec{
+		Authorities: map[string]Authority{
+			"sre": Authority{NameConstraints: nc},
+		},
 	}
+
+	assert.Equal(t, Authority{NameConstraints: nc}, spec.AuthorityFor("sre"))
+	assert.Equal(t, Authority{}, spec.AuthorityFor("ops"))
+	assert.Equal(t, Authority{}, NewSpec().AuthorityFor("sre"))
+
+	// Authorities are stored as tables in spec file
+	file := filepath.Join(t.TempDir(), "spec.toml")
+	assert.NoError(t, SaveSpec(spec, file))
+	loaded, err := LoadSpec(file)
+	assert.NoError(t, err)
+	assert.Equal(t, Authority{NameConstraints: nc}, loaded.AuthorityFor("sre"))
 }
 
 func TestClaim(t *testing.T) {
@@ -290,12 +418,18 @@ func TestClaim(t *testing.T) {
 
 func TestCert(t *testing.T) {
 	tests := []struct {
-		c                 Cert
-		expectedTitle     string
-		expectedCertPath  string
-		expectedKeyPath   string
-		expectedCSRPath   string
-		expectedChainPath string
+		c                   Cert
+		expectedTitle       string
+		expectedTypeName    string
+		expectedCertPath    string
+		expectedKeyPath     string
+		expectedCSRPath     string
+		expectedChainPath   string
+		expectedFullchain   string
+		expectedCRLPath     string
+		expectedStatusPath  string
+		expectedArchiveCert string
+		expectedArchiveKey  string
 	}{
 		{
 			Cert{},
@@ -304,6 +438,12 @@ func TestCert(t *testing.T) {
 			"",
 			"",
 			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
 		},
 		{
 			Cert{Name: "root"},
@@ -312,6 +452,12 @@ func TestCert(t *testing.T) {
 			"",
 			"",
 			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
 		},
 		{
 			Cert{
@@ -319,10 +465,16 @@ func TestCert(t *testing.T) {
 				Type: CertTypeRoot,
 			},
 			titleRoot,
+			typeNameRoot,
 			path.Join(DirRoot, "root"+extCACert),
 			path.Join(DirRoot, "root"+extCAKey),
 			"",
 			path.Join(DirRoot, "root"+extCACert),
+			"",
+			path.Join(DirCRL, "root"+extCRL),
+			path.Join(DirCRL, "root"+extStatus),
+			path.Join(DirArchive, "root.7"+extCACert),
+			path.Join(DirArchive, "root.7"+extCAKey),
 		},
 		{
 			Cert{
@@ -330,10 +482,16 @@ func TestCert(t *testing.T) {
 				Type: CertTypeInterm,
 			},
 			titleInterm,
+			typeNameInterm,
 			path.Join(DirInterm, "ops"+extCACert),
 			path.Join(DirInterm, "ops"+extCAKey),
 			path.Join(DirCSR, "ops"+extCACSR),
 			path.Join(DirInterm, "ops"+extCAChain),
+			"",
+			path.Join(DirCRL, "ops"+extCRL),
+			path.Join(DirCRL, "ops"+extStatus),
+			path.Join(DirArchive, "ops.7"+extCACert),
+			path.Join(DirArchive, "ops.7"+extCAKey),
 		},
 		{
 			Cert{
@@ -341,10 +499,16 @@ func TestCert(t *testing.T) {
 				Type: CertTypeServer,
 			},
 			titleServer,
+			typeNameServer,
 			path.Join(DirServer, "webapp"+extCert),
 			path.Join(DirServer, "webapp"+extKey),
 			path.Join(DirCSR, "webapp"+extCSR),
 			"",
+			path.Join(DirServer, "webapp"+extFullchain),
+			"",
+			"",
+			path.Join(DirArchive, "webapp.7"+extCert),
+			path.Join(DirArchive, "webapp.7"+extKey),
 		},
 		{
 			Cert{
@@ -352,18 +516,51 @@ func TestCert(t *testing.T) {
 				Type: CertTypeClient,
 			},
 			titleClient,
+			typeNameClient,
 			path.Join(DirClient, "service"+extCert),
 			path.Join(DirClient, "service"+extKey),
 			path.Join(DirCSR, "service"+extCSR),
 			"",
+			path.Join(DirClient, "service"+extFullchain),
+			"",
+			"",
+			path.Join(DirArchive, "service.7"+extCert),
+			path.Join(DirArchive, "service.7"+extKey),
 		},
 	}
 
 	for _, test := range tests {
 		assert.Equal(t, test.expectedTitle, test.c.Title())
+		assert.Equal(t, test.expectedTypeName, test.c.TypeName())
+		if test.c.Type != 0 {
+			assert.Equal(t, test.c.Type, ParseCertType(test.expectedTypeName))
+		}
 		assert.Equal(t, test.expectedCertPath, test.c.CertPath())
 		assert.Equal(t, test.expectedKeyPath, test.c.KeyPath())
 		assert.Equal(t, test.expectedCSRPath, test.c.CSRPath())
 		assert.Equal(t, test.expectedChainPath, test.c.ChainPath())
+		assert.Equal(t, test.expectedFullchain, test.c.FullchainPath())
+		assert.Equal(t, test.expectedCRLPath, test.c.CRLPath())
+		assert.Equal(t, test.expectedStatusPath, test.c.StatusPath())
+		assert.Equal(t, test.expectedArchiveCert, test.c.ArchiveCertPath(big.NewInt(7)))
+		assert.Equal(t, test.expectedArchiveKey, test.c.ArchiveKeyPath(big.NewInt(7)))
+	}
+}
+
+func TestParseCertType(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedType int
+	}{
+		{"root", CertTypeRoot},
+		{"intermediate", CertTypeInterm},
+		{"server", CertTypeServer},
+		{"client", CertTypeClient},
+		{"invalid", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expectedType, ParseCertType(test.name))
 	}
 }
@@ -0,0 +1,190 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLeafKeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment
+	caKeyUsage          = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+)
+
+var (
+	defaultServerExtKeyUsage = []string{"ServerAuth"}
+	defaultClientExtKeyUsage = []string{"ClientAuth"}
+
+	oidExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+	// extKeyUsageOIDs are object identifiers of extended key usages with a name
+	extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+		x509.ExtKeyUsageAny:             {2, 5, 29, 37, 0},
+		x509.ExtKeyUsageServerAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 1},
+		x509.ExtKeyUsageClientAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 2},
+		x509.ExtKeyUsageCodeSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 3},
+		x509.ExtKeyUsageEmailProtection: {1, 3, 6, 1, 5, 5, 7, 3, 4},
+		x509.ExtKeyUsageIPSECEndSystem:  {1, 3, 6, 1, 5, 5, 7, 3, 5},
+		x509.ExtKeyUsageIPSECTunnel:     {1, 3, 6, 1, 5, 5, 7, 3, 6},
+		x509.ExtKeyUsageIPSECUser:       {1, 3, 6, 1, 5, 5, 7, 3, 7},
+		x509.ExtKeyUsageTimeStamping:    {1, 3, 6, 1, 5, 5, 7, 3, 8},
+		x509.ExtKeyUsageOCSPSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 9},
+
+		x509.ExtKeyUsageMicrosoftServerGatedCrypto:     {1, 3, 6, 1, 4, 1, 311, 10, 3, 3},
+		x509.ExtKeyUsageNetscapeServerGatedCrypto:      {2, 16, 840, 1, 113730, 4, 1},
+		x509.ExtKeyUsageMicrosoftCommercialCodeSigning: {1, 3, 6, 1, 4, 1, 311, 2, 1, 22},
+		x509.ExtKeyUsageMicrosoftKernelCodeSigning:     {1, 3, 6, 1, 4, 1, 311, 61, 1, 1},
+	}
+)
+
+// parseKeyUsage returns a key usage by its name
+func parseKeyUsage(name string) (x509.KeyUsage, error) {
+	for _, ku := range keyUsageNames {
+		if strings.EqualFold(ku.name, name) {
+			return ku.usage, nil
+		}
+	}
+
+	return 0, errors.New("unknown key usage: " + name)
+}
+
+// parseOID parses an object identifier in dotted notation
+func parseOID(s string) (asn1.ObjectIdentifier, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, false
+	}
+
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		oid[i] = n
+	}
+
+	return oid, true
+}
+
+// parseExtKeyUsage returns an extended key usage by its name or an object identifier for an arbitrary extended key usage
+func parseExtKeyUsage(name string) (x509.ExtKeyUsage, asn1.ObjectIdentifier, error) {
+	for usage, usageName := range extKeyUsageNames {
+		if strings.EqualFold(usageName, name) {
+			return usage, nil, nil
+		}
+	}
+
+	if oid, ok := parseOID(name); ok {
+		return 0, oid, nil
+	}
+
+	return 0, nil, errors.New("unknown extended key usage: " + name)
+}
+
+// extKeyUsageOID returns the object identifier of an extended key usage by its name or an object identifier
+func extKeyUsageOID(name string) (asn1.ObjectIdentifier, error) {
+	usage, oid, err := parseExtKeyUsage(name)
+	if err != nil || oid != nil {
+		return oid, err
+	}
+
+	return extKeyUsageOIDs[usage], nil
+}
+
+// requestsExtKeyUsage determines whether or not a certificate signing request requests an extended key usage by its name
+func requestsExtKeyUsage(csr *x509.CertificateRequest, name string) (bool, error) {
+	oid, err := extKeyUsageOID(name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, ext := range csr.Extensions {
+		if !ext.Id.Equal(oidExtKeyUsage) {
+			continue
+		}
+
+		var requested []asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(ext.Value, &requested); err != nil {
+			return false, err
+		}
+
+		for _, r := range requested {
+			if r.Equal(oid) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// usageFromCert returns key usages, extended key usages, name constraints, and distribution URLs of an existing certificate
+func usageFromCert(cert *x509.Certificate) Usage {
+	return Usage{
+		KeyUsage:        describeKeyUsage(cert.KeyUsage),
+		ExtKeyUsage:     describeExtKeyUsage(cert.ExtKeyUsage, cert.UnknownExtKeyUsage),
+		NameConstraints: nameConstraintsFromCert(cert),
+		Distribution:    distributionFromCert(cert),
+	}
+}
+
+// apply sets key usages and extended key usages of a certificate template for a certificate type
+// Type defaults are used for unset usages.
+func (u Usage) apply(cert *x509.Certificate, certType int, pubAlg x509.PublicKeyAlgorithm) error {
+	if len(u.KeyUsage) == 0 {
+		switch certType {
+		case CertTypeRoot, CertTypeInterm:
+			cert.KeyUsage = caKeyUsage
+		default:
+			cert.KeyUsage = defaultLeafKeyUsage
+			// Key encipherment is only meaningful for RSA keys
+			if pubAlg == x509.RSA {
+				cert.KeyUsage |= x509.KeyUsageKeyEncipherment
+			}
+		}
+	} else {
+		cert.KeyUsage = 0
+		for _, name := range u.KeyUsage {
+			usage, err := parseKeyUsage(name)
+			if err != nil {
+				return err
+			}
+			cert.KeyUsage |= usage
+		}
+	}
+
+	// A certificate authority cannot sign certificates and revocation lists without these usages
+	if certType == CertTypeRoot || certType == CertTypeInterm {
+		cert.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	extKeyUsage := u.ExtKeyUsage
+	if len(extKeyUsage) == 0 {
+		switch certType {
+		case CertTypeServer:
+			extKeyUsage = defaultServerExtKeyUsage
+		case CertTypeClient:
+			extKeyUsage = defaultClientExtKeyUsage
+		}
+	}
+
+	cert.ExtKeyUsage = []x509.ExtKeyUsage{}
+	cert.UnknownExtKeyUsage = nil
+	for _, name := range extKeyUsage {
+		usage, oid, err := parseExtKeyUsage(name)
+		if err != nil {
+			return err
+		}
+
+		if oid != nil {
+			cert.UnknownExtKeyUsage = append(cert.UnknownExtKeyUsage, oid)
+		} else {
+			cert.ExtKeyUsage = append(cert.ExtKeyUsage, usage)
+		}
+	}
+
+	return nil
+}
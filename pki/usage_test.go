@@ -0,0 +1,237 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedUsage x509.KeyUsage
+		expectedError string
+	}{
+		{"DigitalSignature", x509.KeyUsageDigitalSignature, ""},
+		{"keyEncipherment", x509.KeyUsageKeyEncipherment, ""},
+		{"CRLSign", x509.KeyUsageCRLSign, ""},
+		{"Invalid", 0, "unknown key usage: Invalid"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage, err := parseKeyUsage(test.name)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedUsage, usage)
+			}
+		})
+	}
+}
+
+func TestParseExtKeyUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedUsage x509.ExtKeyUsage
+		expectedOID   asn1.ObjectIdentifier
+		expectedError string
+	}{
+		{"ServerAuth", x509.ExtKeyUsageServerAuth, nil, ""},
+		{"clientauth", x509.ExtKeyUsageClientAuth, nil, ""},
+		{"CodeSigning", x509.ExtKeyUsageCodeSigning, nil, ""},
+		{"EmailProtection", x509.ExtKeyUsageEmailProtection, nil, ""},
+		{"OCSPSigning", x509.ExtKeyUsageOCSPSigning, nil, ""},
+		{"TimeStamping", x509.ExtKeyUsageTimeStamping, nil, ""},
+		{"1.3.6.1.4.1.311.20.2.2", 0, asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}, ""},
+		{"1", 0, nil, "unknown extended key usage: 1"},
+		{"1.3.a", 0, nil, "unknown extended key usage: 1.3.a"},
+		{"Invalid", 0, nil, "unknown extended key usage: Invalid"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage, oid, err := parseExtKeyUsage(test.name)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedUsage, usage)
+				assert.Equal(t, test.expectedOID, oid)
+			}
+		})
+	}
+}
+
+func TestExtKeyUsageOID(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedOID   asn1.ObjectIdentifier
+		expectedError string
+	}{
+		{"ServerAuth", asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}, ""},
+		{"clientauth", asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}, ""},
+		{"1.3.6.1.4.1.311.20.2.2", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}, ""},
+		{"Invalid", nil, "unknown extended key usage: Invalid"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oid, err := extKeyUsageOID(test.name)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedOID, oid)
+			}
+		})
+	}
+
+	// Every extended key usage with a name has an object identifier
+	for usage, name := range extKeyUsageNames {
+		assert.Contains(t, extKeyUsageOIDs, usage, name)
+	}
+}
+
+func TestRequestsExtKeyUsage(t *testing.T) {
+	value, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}})
+	assert.NoError(t, err)
+
+	csr := &x509.CertificateRequest{
+		Extensions: []pkix.Extension{{Id: oidExtKeyUsage, Value: value}},
+	}
+
+	ok, err := requestsExtKeyUsage(csr, "ClientAuth")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = requestsExtKeyUsage(csr, "ServerAuth")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = requestsExtKeyUsage(&x509.CertificateRequest{}, "ClientAuth")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = requestsExtKeyUsage(csr, "Invalid")
+	assert.EqualError(t, err, "unknown extended key usage: Invalid")
+
+	csr.Extensions[0].Value = []byte("invalid")
+	_, err = requestsExtKeyUsage(csr, "ClientAuth")
+	assert.Error(t, err)
+}
+
+func TestUsageApply(t *testing.T) {
+	tests := []struct {
+		name                       string
+		usage                      Usage
+		certType                   int
+		pubAlg                     x509.PublicKeyAlgorithm
+		expectedError              string
+		expectedKeyUsage           x509.KeyUsage
+		expectedExtKeyUsage        []x509.ExtKeyUsage
+		expectedUnknownExtKeyUsage []asn1.ObjectIdentifier
+	}{
+		{
+			name:                "IntermDefault",
+			usage:               Usage{},
+			certType:            CertTypeInterm,
+			pubAlg:              x509.RSA,
+			expectedKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			expectedExtKeyUsage: []x509.ExtKeyUsage{},
+		},
+		{
+			name:                "IntermCustom",
+			usage:               Usage{KeyUsage: []string{"DigitalSignature"}, ExtKeyUsage: []string{"ServerAuth", "ClientAuth"}},
+			certType:            CertTypeInterm,
+			pubAlg:              x509.ECDSA,
+			expectedKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			expectedExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		},
+		{
+			name:                "ServerDefaultRSA",
+			usage:               Usage{},
+			certType:            CertTypeServer,
+			pubAlg:              x509.RSA,
+			expectedKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment | x509.KeyUsageKeyEncipherment,
+			expectedExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:                "ServerDefaultECDSA",
+			usage:               Usage{},
+			certType:            CertTypeServer,
+			pubAlg:              x509.ECDSA,
+			expectedKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+			expectedExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:                "ClientDefault",
+			usage:               Usage{},
+			certType:            CertTypeClient,
+			pubAlg:              x509.Ed25519,
+			expectedKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+			expectedExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		},
+		{
+			name:                       "ClientCustom",
+			usage:                      Usage{KeyUsage: []string{"DigitalSignature", "KeyAgreement"}, ExtKeyUsage: []string{"ClientAuth", "EmailProtection", "1.3.6.1.4.1.311.20.2.2"}},
+			certType:                   CertTypeClient,
+			pubAlg:                     x509.ECDSA,
+			expectedKeyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+			expectedExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageEmailProtection},
+			expectedUnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}},
+		},
+		{
+			name:          "InvalidKeyUsage",
+			usage:         Usage{KeyUsage: []string{"Invalid"}},
+			certType:      CertTypeServer,
+			expectedError: "unknown key usage: Invalid",
+		},
+		{
+			name:          "InvalidExtKeyUsage",
+			usage:         Usage{ExtKeyUsage: []string{"Invalid"}},
+			certType:      CertTypeServer,
+			expectedError: "unknown extended key usage: Invalid",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert := &x509.Certificate{}
+			err := test.usage.apply(cert, test.certType, test.pubAlg)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedKeyUsage, cert.KeyUsage)
+				assert.Equal(t, test.expectedExtKeyUsage, cert.ExtKeyUsage)
+				assert.Equal(t, test.expectedUnknownExtKeyUsage, cert.UnknownExtKeyUsage)
+			}
+		})
+	}
+}
+
+func TestUsageFromCert(t *testing.T) {
+	cert := &x509.Certificate{
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 2, 3}},
+	}
+
+	usage := usageFromCert(cert)
+	assert.Equal(t, Usage{
+		KeyUsage:    []string{"DigitalSignature"},
+		ExtKeyUsage: []string{"ClientAuth", "1.2.3"},
+	}, usage)
+
+	renewed := &x509.Certificate{}
+	assert.NoError(t, usage.apply(renewed, CertTypeClient, x509.ECDSA))
+	assert.Equal(t, cert.KeyUsage, renewed.KeyUsage)
+	assert.Equal(t, cert.ExtKeyUsage, renewed.ExtKeyUsage)
+	assert.Equal(t, cert.UnknownExtKeyUsage, renewed.UnknownExtKeyUsage)
+}
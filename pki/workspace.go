@@ -1,7 +1,10 @@
 package pki
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/moorara/gocert/util"
@@ -35,7 +38,14 @@ func SaveState(state *State, file string) error {
 		return err
 	}
 
-	err = os.WriteFile(file, data, 0644)
+	// Write to a temporary file first and rename it, so state file is never left partially written
+	tmp := file + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, file)
 	if err != nil {
 		return err
 	}
@@ -81,7 +91,7 @@ func SaveSpec(spec *Spec, file string) error {
 // NewWorkspace creates a new workspace in current directory
 func NewWorkspace(state *State, spec *Spec) error {
 	// Make sub-directories
-	_, err := util.MkDirs("", DirRoot, DirInterm, DirServer, DirClient, DirCSR)
+	_, err := util.MkDirs("", DirRoot, DirInterm, DirServer, DirClient, DirCSR, DirCRL, DirArchive)
 	if err != nil {
 		return err
 	}
@@ -98,6 +108,12 @@ func NewWorkspace(state *State, spec *Spec) error {
 		return err
 	}
 
+	// Write an empty issuance index file
+	err = SaveIndex(&Index{}, FileIndex)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -135,6 +151,123 @@ func SaveWorkspace(state *State, spec *Spec) error {
 	return nil
 }
 
+// ListCerts returns all certificates of a type in the current workspace
+func ListCerts(certType int) ([]Cert, error) {
+	pattern := Cert{Type: certType, Name: "*"}.CertPath()
+	if pattern == "" {
+		return nil, errors.New("invalid certificate type")
+	}
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	certs := make([]Cert, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, prefix), suffix)
+		certs = append(certs, Cert{Type: certType, Name: name})
+	}
+
+	return certs, nil
+}
+
+// ListCSRs returns all pending certificate signing requests of a type in the current workspace
+// A certificate signing request is pending if it is not signed yet.
+// External requests imported for signing have no key in workspace (see isExternal).
+func ListCSRs(certType int) ([]Cert, error) {
+	pattern := Cert{Type: certType, Name: "*"}.CSRPath()
+	if pattern == "" {
+		return nil, errors.New("invalid certificate type")
+	}
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	certs := make([]Cert, 0, len(files))
+	for _, file := range files {
+		c := Cert{
+			Type: certType,
+			Name: strings.TrimSuffix(strings.TrimPrefix(file, prefix), suffix),
+		}
+
+		// Intermediate requests are stored with their own extension
+		if certType != CertTypeInterm && strings.HasSuffix(c.Name, strings.TrimSuffix(extCACSR, extCSR)) {
+			continue
+		}
+
+		if _, err := os.Stat(c.CertPath()); err == nil {
+			continue
+		}
+
+		// Server and client requests share the same directory and extension, so the key file determines the type
+		// An external request has no key, so its type is determined by the extended key usages it requests.
+		if isExternal(c) {
+			if certType != CertTypeInterm {
+				external, err := externalCSRType(c)
+				if err != nil {
+					return nil, err
+				}
+				if external != certType {
+					continue
+				}
+			}
+		} else if _, err := os.Stat(c.KeyPath()); err != nil {
+			continue
+		}
+
+		certs = append(certs, c)
+	}
+
+	return certs, nil
+}
+
+// isExternal determines whether a certificate signing request is imported from outside workspace
+// The private key of an external request never leaves its host, so no key of the same name exists in workspace.
+func isExternal(c Cert) bool {
+	keyTypes := []int{CertTypeServer, CertTypeClient}
+	if c.Type == CertTypeInterm {
+		keyTypes = []int{CertTypeInterm}
+	}
+
+	for _, keyType := range keyTypes {
+		if _, err := os.Stat(Cert{Name: c.Name, Type: keyType}.KeyPath()); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// externalCSRType returns the type of an external server or client certificate signing request
+// A request is for a client certificate if it only requests client authentication, and for a server certificate otherwise.
+func externalCSRType(c Cert) (int, error) {
+	csr, err := readCertificateRequest(c.CSRPath())
+	if err != nil {
+		return 0, err
+	}
+
+	serverAuth, err := requestsExtKeyUsage(csr, "ServerAuth")
+	if err != nil {
+		return 0, err
+	}
+
+	clientAuth, err := requestsExtKeyUsage(csr, "ClientAuth")
+	if err != nil {
+		return 0, err
+	}
+
+	if clientAuth && !serverAuth {
+		return CertTypeClient, nil
+	}
+
+	return CertTypeServer, nil
+}
+
 // CleanupWorkspace removes all directories and files in a workspace
 func CleanupWorkspace() error {
 	return util.DeleteAll(
@@ -144,7 +277,10 @@ func CleanupWorkspace() error {
 		DirServer,
 		DirClient,
 		DirCSR,
+		DirCRL,
+		DirArchive,
 		FileState,
 		FileSpec,
+		FileIndex,
 	)
 }
@@ -1,6 +1,11 @@
 package pki
 
 import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
 	"net"
 	"os"
 	"testing"
@@ -163,6 +168,13 @@ var (
 						Match:    []string{"Organization"},
 						Supplied: []string{"CommonName"},
 					},
+					ServerUsage: Usage{
+						ExtKeyUsage: []string{"ServerAuth"},
+					},
+					ClientUsage: Usage{
+						KeyUsage:    []string{"DigitalSignature"},
+						ExtKeyUsage: []string{"ClientAuth", "EmailProtection", "1.3.6.1.4.1.311.20.2.2"},
+					},
 					Metadata: Metadata{
 						"RootSkip":   []string{"IPAddress", "StreetAddress", "PostalCode"},
 						"IntermSkip": []string{"IPAddress", "StreetAddress", "PostalCode"},
@@ -457,6 +469,258 @@ func TestSaveWorkspace(t *testing.T) {
 	}
 }
 
+func TestListCerts(t *testing.T) {
+	tests := []struct {
+		title         string
+		files         []string
+		certType      int
+		expectError   bool
+		expectedCerts []Cert
+	}{
+		{
+			"InvalidType",
+			[]string{},
+			-1,
+			true,
+			nil,
+		},
+		{
+			"NoCert",
+			[]string{},
+			CertTypeInterm,
+			false,
+			[]Cert{},
+		},
+		{
+			"Root",
+			[]string{
+				DirRoot + "/root.ca.key",
+				DirRoot + "/root.ca.cert",
+			},
+			CertTypeRoot,
+			false,
+			[]Cert{
+				{Type: CertTypeRoot, Name: "root"},
+			},
+		},
+		{
+			"Intermediate",
+			[]string{
+				DirInterm + "/ops.ca.key",
+				DirInterm + "/ops.ca.cert",
+				DirInterm + "/ops.ca.chain",
+				DirInterm + "/sre.ca.key",
+				DirInterm + "/sre.ca.cert",
+				DirInterm + "/sre.ca.chain",
+			},
+			CertTypeInterm,
+			false,
+			[]Cert{
+				{Type: CertTypeInterm, Name: "ops"},
+				{Type: CertTypeInterm, Name: "sre"},
+			},
+		},
+		{
+			"Server",
+			[]string{
+				DirServer + "/example.com.key",
+				DirServer + "/example.com.cert",
+				DirCSR + "/example.com.csr",
+			},
+			CertTypeServer,
+			false,
+			[]Cert{
+				{Type: CertTypeServer, Name: "example.com"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := NewWorkspace(nil, nil)
+			assert.NoError(t, err)
+			defer CleanupWorkspace() // nolint: errcheck
+
+			for _, file := range test.files {
+				err = os.WriteFile(file, nil, 0644)
+				assert.NoError(t, err)
+			}
+
+			certs, err := ListCerts(test.certType)
+
+			if test.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, certs)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedCerts, certs)
+			}
+		})
+	}
+}
+
+// writeExternalCSR writes a certificate signing request generated outside workspace, requesting extended key usages if set
+func writeExternalCSR(t *testing.T, c Cert, usages ...string) {
+	_, key, err := genKeyPair(AlgorithmECDSA, 256)
+	assert.NoError(t, err)
+
+	req := &x509.CertificateRequest{Subject: pkix.Name{CommonName: c.Name}}
+	if len(usages) > 0 {
+		oids := []asn1.ObjectIdentifier{}
+		for _, usage := range usages {
+			oid, err := extKeyUsageOID(usage)
+			assert.NoError(t, err)
+			oids = append(oids, oid)
+		}
+
+		value, err := asn1.Marshal(oids)
+		assert.NoError(t, err)
+		req.ExtraExtensions = []pkix.Extension{{Id: oidExtKeyUsage, Value: value}}
+	}
+
+	csrData, err := x509.CreateCertificateRequest(rand.Reader, req, key)
+	assert.NoError(t, err)
+	assert.NoError(t, ImportCSR(c, pem.EncodeToMemory(&pem.Block{Type: pemTypeCSR, Bytes: csrData})))
+}
+
+func TestListCSRs(t *testing.T) {
+	tests := []struct {
+		title         string
+		files         []string
+		certType      int
+		expectError   bool
+		expectedCerts []Cert
+	}{
+		{
+			"InvalidType",
+			[]string{},
+			CertTypeRoot,
+			true,
+			nil,
+		},
+		{
+			"NoCSR",
+			[]string{},
+			CertTypeInterm,
+			false,
+			[]Cert{},
+		},
+		{
+			"Intermediate",
+			[]string{
+				DirInterm + "/ops.ca.key",
+				DirCSR + "/ops.ca.csr",
+				DirInterm + "/sre.ca.key",
+				DirInterm + "/sre.ca.cert",
+				DirCSR + "/sre.ca.csr",
+			},
+			CertTypeInterm,
+			false,
+			[]Cert{
+				{Type: CertTypeInterm, Name: "ops"},
+			},
+		},
+		{
+			"Server",
+			[]string{
+				DirInterm + "/ops.ca.key",
+				DirCSR + "/ops.ca.csr",
+				DirServer + "/example.com.key",
+				DirCSR + "/example.com.csr",
+				DirServer + "/example.org.key",
+				DirServer + "/example.org.cert",
+				DirCSR + "/example.org.csr",
+				DirClient + "/service.key",
+				DirCSR + "/service.csr",
+			},
+			CertTypeServer,
+			false,
+			[]Cert{
+				{Type: CertTypeServer, Name: "example.com"},
+			},
+		},
+		{
+			"Client",
+			[]string{
+				DirServer + "/example.com.key",
+				DirCSR + "/example.com.csr",
+				DirClient + "/service.key",
+				DirCSR + "/service.csr",
+			},
+			CertTypeClient,
+			false,
+			[]Cert{
+				{Type: CertTypeClient, Name: "service"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			err := NewWorkspace(nil, nil)
+			assert.NoError(t, err)
+			defer CleanupWorkspace() // nolint: errcheck
+
+			for _, file := range test.files {
+				err = os.WriteFile(file, nil, 0644)
+				assert.NoError(t, err)
+			}
+
+			certs, err := ListCSRs(test.certType)
+
+			if test.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, certs)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedCerts, certs)
+			}
+		})
+	}
+}
+
+func TestListCSRsExternal(t *testing.T) {
+	err := NewWorkspace(nil, nil)
+	assert.NoError(t, err)
+	defer CleanupWorkspace() // nolint: errcheck
+
+	cOps := Cert{Name: "ops", Type: CertTypeInterm}
+	cWebapp := Cert{Name: "webapp", Type: CertTypeServer}
+	cAgent := Cert{Name: "agent", Type: CertTypeClient}
+	writeExternalCSR(t, cOps)
+	writeExternalCSR(t, cWebapp, "ServerAuth", "ClientAuth")
+	writeExternalCSR(t, cAgent, "ClientAuth")
+
+	// A request generated in workspace has a key
+	assert.NoError(t, os.WriteFile(DirClient+"/service.key", nil, 0644))
+	assert.NoError(t, os.WriteFile(DirCSR+"/service.csr", nil, 0644))
+
+	tests := []struct {
+		certType      int
+		expectedCerts []Cert
+	}{
+		{CertTypeInterm, []Cert{cOps}},
+		{CertTypeServer, []Cert{cWebapp}},
+		{CertTypeClient, []Cert{cAgent, {Name: "service", Type: CertTypeClient}}},
+	}
+
+	for _, test := range tests {
+		certs, err := ListCSRs(test.certType)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expectedCerts, certs)
+	}
+
+	assert.True(t, isExternal(cOps))
+	assert.True(t, isExternal(cAgent))
+	assert.False(t, isExternal(Cert{Name: "service", Type: CertTypeServer}))
+
+	// A signed external request is not pending
+	assert.NoError(t, os.WriteFile(cWebapp.CertPath(), nil, 0644))
+	certs, err := ListCSRs(CertTypeServer)
+	assert.NoError(t, err)
+	assert.Empty(t, certs)
+}
+
 func TestCleanupWorkspace(t *testing.T) {
 	tests := []struct {
 		files []string